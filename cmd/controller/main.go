@@ -35,6 +35,7 @@ func main() {
 		op.EventRecorder,
 		op.InstanceTypeProvider,
 		op.InstanceProvider,
+		op.QuotaProvider,
 	)
 
 	lo.Must0(op.AddHealthzCheck("cloud-provider", aliCloudProvider.LivenessProbe))
@@ -62,6 +63,14 @@ func main() {
 			op.VSwitchProvider,
 			op.SecurityGroupProvider,
 			op.ImageProvider,
+			op.EIPProvider,
+			op.KeyPairProvider,
+			op.ResourceGroupProvider,
+			op.CapacityReservationProvider,
+			op.QuotaProvider,
+			op.BootstrapTokenProvider,
+			op.ACKNodePoolProvider,
+			op.ACKClient,
 		)...).
 		Start(ctx, cloudProvider)
 }
@@ -26,11 +26,13 @@ import (
 
 // ECSNodeClassSpec is the top level specification for the AlibabaCloud Karpenter Provider.
 // This will contain configuration necessary to launch instances in AliCloud.
+// +kubebuilder:validation:XValidation:message="securityGroupSelectorTerms must be set unless createSecurityGroup is enabled",rule="has(self.securityGroupSelectorTerms) || (has(self.createSecurityGroup) && self.createSecurityGroup)"
+// +kubebuilder:validation:XValidation:message="hostNameTemplate isn't supported with the Windows2019 and Windows2022 image families",rule="!(has(self.hostNameTemplate) && self.imageSelectorTerms.exists(x, has(x.alias) && x.alias in ['Windows2019', 'Windows2022']))"
 type ECSNodeClassSpec struct {
 	// VSwitchSelectorTerms is a list of or vSwitch selector terms. The terms are ORed.
 	// +kubebuilder:validation:XValidation:message="vSwitchSelectorTerms cannot be empty",rule="self.size() != 0"
-	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id']",rule="self.all(x, has(x.tags) || has(x.id))"
-	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in vSwitchSelectorTerms",rule="!self.all(x, has(x.id) && has(x.tags))"
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name', 'vpcId', 'zoneId', 'cidrBlock']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name) || has(x.vpcId) || has(x.zoneId) || has(x.cidrBlock))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in vSwitchSelectorTerms",rule="!self.exists(x, has(x.id) && (has(x.tags) || has(x.name) || has(x.vpcId) || has(x.zoneId) || has(x.cidrBlock)))"
 	// +kubebuilder:validation:MaxItems:=30
 	// +required
 	VSwitchSelectorTerms []VSwitchSelectorTerm `json:"vSwitchSelectorTerms" hash:"ignore"`
@@ -40,8 +42,21 @@ type ECSNodeClassSpec struct {
 	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in securityGroupSelectorTerms",rule="!self.all(x, has(x.id) && (has(x.tags) || has(x.name)))"
 	// +kubebuilder:validation:XValidation:message="'name' is mutually exclusive, cannot be set with a combination of other fields in securityGroupSelectorTerms",rule="!self.all(x, has(x.name) && (has(x.tags) || has(x.id)))"
 	// +kubebuilder:validation:MaxItems:=30
-	// +required
-	SecurityGroupSelectorTerms []SecurityGroupSelectorTerm `json:"securityGroupSelectorTerms" hash:"ignore"`
+	// +optional
+	SecurityGroupSelectorTerms []SecurityGroupSelectorTerm `json:"securityGroupSelectorTerms,omitempty" hash:"ignore"`
+	// CreateSecurityGroup makes Karpenter create and own a security group for the nodes of this ECSNodeClass,
+	// in addition to any security groups selected by SecurityGroupSelectorTerms.
+	// The group allows all traffic from the VPC CIDR blocks, is tagged with kubernetes.io/cluster/<cluster-name>
+	// and is deleted once the ECSNodeClass is deleted and no instances use it.
+	// +optional
+	CreateSecurityGroup bool `json:"createSecurityGroup,omitempty" hash:"ignore"`
+	// CapacityReservationSelectorTerms is a list of capacity reservation and elasticity assurance selector terms. The terms are ORed.
+	// Instance types and zones covered by the selected private pools are offered with the "reserved" capacity type.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id']",rule="self.all(x, has(x.tags) || has(x.id))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in capacityReservationSelectorTerms",rule="!self.exists(x, has(x.id) && has(x.tags))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +optional
+	CapacityReservationSelectorTerms []CapacityReservationSelectorTerm `json:"capacityReservationSelectorTerms,omitempty" hash:"ignore"`
 	// ImageSelectorTerms is a list of or image selector terms. The terms are ORed.
 	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['id', 'alias']",rule="self.all(x, has(x.id) || has(x.alias))"
 	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in imageSelectorTerms",rule="!self.exists(x, has(x.id) && (has(x.alias)))"
@@ -56,17 +71,79 @@ type ECSNodeClassSpec struct {
 	// this UserData to ensure nodes are being provisioned with the correct configuration.
 	// +optional
 	UserData *string `json:"userData,omitempty"`
+	// Bootstrap configures how provisioned nodes join the cluster. By default the bootstrap script of
+	// the image family is rendered into the user data.
+	// +kubebuilder:validation:XValidation:message="nodePoolId is required with the ack-nodepool mode",rule="has(self.mode) && self.mode == 'ack-nodepool' ? has(self.nodePoolId) : true"
+	// +optional
+	Bootstrap *Bootstrap `json:"bootstrap,omitempty"`
 	// KubeletConfiguration defines args to be used when configuring kubelet on provisioned nodes.
 	// They are a vswitch of the upstream types, recognizing not all options may be supported.
 	// Wherever possible, the types and names should reflect the upstream kubelet types.
 	// +kubebuilder:validation:XValidation:message="imageGCHighThresholdPercent must be greater than imageGCLowThresholdPercent",rule="has(self.imageGCHighThresholdPercent) && has(self.imageGCLowThresholdPercent) ?  self.imageGCHighThresholdPercent > self.imageGCLowThresholdPercent  : true"
 	// +kubebuilder:validation:XValidation:message="evictionSoft OwnerKey does not have a matching evictionSoftGracePeriod",rule="has(self.evictionSoft) ? self.evictionSoft.all(e, (e in self.evictionSoftGracePeriod)):true"
 	// +kubebuilder:validation:XValidation:message="evictionSoftGracePeriod OwnerKey does not have a matching evictionSoft",rule="has(self.evictionSoftGracePeriod) ? self.evictionSoftGracePeriod.all(e, (e in self.evictionSoft)):true"
+	// +kubebuilder:validation:XValidation:message="cpuManagerPolicy static requires cpu to be set in kubeReserved or systemReserved",rule="has(self.cpuManagerPolicy) && self.cpuManagerPolicy == 'static' ? ((has(self.kubeReserved) && 'cpu' in self.kubeReserved) || (has(self.systemReserved) && 'cpu' in self.systemReserved)) : true"
+	// +kubebuilder:validation:XValidation:message="memoryManagerPolicy Static requires reservedMemory",rule="has(self.memoryManagerPolicy) && self.memoryManagerPolicy == 'Static' ? has(self.reservedMemory) : true"
+	// +kubebuilder:validation:XValidation:message="reservedMemory requires memoryManagerPolicy Static",rule="has(self.reservedMemory) ? has(self.memoryManagerPolicy) && self.memoryManagerPolicy == 'Static' : true"
+	// +kubebuilder:validation:XValidation:message="shutdownGracePeriodCriticalPods must not be greater than shutdownGracePeriod",rule="has(self.shutdownGracePeriodCriticalPods) ? has(self.shutdownGracePeriod) && duration(self.shutdownGracePeriodCriticalPods) <= duration(self.shutdownGracePeriod) : true"
 	// +optional
 	KubeletConfiguration *KubeletConfiguration `json:"kubeletConfiguration,omitempty"`
 	// SystemDisk to be applied to provisioned nodes.
 	// +optional
 	SystemDisk *SystemDisk `json:"systemDisk,omitempty"`
+	// PublicIP configures public network access for provisioned nodes, either through a
+	// public IP address allocated with the instance or through an EIP bound from a tagged pool.
+	// +kubebuilder:validation:XValidation:message="eipPoolTags cannot be set together with a non-zero internetMaxBandwidthOut",rule="!(has(self.eipPoolTags) && has(self.internetMaxBandwidthOut) && self.internetMaxBandwidthOut > 0)"
+	// +optional
+	PublicIP *PublicIP `json:"publicIP,omitempty"`
+	// MetadataOptions for the instance metadata service of provisioned nodes.
+	// If metadataOptions is not specified, the metadata endpoint is enabled and the
+	// security hardening mode (tokens required) is enforced.
+	// +kubebuilder:default={"httpEndpoint":"enabled","httpTokens":"required"}
+	// +optional
+	MetadataOptions *MetadataOptions `json:"metadataOptions,omitempty"`
+	// InstanceNameTemplate is rendered into the ECS instance name of provisioned nodes, e.g. "{{.ClusterName}}-{{.NodeClaim}}".
+	// The rendered instance name must not exceed 128 characters. The name is set at launch, so only the variables known
+	// before the instance exists are available: ClusterName, NodePool, NodeClaim, NodeClass and CapacityType.
+	// +kubebuilder:validation:XValidation:message="instanceNameTemplate must start with a letter or a variable",rule="self.matches('^([A-Za-z]|[{][{])')"
+	// +kubebuilder:validation:XValidation:message="instanceNameTemplate may only contain letters, digits, '.', '_', ':', '-' and the variables ClusterName, NodePool, NodeClaim, NodeClass, CapacityType",rule="self.matches('^([A-Za-z0-9._:-]|[{][{] *[.](ClusterName|NodePool|NodeClaim|NodeClass|CapacityType) *[}][}])+$')"
+	// +kubebuilder:validation:MinLength=2
+	// +kubebuilder:validation:MaxLength=128
+	// +optional
+	InstanceNameTemplate *string `json:"instanceNameTemplate,omitempty"`
+	// HostNameTemplate is rendered into the host name of provisioned nodes, which the kubelet uses as the node name.
+	// Available variables are the same as for InstanceNameTemplate, the rendered host name must not exceed 64 characters.
+	// It isn't supported with the Windows image families, whose host names are limited to 15 characters.
+	// +kubebuilder:validation:XValidation:message="hostNameTemplate must start and end with a lowercase letter, a digit or a variable",rule="self.matches('^([a-z0-9]|[{][{]).*([a-z0-9]|[}][}])$')"
+	// +kubebuilder:validation:XValidation:message="hostNameTemplate may only contain lowercase letters, digits, '.', '-' and the variables ClusterName, NodePool, NodeClaim, NodeClass, CapacityType",rule="self.matches('^([a-z0-9.-]|[{][{] *[.](ClusterName|NodePool|NodeClaim|NodeClass|CapacityType) *[}][}])+$')"
+	// +kubebuilder:validation:XValidation:message="hostNameTemplate cannot contain consecutive '.' or '-'",rule="!self.matches('[.-][.-]')"
+	// +kubebuilder:validation:XValidation:message="hostNameTemplate must contain the NodeClaim variable so that node names are unique",rule="self.matches('[{][{] *[.]NodeClaim *[}][}]')"
+	// +kubebuilder:validation:MinLength=2
+	// +kubebuilder:validation:MaxLength=64
+	// +optional
+	HostNameTemplate *string `json:"hostNameTemplate,omitempty"`
+	// KeyPairName is the name of the ECS key pair attached to provisioned nodes for SSH access.
+	// The key pair must exist in the region of the cluster.
+	// +kubebuilder:validation:Pattern="^[A-Za-z][A-Za-z0-9._:-]*$"
+	// +kubebuilder:validation:MinLength=2
+	// +kubebuilder:validation:MaxLength=128
+	// +optional
+	KeyPairName *string `json:"keyPairName,omitempty"`
+	// PasswordInherit uses the password preset in the image for provisioned nodes.
+	// +optional
+	PasswordInherit *bool `json:"passwordInherit,omitempty"`
+	// ResourceGroupID is the ID of the resource group that instances, their disks and the
+	// managed security group are placed in. Defaults to the default resource group of the account.
+	// +kubebuilder:validation:Pattern="^rg-[0-9a-z]+$"
+	// +optional
+	ResourceGroupID *string `json:"resourceGroupId,omitempty"`
+	// CreditSpecification is the performance mode of burstable (t5/t6) instances, it is ignored for other instance types.
+	// In Unlimited mode instances can burst beyond their CPU credits at an additional cost, which isn't part of the
+	// instance price Karpenter compares instance types by since it depends on the load of the instance.
+	// When set, burstable instance types aren't launched along with other instance types.
+	// +kubebuilder:validation:Enum:={Standard,Unlimited}
+	// +optional
+	CreditSpecification *string `json:"creditSpecification,omitempty"`
 	// Tags to be applied on ecs resources like instances and launch templates.
 	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching ecs:ecs-cluster-name",rule="self.all(k, k !='ecs:ecs-cluster-name')"
@@ -79,6 +156,7 @@ type ECSNodeClassSpec struct {
 }
 
 // VSwitchSelectorTerm defines selection logic for a vSwitch used by Karpenter to launch nodes.
+// If multiple fields are used for selection, the requirements are ANDed.
 type VSwitchSelectorTerm struct {
 	// Tags is a map of key/value tags used to select vSwitches
 	// Specifying '*' for a value selects all values for a given tag key.
@@ -90,6 +168,23 @@ type VSwitchSelectorTerm struct {
 	// +kubebuilder:validation:Pattern="vsw-[0-9a-z]+"
 	// +optional
 	ID string `json:"id,omitempty"`
+	// Name is the vSwitch name in VPC.
+	// Specifying '*' in the name matches any sequence of characters, e.g. 'karpenter-*'.
+	// +kubebuilder:validation:MaxLength=128
+	// +optional
+	Name string `json:"name,omitempty"`
+	// VPCID is the id of the VPC the vSwitch belongs to
+	// +kubebuilder:validation:Pattern="vpc-[0-9a-z]+"
+	// +optional
+	VPCID string `json:"vpcId,omitempty"`
+	// ZoneID is the availability zone of the vSwitch, e.g. cn-hangzhou-h
+	// +kubebuilder:validation:Pattern="^[a-z0-9-]+$"
+	// +optional
+	ZoneID string `json:"zoneId,omitempty"`
+	// CIDRBlock selects vSwitches whose CIDR block is contained within the given IPv4 CIDR block
+	// +kubebuilder:validation:Pattern=`^([0-9]{1,3}\.){3}[0-9]{1,3}/([0-9]|[12][0-9]|3[0-2])$`
+	// +optional
+	CIDRBlock string `json:"cidrBlock,omitempty"`
 }
 
 // SecurityGroupSelectorTerm defines selection logic for a security group used by Karpenter to launch nodes.
@@ -110,16 +205,31 @@ type SecurityGroupSelectorTerm struct {
 	Name string `json:"name,omitempty"`
 }
 
+// CapacityReservationSelectorTerm defines selection logic for a capacity reservation or an elasticity assurance
+// used by Karpenter to launch reserved nodes. If multiple fields are used for selection, the requirements are ANDed.
+type CapacityReservationSelectorTerm struct {
+	// Tags is a map of key/value tags used to select capacity reservations and elasticity assurances
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// ID is the private pool id of the capacity reservation (crp-) or elasticity assurance (eap-) in ECS
+	// +kubebuilder:validation:Pattern:="^(crp|eap)-[0-9a-z]+$"
+	// +optional
+	ID string `json:"id,omitempty"`
+}
+
 // ImageSelectorTerm defines selection logic for an image used by Karpenter to launch nodes.
 // If multiple fields are used for selection, the requirements are ANDed.
 type ImageSelectorTerm struct {
 	// Alias specifies which ACK image to select.
 	// Each alias consists of a family and an image version, specified as "family@version".
-	// Valid families include: AlibabaCloudLinux3,AlibabaCloudLinux2
+	// Valid families include: AlibabaCloudLinux3,AlibabaCloudLinux2,ContainerOS,Ubuntu2204,Ubuntu2404,Windows2019,Windows2022
 	// Currently only supports version pinning to the latest image release, with that images version format (ex: "aliyun3@latest").
 	// Setting the version to latest will result in drift when a new Image is released. This is **not** recommended for production environments.
 	// +kubebuilder:validation:XValidation:message="'alias' is improperly formatted, must match the format 'family'",rule="self.matches('^[a-zA-Z0-9]*$')"
-	// +kubebuilder:validation:XValidation:message="family is not supported, must be one of the following: 'AlibabaCloudLinux3,AlibabaCloudLinux2'",rule="self.find('^[^@]+') in ['AlibabaCloudLinux3', 'AlibabaCloudLinux2']"
+	// +kubebuilder:validation:XValidation:message="family is not supported, must be one of the following: 'AlibabaCloudLinux3,AlibabaCloudLinux2,ContainerOS,Ubuntu2204,Ubuntu2404,Windows2019,Windows2022'",rule="self.find('^[^@]+') in ['AlibabaCloudLinux3', 'AlibabaCloudLinux2', 'ContainerOS', 'Ubuntu2204', 'Ubuntu2404', 'Windows2019', 'Windows2022']"
 	// +kubebuilder:validation:MaxLength=30
 	// +optional
 	Alias string `json:"alias,omitempty"`
@@ -195,6 +305,128 @@ type KubeletConfiguration struct {
 	// CPUCFSQuota enables CPU CFS quota enforcement for containers that specify CPU limits.
 	// +optional
 	CPUCFSQuota *bool `json:"cpuCFSQuota,omitempty"`
+	// CPUManagerPolicy is the policy of the CPU manager, the static policy grants Guaranteed pods with integer CPU
+	// requests exclusive CPUs. It requires cpu to be reserved, the kubelet keeps the whole cores covering it shared.
+	// +kubebuilder:validation:Enum:={none,static}
+	// +optional
+	CPUManagerPolicy *string `json:"cpuManagerPolicy,omitempty"`
+	// TopologyManagerPolicy is the policy of the topology manager aligning the resources of pods across NUMA nodes.
+	// +kubebuilder:validation:Enum:={none,best-effort,restricted,single-numa-node}
+	// +optional
+	TopologyManagerPolicy *string `json:"topologyManagerPolicy,omitempty"`
+	// TopologyManagerScope is the granularity the topology manager aligns resources at.
+	// +kubebuilder:validation:Enum:={container,pod}
+	// +optional
+	TopologyManagerScope *string `json:"topologyManagerScope,omitempty"`
+	// MemoryManagerPolicy is the policy of the memory manager, the static policy pins the memory of Guaranteed pods to NUMA nodes.
+	// +kubebuilder:validation:Enum:={None,Static}
+	// +optional
+	MemoryManagerPolicy *string `json:"memoryManagerPolicy,omitempty"`
+	// ReservedMemory is the memory reserved per NUMA node for the Static memory manager policy. The total of each
+	// resource must equal the sum of kubeReserved, systemReserved and the hard eviction threshold.
+	// +kubebuilder:validation:MaxItems:=16
+	// +optional
+	ReservedMemory []MemoryReservation `json:"reservedMemory,omitempty"`
+	// ContainerLogMaxSize is the maximum size of a container log file before it is rotated, e.g. 10Mi.
+	// +kubebuilder:validation:Pattern:="^[0-9]+(Ki|Mi|Gi)?$"
+	// +optional
+	ContainerLogMaxSize *string `json:"containerLogMaxSize,omitempty"`
+	// ContainerLogMaxFiles is the maximum number of log files kept per container.
+	// +kubebuilder:validation:Minimum:=2
+	// +optional
+	ContainerLogMaxFiles *int32 `json:"containerLogMaxFiles,omitempty"`
+	// RegistryPullQPS is the limit of registry pulls per second, 0 disables the limit.
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	RegistryPullQPS *int32 `json:"registryPullQPS,omitempty"`
+	// RegistryBurst is the maximum burst of registry pulls, it's only used when registryPullQPS is greater than 0.
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	RegistryBurst *int32 `json:"registryBurst,omitempty"`
+	// ShutdownGracePeriod is the total time the node delays its shutdown for terminating pods.
+	// It's set through a kubelet configuration drop-in, which requires Kubernetes 1.30 or later.
+	// +kubebuilder:validation:Type:="string"
+	// +kubebuilder:validation:Pattern:="^([0-9]+(s|m|h))+$"
+	// +optional
+	ShutdownGracePeriod *metav1.Duration `json:"shutdownGracePeriod,omitempty"`
+	// ShutdownGracePeriodCriticalPods is the part of shutdownGracePeriod reserved for terminating critical pods.
+	// +kubebuilder:validation:Type:="string"
+	// +kubebuilder:validation:Pattern:="^([0-9]+(s|m|h))+$"
+	// +optional
+	ShutdownGracePeriodCriticalPods *metav1.Duration `json:"shutdownGracePeriodCriticalPods,omitempty"`
+	// AllowedUnsafeSysctls are the unsafe sysctls or sysctl patterns ending in * that pods may set.
+	// +kubebuilder:validation:items:Pattern:="^([a-z0-9]([-_a-z0-9]*[a-z0-9])?[./])*[a-z0-9]([-_a-z0-9]*[a-z0-9])?[*]?$"
+	// +optional
+	AllowedUnsafeSysctls []string `json:"allowedUnsafeSysctls,omitempty"`
+}
+
+// MemoryReservation is the memory reserved on a NUMA node for the Static memory manager policy
+type MemoryReservation struct {
+	// NUMANode is the id of the NUMA node
+	// +kubebuilder:validation:Minimum:=0
+	// +required
+	NUMANode int32 `json:"numaNode"`
+	// Limits are the reserved quantities of memory and hugepages on the NUMA node
+	// +kubebuilder:validation:XValidation:message="valid keys for limits are ['memory','hugepages-<size>']",rule="self.all(x, x == 'memory' || x.startsWith('hugepages-'))"
+	// +kubebuilder:validation:XValidation:message="limits value cannot be a negative resource quantity",rule="self.all(x, !self[x].startsWith('-'))"
+	// +required
+	Limits map[string]string `json:"limits"`
+}
+
+// MetadataOptions contains parameters for specifying the exposure of the
+// Instance Metadata Service to provisioned ECS nodes.
+type MetadataOptions struct {
+	// HTTPEndpoint enables or disables the HTTP metadata endpoint on provisioned
+	// nodes. If metadata options is non-nil, but this parameter is not specified,
+	// the default state is "enabled".
+	// +kubebuilder:default=enabled
+	// +kubebuilder:validation:Enum:={enabled,disabled}
+	// +optional
+	HTTPEndpoint *string `json:"httpEndpoint,omitempty"`
+	// HTTPTokens determines whether the security hardening mode is required when
+	// requesting metadata. With "optional", metadata can be retrieved with or
+	// without a token. With "required", every request must carry a token and
+	// the normal mode is rejected. If metadata options is non-nil, but this
+	// parameter is not specified, the default state is "required".
+	// +kubebuilder:default=required
+	// +kubebuilder:validation:Enum:={required,optional}
+	// +optional
+	HTTPTokens *string `json:"httpTokens,omitempty"`
+}
+
+type PublicIP struct {
+	// InternetMaxBandwidthOut is the maximum outbound public bandwidth of the instance. Unit: Mbit/s.
+	// A public IP address is allocated with the instance when this value is greater than 0.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=100
+	// +optional
+	InternetMaxBandwidthOut *int32 `json:"internetMaxBandwidthOut,omitempty"`
+	// InternetChargeType is the billing method for the public bandwidth.
+	// +kubebuilder:validation:Enum:={PayByTraffic,PayByBandwidth}
+	// +optional
+	InternetChargeType *string `json:"internetChargeType,omitempty"`
+	// EIPPoolTags selects the pool of EIPs to bind to provisioned nodes, every tag must match.
+	// An available EIP from the pool is bound once the instance is running and unbound when it is deleted.
+	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
+	// +kubebuilder:validation:MinProperties:=1
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	EIPPoolTags map[string]string `json:"eipPoolTags,omitempty"`
+}
+
+type Bootstrap struct {
+	// Mode is "userdata" to join nodes with the bootstrap script in the user data, or "ack-nodepool" to attach
+	// the instances to an ACK node pool, which installs and configures them as nodes of the pool.
+	// +kubebuilder:validation:Enum:={userdata,ack-nodepool}
+	// +kubebuilder:default=userdata
+	// +optional
+	Mode *string `json:"mode,omitempty"`
+	// NodePoolID is the ID of the ACK node pool that instances are attached to in the ack-nodepool mode.
+	// The node pool must register its nodes with the karpenter.sh/unregistered:NoExecute taint, the labels
+	// and taints of the NodeClaims are synced to the nodes once they register.
+	// +kubebuilder:validation:Pattern="^np[0-9a-z]+$"
+	// +optional
+	NodePoolID *string `json:"nodePoolId,omitempty"`
 }
 
 type SystemDisk struct {
@@ -250,7 +482,7 @@ type ECSNodeClass struct {
 // 1. A field changes its default value for an existing field that is already hashed
 // 2. A field is added to the hash calculation with an already-set value
 // 3. A field is removed from the hash calculations
-const ECSNodeClassHashVersion = "v1"
+const ECSNodeClassHashVersion = "v2"
 
 func (in *ECSNodeClass) Hash() string {
 	return fmt.Sprint(lo.Must(hashstructure.Hash([]interface{}{
@@ -273,6 +505,14 @@ func (in *ECSNodeClass) ImageFamily() string {
 	return ImageFamilyCustom
 }
 
+// BootstrapMode returns the bootstrap mode, defaulting to user data
+func (in *ECSNodeClass) BootstrapMode() string {
+	if in.Spec.Bootstrap == nil || in.Spec.Bootstrap.Mode == nil {
+		return BootstrapModeUserData
+	}
+	return *in.Spec.Bootstrap.Mode
+}
+
 // ECSNodeClassList contains a list of ECSNodeClass
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type ECSNodeClassList struct {
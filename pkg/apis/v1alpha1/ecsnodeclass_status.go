@@ -26,6 +26,20 @@ const (
 	ConditionTypeSecurityGroupsReady = "SecurityGroupsReady"
 	ConditionTypeInstanceRAMReady    = "InstanceRAMReady"
 	ConditionTypeImagesReady         = "ImagesReady"
+	ConditionTypeVPCConsistent       = "VPCConsistent"
+	ConditionTypeKeyPairReady        = "KeyPairReady"
+	ConditionTypeResourceGroupReady  = "ResourceGroupReady"
+	// ConditionTypeACKNodePoolReady is False when the ACK node pool of the ack-nodepool bootstrap mode doesn't
+	// register its nodes with the unregistered taint
+	ConditionTypeACKNodePoolReady = "ACKNodePoolReady"
+	// ConditionTypeUserDataReady is False when the custom user data can't be merged with the bootstrap user data
+	ConditionTypeUserDataReady = "UserDataReady"
+	// ConditionTypeSecurityGroupsCapacityAvailable is False when any of the resolved security groups can't accept any more instances
+	ConditionTypeSecurityGroupsCapacityAvailable = "SecurityGroupsCapacityAvailable"
+
+	// ConditionTypeInstanceAttached is set on NodeClaims of the ack-nodepool bootstrap mode, it's True once ACK
+	// attached the instance to the node pool and False while a failed attach is retried
+	ConditionTypeInstanceAttached = "InstanceAttached"
 )
 
 // VSwitch contains resolved VSwitch selector values utilized for node launch
@@ -36,6 +50,9 @@ type VSwitch struct {
 	// The associated availability zone ID
 	// +required
 	ZoneID string `json:"zoneID,omitempty"`
+	// The ID of the VPC the vSwitch belongs to
+	// +optional
+	VPCID string `json:"vpcID,omitempty"`
 }
 
 // SecurityGroup contains resolved SecurityGroup selector values utilized for node launch
@@ -46,6 +63,35 @@ type SecurityGroup struct {
 	// Name of the security group
 	// +optional
 	Name string `json:"name,omitempty"`
+	// The ID of the VPC the security group belongs to
+	// +optional
+	VPCID string `json:"vpcID,omitempty"`
+	// ECSCount is the number of instances currently associated with the security group
+	// +optional
+	ECSCount int32 `json:"ecsCount,omitempty"`
+	// AvailableInstanceAmount is the number of instances that can still be added to the security group
+	// +optional
+	AvailableInstanceAmount int32 `json:"availableInstanceAmount,omitempty"`
+}
+
+// CapacityReservation contains resolved capacity reservation and elasticity assurance selector values utilized for
+// node launch, there is one entry per instance type and zone of a private pool
+type CapacityReservation struct {
+	// ID of the private pool
+	// +required
+	ID string `json:"id"`
+	// Type of the private pool, either CapacityReservation or ElasticityAssurance
+	// +required
+	Type string `json:"type"`
+	// InstanceType that is reserved
+	// +required
+	InstanceType string `json:"instanceType"`
+	// ZoneID the instances are reserved in
+	// +required
+	ZoneID string `json:"zoneID"`
+	// AvailableInstanceCount is the number of instances that can still be launched into the private pool
+	// +optional
+	AvailableInstanceCount int32 `json:"availableInstanceCount,omitempty"`
 }
 
 // Image contains resolved image selector values utilized for node launch
@@ -71,6 +117,13 @@ type ECSNodeClassStatus struct {
 	// cluster under the SecurityGroups selectors.
 	// +optional
 	SecurityGroups []SecurityGroup `json:"securityGroups,omitempty"`
+	// VPCID is the VPC shared by the resolved vSwitches and security groups
+	// +optional
+	VPCID string `json:"vpcID,omitempty"`
+	// CapacityReservations contains the current capacity reservations and elasticity assurances that are
+	// available to the cluster under the capacity reservation selectors.
+	// +optional
+	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty"`
 	// Image contains the current image that are available to the
 	// cluster under the Image selectors.
 	// +optional
@@ -86,6 +139,12 @@ func (in *ECSNodeClass) StatusConditions() status.ConditionSet {
 		ConditionTypeSecurityGroupsReady,
 		ConditionTypeInstanceRAMReady,
 		ConditionTypeImagesReady,
+		ConditionTypeVPCConsistent,
+		ConditionTypeSecurityGroupsCapacityAvailable,
+		ConditionTypeKeyPairReady,
+		ConditionTypeResourceGroupReady,
+		ConditionTypeACKNodePoolReady,
+		ConditionTypeUserDataReady,
 	).For(in)
 }
 
@@ -50,6 +50,8 @@ func init() {
 		LabelInstanceAcceleratorName,
 		LabelInstanceAcceleratorManufacturer,
 		LabelInstanceAcceleratorCount,
+		LabelInstanceBurstable,
+		LabelCapacityReservationID,
 		LabelTopologyZoneID,
 		corev1.LabelWindowsBuild,
 	)
@@ -79,11 +81,26 @@ var (
 	}
 	ImageFamilyAlibabaCloudLinux3                     = "AlibabaCloudLinux3"
 	ImageFamilyAlibabaCloudLinux2                     = "AlibabaCloudLinux2"
+	ImageFamilyContainerOS                            = "ContainerOS"
+	ImageFamilyUbuntu2204                             = "Ubuntu2204"
+	ImageFamilyUbuntu2404                             = "Ubuntu2404"
+	ImageFamilyWindows2019                            = "Windows2019"
+	ImageFamilyWindows2022                            = "Windows2022"
 	ImageFamilyCustom                                 = "Custom"
+	CreditSpecificationStandard                       = "Standard"
+	CreditSpecificationUnlimited                      = "Unlimited"
+	BootstrapModeUserData                             = "userdata"
+	BootstrapModeACKNodePool                          = "ack-nodepool"
 	ResourceNVIDIAGPU             corev1.ResourceName = "nvidia.com/gpu"
 	ResourceAMDGPU                corev1.ResourceName = "amd.com/gpu"
 	ResourcePrivateIPv4Address    corev1.ResourceName = "vpc.alibabacloud.com/PrivateIPv4Address"
 
+	CPUManagerPolicyStatic = "static"
+
+	CapacityTypeReserved                       = "reserved"
+	CapacityReservationTypeCapacityReservation = "CapacityReservation"
+	CapacityReservationTypeElasticityAssurance = "ElasticityAssurance"
+
 	ECSClusterNameTagKey = "ecs:ecs-cluster-name"
 
 	LabelNodeClass = apis.Group + "/ecsnodeclass"
@@ -109,12 +126,19 @@ var (
 	LabelInstanceAcceleratorName              = apis.Group + "/instance-accelerator-name"
 	LabelInstanceAcceleratorManufacturer      = apis.Group + "/instance-accelerator-manufacturer"
 	LabelInstanceAcceleratorCount             = apis.Group + "/instance-accelerator-count"
+	LabelInstanceBurstable                    = apis.Group + "/instance-burstable"
+	LabelCapacityReservationID                = apis.Group + "/capacity-reservation-id"
 	AnnotationECSNodeClassHash                = apis.Group + "/ecsnodeclass-hash"
 	AnnotationClusterNameTaggedCompatability  = apis.CompatibilityGroup + "/cluster-name-tagged"
 	AnnotationECSNodeClassHashVersion         = apis.Group + "/ecsnodeclass-hash-version"
 	AnnotationInstanceTagged                  = apis.Group + "/tagged"
+	// AnnotationACKAttachTaskID is the ACK task attaching the instance of a NodeClaim to the node pool
+	AnnotationACKAttachTaskID = apis.Group + "/ack-attach-task-id"
+	// AnnotationEIPAssociated marks NodeClaims whose instance got an EIP from the pool of the ECSNodeClass
+	AnnotationEIPAssociated = apis.Group + "/eip-associated"
 
 	TagNodeClaim             = coreapis.Group + "/nodeclaim"
+	TagEIPInstance           = apis.Group + "/instance"
 	TagManagedLaunchTemplate = apis.Group + "/cluster"
 	TagName                  = "Name"
 )
@@ -34,6 +34,8 @@ const (
 	UnavailableOfferingsTTL = 3 * time.Minute
 	// AvailableIPAddressTTL is time to drop AvailableIPAddress data if it is not updated within the TTL
 	AvailableIPAddressTTL = 5 * time.Minute
+	// AvailableSecurityGroupCapacityTTL is time to drop security group AvailableInstanceAmount data if it is not updated within the TTL
+	AvailableSecurityGroupCapacityTTL = 5 * time.Minute
 	// InstanceTypeAvailableDiskTTL is the time refresh InstanceType compatible disk
 	InstanceTypeAvailableDiskTTL = 30 * time.Minute
 	// LaunchTemplateTTL is time to drop LaunchTemplate data
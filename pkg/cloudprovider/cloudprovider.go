@@ -46,6 +46,7 @@ import (
 	cloudproviderevents "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/cloudprovider/events"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/instance"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/instancetype"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/quota"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils"
 )
 
@@ -57,18 +58,21 @@ type CloudProvider struct {
 
 	instanceTypeProvider instancetype.Provider
 	instanceProvider     instance.Provider
+	quotaProvider        quota.Provider
 }
 
 func New(kubeClient client.Client,
 	recorder events.Recorder,
 	instanceTypeProvider instancetype.Provider,
-	instanceProvider instance.Provider) *CloudProvider {
+	instanceProvider instance.Provider,
+	quotaProvider quota.Provider) *CloudProvider {
 	return &CloudProvider{
 		kubeClient: kubeClient,
 		recorder:   recorder,
 
 		instanceTypeProvider: instanceTypeProvider,
 		instanceProvider:     instanceProvider,
+		quotaProvider:        quotaProvider,
 	}
 }
 
@@ -99,8 +103,10 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 	}
 	instance, err := c.instanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
 	if err != nil {
-		return nil, fmt.Errorf("creating instance, %w", err)
+		return nil, launchError(fmt.Errorf("creating instance, %w", err))
 	}
+	// Offerings of the next launches are only available if they fit into the quota left by this one
+	c.quotaProvider.MarkLaunched(instance.CapacityType, int64(instance.CPU))
 	instanceType, _ := lo.Find(instanceTypes, func(i *cloudprovider.InstanceType) bool {
 		return i.Name == instance.Type
 	})
@@ -308,6 +314,9 @@ func (c *CloudProvider) instanceToNodeClaim(i *instance.Instance, instanceType *
 	}
 	labels[corev1.LabelTopologyZone] = i.Zone
 	labels[karpv1.CapacityTypeLabelKey] = i.CapacityType
+	if i.CapacityReservationID != "" {
+		labels[v1alpha1.LabelCapacityReservationID] = i.CapacityReservationID
+	}
 	if v, ok := i.Tags[karpv1.NodePoolLabelKey]; ok {
 		labels[karpv1.NodePoolLabelKey] = v
 	}
@@ -0,0 +1,39 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/alierrors"
+)
+
+// launchError converts Alibaba Cloud API errors of a launch into the errors core Karpenter reacts to. Out of stock and
+// quota errors are insufficient capacity so that other offerings are tried, permission and parameter errors can only
+// be fixed in the ECSNodeClass, and any other error, including throttling, is returned as is to be retried.
+func launchError(err error) error {
+	if cloudprovider.IsInsufficientCapacityError(err) || cloudprovider.IsNodeClassNotReadyError(err) {
+		return err
+	}
+	switch alierrors.Classify(err) {
+	case alierrors.KindInsufficientCapacity, alierrors.KindQuotaExceeded:
+		return cloudprovider.NewInsufficientCapacityError(err)
+	case alierrors.KindForbidden, alierrors.KindInvalidParameter:
+		return cloudprovider.NewNodeClassNotReadyError(err)
+	}
+	return err
+}
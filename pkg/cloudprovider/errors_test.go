@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+func TestLaunchError(t *testing.T) {
+	sdkError := func(code string, statusCode int) error {
+		return fmt.Errorf("creating instance, %w", tea.NewSDKError(map[string]interface{}{"code": code, "statusCode": statusCode}))
+	}
+	tests := []struct {
+		name                 string
+		err                  error
+		insufficientCapacity bool
+		nodeClassNotReady    bool
+	}{
+		{name: "no stock", err: sdkError("OperationDenied.NoStock", 403), insufficientCapacity: true},
+		{name: "quota exceeded", err: sdkError("QuotaExceed.PostPaidInstance", 403), insufficientCapacity: true},
+		{name: "ram forbidden", err: sdkError("Forbidden.RAM", 403), nodeClassNotReady: true},
+		{name: "invalid parameter", err: sdkError("InvalidParameter", 400), nodeClassNotReady: true},
+		{name: "throttling", err: sdkError("Throttling.User", 400)},
+		{name: "internal error", err: sdkError("InternalError", 500)},
+		{name: "non sdk error", err: errors.New("boom")},
+		{name: "already insufficient capacity", err: cloudprovider.NewInsufficientCapacityError(errors.New("boom")), insufficientCapacity: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := launchError(tt.err)
+			if got := cloudprovider.IsInsufficientCapacityError(err); got != tt.insufficientCapacity {
+				t.Errorf("IsInsufficientCapacityError() = %t, want %t", got, tt.insufficientCapacity)
+			}
+			if got := cloudprovider.IsNodeClassNotReadyError(err); got != tt.nodeClassNotReady {
+				t.Errorf("IsNodeClassNotReadyError() = %t, want %t", got, tt.nodeClassNotReady)
+			}
+		})
+	}
+}
@@ -26,19 +26,31 @@ import (
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/events"
 
+	nodeclaimattach "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/controllers/nodeclaim/attach"
+	nodeclaimeip "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/controllers/nodeclaim/eip"
 	nodeclaimgarbagecollection "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/controllers/nodeclaim/garbagecollection"
 	nodeclaimtagging "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/controllers/nodeclaim/tagging"
 	nodeclasshash "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/controllers/nodeclass/hash"
 	nodeclaasstatus "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/controllers/nodeclass/status"
 	nodeclasstermination "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/controllers/nodeclass/termination"
+	providersbootstraptoken "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/controllers/providers/bootstraptoken"
 	providersinstancetype "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/controllers/providers/instancetype"
 	controllerspricing "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/controllers/providers/pricing"
+	providersquota "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/controllers/providers/quota"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/acknodepool"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/bootstraptoken"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/capacityreservation"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/eip"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/imagefamily"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/instance"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/instancetype"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/keypair"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/pricing"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/quota"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/resourcegroup"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/securitygroup"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/vswitch"
+	alicloudclient "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
 )
 
 func NewControllers(ctx context.Context, mgr manager.Manager, clk clock.Clock,
@@ -47,16 +59,24 @@ func NewControllers(ctx context.Context, mgr manager.Manager, clk clock.Clock,
 	instanceProvider instance.Provider, instanceTypeProvider instancetype.Provider,
 	pricingProvider pricing.Provider,
 	vSwitchProvider vswitch.Provider, securitygroupProvider securitygroup.Provider,
-	imageProvider imagefamily.Provider) []controller.Controller {
+	imageProvider imagefamily.Provider, eipProvider eip.Provider,
+	keyPairProvider keypair.Provider, resourceGroupProvider resourcegroup.Provider,
+	capacityReservationProvider capacityreservation.Provider, quotaProvider quota.Provider,
+	bootstrapTokenProvider bootstraptoken.Provider, ackNodePoolProvider acknodepool.Provider,
+	ackapi alicloudclient.ACKAPI) []controller.Controller {
 
 	controllers := []controller.Controller{
-		nodeclasshash.NewController(kubeClient),
-		nodeclaasstatus.NewController(kubeClient, vSwitchProvider, securitygroupProvider, imageProvider),
-		nodeclasstermination.NewController(kubeClient, recorder),
+		nodeclasshash.NewController(kubeClient, recorder),
+		nodeclaasstatus.NewController(kubeClient, recorder, vSwitchProvider, securitygroupProvider, imageProvider, keyPairProvider, resourceGroupProvider, capacityReservationProvider, ackNodePoolProvider),
+		nodeclasstermination.NewController(kubeClient, recorder, securitygroupProvider),
 		controllerspricing.NewController(pricingProvider),
-		nodeclaimgarbagecollection.NewController(kubeClient, cloudProvider),
+		nodeclaimgarbagecollection.NewController(kubeClient, cloudProvider, eipProvider),
 		nodeclaimtagging.NewController(kubeClient, instanceProvider),
+		nodeclaimattach.NewController(kubeClient, recorder, instanceProvider, ackNodePoolProvider, ackapi),
+		nodeclaimeip.NewController(kubeClient, recorder, instanceProvider, eipProvider),
 		providersinstancetype.NewController(instanceTypeProvider),
+		providersquota.NewController(kubeClient, recorder, quotaProvider),
+		providersbootstraptoken.NewController(bootstrapTokenProvider),
 	}
 	return controllers
 }
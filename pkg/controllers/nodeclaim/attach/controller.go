@@ -0,0 +1,203 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attach
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/acknodepool"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/instance"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils"
+	alicloudclient "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+const (
+	// taskPollInterval is how often a running attach task is described
+	taskPollInterval = 15 * time.Second
+	// retryInterval delays attaching again after a failed attach task
+	retryInterval = time.Minute
+)
+
+// Controller attaches the instances of NodeClaims with the ack-nodepool bootstrap mode to their ACK node pool and
+// tracks the attach task in the InstanceAttached condition of the NodeClaim
+type Controller struct {
+	kubeClient          client.Client
+	recorder            events.Recorder
+	instanceProvider    instance.Provider
+	ackNodePoolProvider acknodepool.Provider
+	ackapi              alicloudclient.ACKAPI
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder, instanceProvider instance.Provider,
+	ackNodePoolProvider acknodepool.Provider, ackapi alicloudclient.ACKAPI) *Controller {
+	return &Controller{
+		kubeClient:          kubeClient,
+		recorder:            recorder,
+		instanceProvider:    instanceProvider,
+		ackNodePoolProvider: ackNodePoolProvider,
+		ackapi:              ackapi,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.attach")
+
+	if !isAttachable(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	nodeClass := &v1alpha1.ECSNodeClass{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if nodeClass.BootstrapMode() != v1alpha1.BootstrapModeACKNodePool {
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID))
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+
+	stored := nodeClaim.DeepCopy()
+	result, err := c.attach(ctx, nodeClaim, lo.FromPtr(nodeClass.Spec.Bootstrap.NodePoolID), id)
+	// The task ID is patched first so that a started task is never lost to a conflict of the status patch
+	if !equality.Semantic.DeepEqual(nodeClaim.Annotations, stored.Annotations) {
+		// Patch overwrites the NodeClaim with the response, which holds the stored status and the new resource version
+		status := nodeClaim.Status.DeepCopy()
+		if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+		stored = nodeClaim.DeepCopy()
+		nodeClaim.Status = *status
+	}
+	if !equality.Semantic.DeepEqual(nodeClaim.Status, stored.Status) {
+		// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+		// can cause races due to the fact that it fully replaces the list on a change
+		// Here, we are updating the status condition list
+		if err := c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+			if errors.IsConflict(err) {
+				return reconcile.Result{Requeue: true}, nil
+			}
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+	}
+	return result, err
+}
+
+// attach starts the attach task of the instance or follows up on the running one, a failed task is retried with a new
+// one after retryInterval
+func (c *Controller) attach(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePoolID, id string) (reconcile.Result, error) {
+	if taskID := nodeClaim.Annotations[v1alpha1.AnnotationACKAttachTaskID]; taskID != "" {
+		task, err := c.ackapi.DescribeTaskInfo(taskID)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("describing attach task %s, %w", taskID, err)
+		}
+		switch {
+		case task.State == alicloudclient.ACKTaskStateSuccess:
+			nodeClaim.StatusConditions().SetTrue(v1alpha1.ConditionTypeInstanceAttached)
+			return reconcile.Result{}, nil
+		case task.Failed():
+			// The failed task is kept until retryInterval passed since it failed, so that reconciles triggered by
+			// patching the NodeClaim don't start a new one early
+			if nodeClaim.StatusConditions().SetFalse(v1alpha1.ConditionTypeInstanceAttached, "AttachFailed", task.FailureMessage()) {
+				c.recorder.Publish(AttachFailedEvent(nodeClaim, nodePoolID, task.FailureMessage()))
+			}
+			failedAt := nodeClaim.StatusConditions().Get(v1alpha1.ConditionTypeInstanceAttached).LastTransitionTime.Time
+			if wait := retryInterval - time.Since(failedAt); wait > 0 {
+				return reconcile.Result{RequeueAfter: wait}, nil
+			}
+		default:
+			return reconcile.Result{RequeueAfter: taskPollInterval}, nil
+		}
+	}
+
+	// Only running instances can be attached
+	inst, err := c.instanceProvider.Get(ctx, id)
+	if err != nil {
+		return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(fmt.Errorf("getting instance, %w", err))
+	}
+	if inst.Status != instance.InstanceStatusRunning {
+		nodeClaim.StatusConditions().SetUnknownWithReason(v1alpha1.ConditionTypeInstanceAttached, "InstanceNotRunning", fmt.Sprintf("Instance is %s", inst.Status))
+		return reconcile.Result{RequeueAfter: taskPollInterval}, nil
+	}
+	clusterID, err := c.ackNodePoolProvider.ClusterID(ctx)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	taskID, err := c.ackapi.AttachInstancesToNodePool(clusterID, nodePoolID, []string{id})
+	if err != nil {
+		nodeClaim.StatusConditions().SetFalse(v1alpha1.ConditionTypeInstanceAttached, "AttachFailed", err.Error())
+		c.recorder.Publish(AttachFailedEvent(nodeClaim, nodePoolID, err.Error()))
+		return reconcile.Result{}, fmt.Errorf("attaching instance to node pool %s, %w", nodePoolID, err)
+	}
+	log.FromContext(ctx).WithValues("node-pool-id", nodePoolID, "task-id", taskID).Info("attaching instance to ACK node pool")
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1alpha1.AnnotationACKAttachTaskID: taskID})
+	nodeClaim.StatusConditions().SetUnknownWithReason(v1alpha1.ConditionTypeInstanceAttached, "Attaching", fmt.Sprintf("Attach task %s is running", taskID))
+	return reconcile.Result{RequeueAfter: taskPollInterval}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.attach").
+		For(&karpv1.NodeClaim{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isAttachable(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isAttachable(nc *karpv1.NodeClaim) bool {
+	// Instance is not launched yet
+	if nc.Status.ProviderID == "" || nc.Spec.NodeClassRef == nil {
+		return false
+	}
+	// Instance has already been attached or the node registered
+	if nc.StatusConditions().IsTrue(v1alpha1.ConditionTypeInstanceAttached) || nc.StatusConditions().IsTrue(karpv1.ConditionTypeRegistered) {
+		return false
+	}
+	// NodeClaim is currently terminating
+	if !nc.DeletionTimestamp.IsZero() {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attach
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/acknodepool"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/instance"
+	alicloudclient "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+// fakeACKAPI starts attach tasks and reports them in the state set by the test
+type fakeACKAPI struct {
+	alicloudclient.ACKAPI
+	attached []string
+	states   map[string]string
+}
+
+func (f *fakeACKAPI) AttachInstancesToNodePool(_, _ string, instanceIDs []string) (string, error) {
+	f.attached = append(f.attached, instanceIDs...)
+	taskID := fmt.Sprintf("task-%d", len(f.attached))
+	f.states[taskID] = alicloudclient.ACKTaskStateRunning
+	return taskID, nil
+}
+
+func (f *fakeACKAPI) DescribeTaskInfo(taskID string) (*alicloudclient.ACKTask, error) {
+	return &alicloudclient.ACKTask{TaskID: taskID, State: f.states[taskID]}, nil
+}
+
+type fakeInstanceProvider struct {
+	instance.Provider
+}
+
+func (f *fakeInstanceProvider) Get(_ context.Context, id string) (*instance.Instance, error) {
+	return &instance.Instance{ID: id, Status: instance.InstanceStatusRunning}, nil
+}
+
+type fakeACKNodePoolProvider struct {
+	acknodepool.Provider
+}
+
+func (f *fakeACKNodePoolProvider) ClusterID(context.Context) (string, error) {
+	return "c1", nil
+}
+
+type fakeRecorder struct {
+	events []events.Event
+}
+
+func (f *fakeRecorder) Publish(evts ...events.Event) {
+	f.events = append(f.events, evts...)
+}
+
+func newNodeClaim() *karpv1.NodeClaim {
+	return &karpv1.NodeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "default-abcde"},
+		Spec:       karpv1.NodeClaimSpec{NodeClassRef: &karpv1.NodeClassReference{Name: "default"}},
+		Status:     karpv1.NodeClaimStatus{ProviderID: "cn-hangzhou.i-1"},
+	}
+}
+
+func newNodeClass() *v1alpha1.ECSNodeClass {
+	return &v1alpha1.ECSNodeClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.ECSNodeClassSpec{Bootstrap: &v1alpha1.Bootstrap{
+			Mode:       lo.ToPtr(v1alpha1.BootstrapModeACKNodePool),
+			NodePoolID: lo.ToPtr("np1"),
+		}},
+	}
+}
+
+func newController(t *testing.T, funcs interceptor.Funcs) (*Controller, client.Client, *fakeACKAPI, *fakeRecorder) {
+	t.Helper()
+	kubeClient := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(newNodeClaim(), newNodeClass()).
+		WithStatusSubresource(&karpv1.NodeClaim{}).
+		WithInterceptorFuncs(funcs).
+		Build()
+	ackapi := &fakeACKAPI{states: map[string]string{}}
+	recorder := &fakeRecorder{}
+	return NewController(kubeClient, recorder, &fakeInstanceProvider{}, &fakeACKNodePoolProvider{}, ackapi), kubeClient, ackapi, recorder
+}
+
+// reconcileNodeClaim reconciles the stored NodeClaim and returns it as stored afterwards
+func reconcileNodeClaim(t *testing.T, c *Controller, kubeClient client.Client) (*karpv1.NodeClaim, time.Duration) {
+	t.Helper()
+	nodeClaim := &karpv1.NodeClaim{}
+	if err := kubeClient.Get(context.Background(), client.ObjectKey{Name: "default-abcde"}, nodeClaim); err != nil {
+		t.Fatalf("getting nodeclaim, %v", err)
+	}
+	result, err := c.Reconcile(context.Background(), nodeClaim)
+	if err != nil {
+		t.Fatalf("reconciling nodeclaim, %v", err)
+	}
+	if err := kubeClient.Get(context.Background(), client.ObjectKey{Name: "default-abcde"}, nodeClaim); err != nil {
+		t.Fatalf("getting nodeclaim, %v", err)
+	}
+	return nodeClaim, result.RequeueAfter
+}
+
+func TestReconcileAttach(t *testing.T) {
+	c, kubeClient, ackapi, _ := newController(t, interceptor.Funcs{})
+
+	// Starting the task stores both its ID and the condition
+	nodeClaim, requeueAfter := reconcileNodeClaim(t, c, kubeClient)
+	if len(ackapi.attached) != 1 || ackapi.attached[0] != "i-1" {
+		t.Fatalf("attached %v, want [i-1]", ackapi.attached)
+	}
+	if taskID := nodeClaim.Annotations[v1alpha1.AnnotationACKAttachTaskID]; taskID != "task-1" {
+		t.Errorf("stored task %q, want task-1", taskID)
+	}
+	if cond := nodeClaim.StatusConditions().Get(v1alpha1.ConditionTypeInstanceAttached); cond == nil || !cond.IsUnknown() || cond.Reason != "Attaching" {
+		t.Errorf("stored condition %v, want attaching", cond)
+	}
+	if requeueAfter != taskPollInterval {
+		t.Errorf("requeued after %s, want %s", requeueAfter, taskPollInterval)
+	}
+
+	// The running task is polled without starting another one
+	if _, requeueAfter = reconcileNodeClaim(t, c, kubeClient); requeueAfter != taskPollInterval {
+		t.Errorf("requeued after %s, want %s", requeueAfter, taskPollInterval)
+	}
+	if len(ackapi.attached) != 1 {
+		t.Errorf("attached %v while the task was running, want a single attach", ackapi.attached)
+	}
+
+	ackapi.states["task-1"] = alicloudclient.ACKTaskStateSuccess
+	nodeClaim, requeueAfter = reconcileNodeClaim(t, c, kubeClient)
+	if !nodeClaim.StatusConditions().IsTrue(v1alpha1.ConditionTypeInstanceAttached) {
+		t.Errorf("stored condition %v, want attached", nodeClaim.StatusConditions().Get(v1alpha1.ConditionTypeInstanceAttached))
+	}
+	if requeueAfter != 0 {
+		t.Errorf("requeued after %s, want no requeue", requeueAfter)
+	}
+	if isAttachable(nodeClaim) {
+		t.Errorf("attached nodeclaim is still attachable")
+	}
+}
+
+func TestReconcileAttachFailed(t *testing.T) {
+	c, kubeClient, ackapi, recorder := newController(t, interceptor.Funcs{})
+	reconcileNodeClaim(t, c, kubeClient)
+	ackapi.states["task-1"] = alicloudclient.ACKTaskStateFailed
+
+	nodeClaim, requeueAfter := reconcileNodeClaim(t, c, kubeClient)
+	if cond := nodeClaim.StatusConditions().Get(v1alpha1.ConditionTypeInstanceAttached); cond == nil || !cond.IsFalse() || cond.Reason != "AttachFailed" {
+		t.Errorf("stored condition %v, want attach failed", cond)
+	}
+	if len(recorder.events) != 1 || recorder.events[0].Reason != "AttachFailed" {
+		t.Errorf("published %v, want a single attach failed event", recorder.events)
+	}
+	if requeueAfter <= 0 || requeueAfter > retryInterval {
+		t.Errorf("requeued after %s, want up to %s", requeueAfter, retryInterval)
+	}
+
+	// Reconciles before retryInterval passed, e.g. triggered by the patches, don't start a new task
+	if _, requeueAfter = reconcileNodeClaim(t, c, kubeClient); requeueAfter <= 0 || requeueAfter > retryInterval {
+		t.Errorf("requeued after %s, want up to %s", requeueAfter, retryInterval)
+	}
+	if len(ackapi.attached) != 1 {
+		t.Errorf("attached %v before retryInterval passed, want a single attach", ackapi.attached)
+	}
+	if len(recorder.events) != 1 {
+		t.Errorf("published %d events, want the failure published once", len(recorder.events))
+	}
+
+	// A new task is started once retryInterval passed
+	for i := range nodeClaim.Status.Conditions {
+		if nodeClaim.Status.Conditions[i].Type == v1alpha1.ConditionTypeInstanceAttached {
+			nodeClaim.Status.Conditions[i].LastTransitionTime = metav1.NewTime(time.Now().Add(-retryInterval))
+		}
+	}
+	if err := kubeClient.Status().Update(context.Background(), nodeClaim); err != nil {
+		t.Fatalf("updating nodeclaim, %v", err)
+	}
+	nodeClaim, _ = reconcileNodeClaim(t, c, kubeClient)
+	if len(ackapi.attached) != 2 {
+		t.Errorf("attached %v after retryInterval passed, want a second attach", ackapi.attached)
+	}
+	if taskID := nodeClaim.Annotations[v1alpha1.AnnotationACKAttachTaskID]; taskID != "task-2" {
+		t.Errorf("stored task %q, want task-2", taskID)
+	}
+}
+
+func TestReconcileAttachConflict(t *testing.T) {
+	c, kubeClient, ackapi, _ := newController(t, interceptor.Funcs{
+		SubResourcePatch: func(_ context.Context, _ client.Client, _ string, obj client.Object, _ client.Patch, _ ...client.SubResourcePatchOption) error {
+			return apierrors.NewConflict(schema.GroupResource{Group: "karpenter.sh", Resource: "nodeclaims"}, obj.GetName(), nil)
+		},
+	})
+	nodeClaim := &karpv1.NodeClaim{}
+	if err := kubeClient.Get(context.Background(), client.ObjectKey{Name: "default-abcde"}, nodeClaim); err != nil {
+		t.Fatalf("getting nodeclaim, %v", err)
+	}
+	result, err := c.Reconcile(context.Background(), nodeClaim)
+	if err != nil {
+		t.Fatalf("reconciling nodeclaim, %v", err)
+	}
+	if !result.Requeue {
+		t.Errorf("result %v, want a requeue after the conflict", result)
+	}
+
+	// The started task is kept despite the conflict and polled instead of starting another one
+	nodeClaim, _ = reconcileNodeClaim(t, c, kubeClient)
+	if taskID := nodeClaim.Annotations[v1alpha1.AnnotationACKAttachTaskID]; taskID != "task-1" {
+		t.Errorf("stored task %q, want task-1", taskID)
+	}
+	if len(ackapi.attached) != 1 {
+		t.Errorf("attached %v after the conflict, want a single attach", ackapi.attached)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eip
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/eip"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/instance"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils"
+)
+
+// instancePollInterval is how often an instance that isn't running yet is described, EIPs can only be bound to
+// running or stopped instances
+const instancePollInterval = 5 * time.Second
+
+// Controller binds an EIP from the pool of the ECSNodeClass to the instances of NodeClaims once they're running, so
+// that launches don't wait for the instance to start
+type Controller struct {
+	kubeClient       client.Client
+	recorder         events.Recorder
+	instanceProvider instance.Provider
+	eipProvider      eip.Provider
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder, instanceProvider instance.Provider, eipProvider eip.Provider) *Controller {
+	return &Controller{
+		kubeClient:       kubeClient,
+		recorder:         recorder,
+		instanceProvider: instanceProvider,
+		eipProvider:      eipProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.eip")
+
+	if !isAssociable(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	nodeClass := &v1alpha1.ECSNodeClass{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if nodeClass.Spec.PublicIP == nil || len(nodeClass.Spec.PublicIP.EIPPoolTags) == 0 {
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID))
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+
+	inst, err := c.instanceProvider.Get(ctx, id)
+	if err != nil {
+		return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(fmt.Errorf("getting instance, %w", err))
+	}
+	if inst.Status != instance.InstanceStatusRunning {
+		return reconcile.Result{RequeueAfter: instancePollInterval}, nil
+	}
+	if err := c.eipProvider.Associate(ctx, nodeClass, id); err != nil {
+		c.recorder.Publish(AssociateFailedEvent(nodeClaim, err.Error()))
+		return reconcile.Result{}, fmt.Errorf("associating eip, %w", err)
+	}
+
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1alpha1.AnnotationEIPAssociated: "true"})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.eip").
+		For(&karpv1.NodeClaim{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isAssociable(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isAssociable(nc *karpv1.NodeClaim) bool {
+	// Instance is not launched yet
+	if nc.Status.ProviderID == "" || nc.Spec.NodeClassRef == nil {
+		return false
+	}
+	// Instance already got its EIP
+	if nc.Annotations[v1alpha1.AnnotationEIPAssociated] == "true" {
+		return false
+	}
+	// NodeClaim is currently terminating
+	if !nc.DeletionTimestamp.IsZero() {
+		return false
+	}
+	return true
+}
@@ -36,18 +36,23 @@ import (
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/eip"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils"
 )
 
 type Controller struct {
 	kubeClient      client.Client
 	cloudProvider   cloudprovider.CloudProvider
+	eipProvider     eip.Provider
 	successfulCount uint64 // keeps track of successful reconciles for more aggressive requeueing near the start of the controller
 }
 
-func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *Controller {
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, eipProvider eip.Provider) *Controller {
 	return &Controller{
 		kubeClient:      kubeClient,
 		cloudProvider:   cloudProvider,
+		eipProvider:     eipProvider,
 		successfulCount: 0,
 	}
 }
@@ -55,6 +60,11 @@ func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudPr
 func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	ctx = injection.WithControllerName(ctx, "instance.garbagecollection")
 
+	// We LIST EIPs BEFORE instances so that an EIP can only be bound to an instance that is already part of the instance LIST
+	eips, err := c.eipProvider.List(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing eips, %w", err)
+	}
 	// We LIST machines on the CloudProvider BEFORE we grab Machines/Nodes on the cluster so that we make sure that, if
 	// LISTing instances takes a long time, our information is more updated by the time we get to Machine and Node LIST
 	// This works since our CloudProvider instances are deleted based on whether the Machine exists or not, not vise-versa
@@ -86,6 +96,9 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	if err = multierr.Combine(errs...); err != nil {
 		return reconcile.Result{}, err
 	}
+	if err = c.garbageCollectEIPs(ctx, eips, retrieved); err != nil {
+		return reconcile.Result{}, err
+	}
 	c.successfulCount++
 	return reconcile.Result{RequeueAfter: lo.Ternary(c.successfulCount <= 20, time.Second*10, time.Minute*2)}, nil
 }
@@ -109,6 +122,24 @@ func (c *Controller) garbageCollect(ctx context.Context, nodeClaim *karpv1.NodeC
 	return nil
 }
 
+// garbageCollectEIPs returns EIPs to their pool when the instance they were bound to no longer exists
+func (c *Controller) garbageCollectEIPs(ctx context.Context, eips []*eip.EIP, retrieved []*karpv1.NodeClaim) error {
+	instanceIDs := sets.New[string](lo.FilterMap(retrieved, func(nc *karpv1.NodeClaim, _ int) (string, bool) {
+		id, err := utils.ParseInstanceID(nc.Status.ProviderID)
+		return id, err == nil
+	})...)
+	leaked := lo.Filter(eips, func(e *eip.EIP, _ int) bool {
+		return !instanceIDs.Has(e.InstanceID) && !instanceIDs.Has(e.AssociatedInstanceID)
+	})
+	errs := make([]error, len(leaked))
+	workqueue.ParallelizeUntil(ctx, 10, len(leaked), func(i int) {
+		if errs[i] = c.eipProvider.Release(ctx, leaked[i]); errs[i] == nil {
+			log.FromContext(ctx).WithValues("allocation-id", leaked[i].AllocationID, "instance", leaked[i].InstanceID).V(1).Info("garbage collected eip")
+		}
+	})
+	return multierr.Combine(errs...)
+}
+
 func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 	return controllerruntime.NewControllerManagedBy(m).
 		Named("instance.garbagecollection").
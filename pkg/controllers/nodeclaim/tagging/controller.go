@@ -19,7 +19,6 @@ package tagging
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/awslabs/operatorpkg/reasonable"
 	"github.com/samber/lo"
@@ -89,7 +88,7 @@ func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
 			return isTaggable(o.(*karpv1.NodeClaim))
 		})).
-		// Ok with using the default MaxConcurrentReconciles of 1 to avoid throttling from CreateTag write API
+		// Ok with using the default MaxConcurrentReconciles of 1 to avoid throttling from AddTags write API
 		WithOptions(controller.Options{
 			RateLimiter: reasonable.RateLimiter(),
 		}).
@@ -113,9 +112,7 @@ func (c *Controller) tagInstance(ctx context.Context, nc *karpv1.NodeClaim, id s
 		return nil
 	}
 
-	// Ensures that no more than 1 CreateTags call is made per second. Rate limiting is required since CreateTags
-	// shares a pool with other mutating calls (e.g. CreateFleet).
-	defer time.Sleep(time.Second)
+	// AddTags is rate limited by the shared ECS client, throttled calls are retried with backoff
 	if err := c.instanceProvider.CreateTags(ctx, id, tags); err != nil {
 		return fmt.Errorf("tagging nodeclaim, %w", err)
 	}
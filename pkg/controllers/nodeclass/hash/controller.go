@@ -29,6 +29,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
@@ -36,11 +37,13 @@ import (
 
 type Controller struct {
 	kubeClient client.Client
+	recorder   events.Recorder
 }
 
-func NewController(kubeClient client.Client) *Controller {
+func NewController(kubeClient client.Client, recorder events.Recorder) *Controller {
 	return &Controller{
 		kubeClient: kubeClient,
+		recorder:   recorder,
 	}
 }
 
@@ -53,6 +56,12 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeC
 		if err := c.updateNodeClaimHash(ctx, nodeClass); err != nil {
 			return reconcile.Result{}, err
 		}
+		// ECSNodeClasses hashed before v2 were launched without metadata options, existing nodes keep the normal mode
+		// while new nodes require tokens
+		if version, ok := nodeClass.Annotations[v1alpha1.AnnotationECSNodeClassHashVersion]; ok && version == "v1" &&
+			nodeClass.Spec.MetadataOptions != nil && lo.FromPtr(nodeClass.Spec.MetadataOptions.HTTPTokens) == "required" {
+			c.recorder.Publish(MetadataTokensRequiredEvent(nodeClass))
+		}
 	}
 	nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
 		v1alpha1.AnnotationECSNodeClassHash:        nodeClass.Hash(),
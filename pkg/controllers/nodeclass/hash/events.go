@@ -0,0 +1,35 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hash
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+func MetadataTokensRequiredEvent(nodeClass *v1alpha1.ECSNodeClass) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "MetadataTokensRequired",
+		Message: "metadataOptions now defaults to httpTokens=required, new nodes reject metadata requests without a token. " +
+			"Existing nodes are not drifted. Set spec.metadataOptions.httpTokens=optional to keep the normal mode",
+		DedupeValues: []string{string(nodeClass.UID)},
+	}
+}
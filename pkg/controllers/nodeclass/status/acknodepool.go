@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/acknodepool"
+)
+
+type ACKNodePool struct {
+	ackNodePoolProvider acknodepool.Provider
+}
+
+// Reconcile verifies that the ACK node pool registers its nodes with the unregistered taint. Karpenter doesn't
+// register nodes without it, the labels and taints of the NodeClaim are synced to the node once it registers.
+func (a *ACKNodePool) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (reconcile.Result, error) {
+	if nodeClass.BootstrapMode() != v1alpha1.BootstrapModeACKNodePool {
+		nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeACKNodePoolReady)
+		return reconcile.Result{}, nil
+	}
+	nodePoolID := lo.FromPtr(nodeClass.Spec.Bootstrap.NodePoolID)
+	nodePool, err := a.ackNodePoolProvider.Get(ctx, nodePoolID)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting ACK node pool, %w", err)
+	}
+	if !lo.ContainsBy(nodePool.Taints(), func(t corev1.Taint) bool { return t.MatchTaint(&karpv1.UnregisteredNoExecuteTaint) }) {
+		nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeACKNodePoolReady, "UnregisteredTaintNotFound",
+			fmt.Sprintf("ACK node pool %q doesn't register its nodes with the %s taint", nodePoolID, karpv1.UnregisteredNoExecuteTaint.ToString()))
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeACKNodePoolReady)
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	alicache "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/cache"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/acknodepool"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+// fakeACKAPI describes the node pools of the cluster
+type fakeACKAPI struct {
+	client.ACKAPI
+	nodePools map[string]*client.ACKNodePool
+}
+
+func (f *fakeACKAPI) DescribeNodePool(_, nodePoolID string) (*client.ACKNodePool, error) {
+	return f.nodePools[nodePoolID], nil
+}
+
+func TestACKNodePoolRegistrationTaint(t *testing.T) {
+	ctx := options.ToContext(context.Background(), &options.Options{ClusterID: "c1"})
+	tainted := &client.ACKNodePool{}
+	tainted.KubernetesConfig.Taints = []client.ACKTaint{{
+		Key:    karpv1.UnregisteredTaintKey,
+		Effect: string(karpv1.UnregisteredNoExecuteTaint.Effect),
+	}}
+	noSchedule := &client.ACKNodePool{}
+	noSchedule.KubernetesConfig.Taints = []client.ACKTaint{{Key: karpv1.UnregisteredTaintKey, Effect: "NoSchedule"}}
+	ackapi := &fakeACKAPI{nodePools: map[string]*client.ACKNodePool{"np1": tainted, "np2": noSchedule, "np3": {}}}
+	reconciler := &ACKNodePool{ackNodePoolProvider: acknodepool.NewDefaultProvider(ackapi, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))}
+
+	for _, tc := range []struct {
+		name      string
+		bootstrap *v1alpha1.Bootstrap
+		ready     bool
+	}{
+		{name: "user data bootstrap doesn't need a node pool", ready: true},
+		{name: "node pool registering nodes with the unregistered taint", bootstrap: &v1alpha1.Bootstrap{Mode: lo.ToPtr(v1alpha1.BootstrapModeACKNodePool), NodePoolID: lo.ToPtr("np1")}, ready: true},
+		{name: "node pool with another effect of the unregistered taint", bootstrap: &v1alpha1.Bootstrap{Mode: lo.ToPtr(v1alpha1.BootstrapModeACKNodePool), NodePoolID: lo.ToPtr("np2")}},
+		{name: "node pool without taints", bootstrap: &v1alpha1.Bootstrap{Mode: lo.ToPtr(v1alpha1.BootstrapModeACKNodePool), NodePoolID: lo.ToPtr("np3")}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			nodeClass := &v1alpha1.ECSNodeClass{Spec: v1alpha1.ECSNodeClassSpec{Bootstrap: tc.bootstrap}}
+			if _, err := reconciler.Reconcile(ctx, nodeClass); err != nil {
+				t.Fatalf("reconciling node pool, %v", err)
+			}
+			if ready := nodeClass.StatusConditions().IsTrue(v1alpha1.ConditionTypeACKNodePoolReady); ready != tc.ready {
+				t.Errorf("%s = %v, want %v", v1alpha1.ConditionTypeACKNodePoolReady, ready, tc.ready)
+			}
+		})
+	}
+}
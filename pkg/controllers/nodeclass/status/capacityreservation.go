@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/capacityreservation"
+)
+
+type CapacityReservation struct {
+	capacityReservationProvider capacityreservation.Provider
+}
+
+func (c *CapacityReservation) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (reconcile.Result, error) {
+	if len(nodeClass.Spec.CapacityReservationSelectorTerms) == 0 {
+		nodeClass.Status.CapacityReservations = nil
+		return reconcile.Result{}, nil
+	}
+	reservations, err := c.capacityReservationProvider.List(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting capacity reservations, %w", err)
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		if reservations[i].ID != reservations[j].ID {
+			return reservations[i].ID < reservations[j].ID
+		}
+		if reservations[i].ZoneID != reservations[j].ZoneID {
+			return reservations[i].ZoneID < reservations[j].ZoneID
+		}
+		return reservations[i].InstanceType < reservations[j].InstanceType
+	})
+	nodeClass.Status.CapacityReservations = reservations
+	// Reserved amounts change with every launch, so they are refreshed more often than other resources
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
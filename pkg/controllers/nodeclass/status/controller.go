@@ -29,11 +29,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/events"
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
 	"sigs.k8s.io/karpenter/pkg/utils/result"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/acknodepool"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/capacityreservation"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/imagefamily"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/keypair"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/resourcegroup"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/securitygroup"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/vswitch"
 )
@@ -47,17 +52,33 @@ type Controller struct {
 
 	vSwitch       *VSwitch
 	securitygroup *SecurityGroup
+	vpc           *VPC
 	image         *Image
+	keyPair       *KeyPair
+	resourceGroup *ResourceGroup
+	ackNodePool   *ACKNodePool
+	userData      *UserData
+
+	capacityReservation *CapacityReservation
 }
 
-func NewController(kubeClient client.Client, vSwitchProvider vswitch.Provider,
-	securitygroupProvider securitygroup.Provider, imageProvider imagefamily.Provider) *Controller {
+func NewController(kubeClient client.Client, recorder events.Recorder, vSwitchProvider vswitch.Provider,
+	securitygroupProvider securitygroup.Provider, imageProvider imagefamily.Provider,
+	keyPairProvider keypair.Provider, resourceGroupProvider resourcegroup.Provider,
+	capacityReservationProvider capacityreservation.Provider, ackNodePoolProvider acknodepool.Provider) *Controller {
 	return &Controller{
 		kubeClient: kubeClient,
 
-		vSwitch:       &VSwitch{vSwitchProvider: vSwitchProvider},
+		vSwitch:       &VSwitch{vSwitchProvider: vSwitchProvider, recorder: recorder},
 		securitygroup: &SecurityGroup{securityGroupProvider: securitygroupProvider},
+		vpc:           &VPC{},
 		image:         &Image{imageProvider: imageProvider},
+		keyPair:       &KeyPair{keyPairProvider: keyPairProvider},
+		resourceGroup: &ResourceGroup{resourceGroupProvider: resourceGroupProvider},
+		ackNodePool:   &ACKNodePool{ackNodePoolProvider: ackNodePoolProvider},
+		userData:      &UserData{},
+
+		capacityReservation: &CapacityReservation{capacityReservationProvider: capacityReservationProvider},
 	}
 }
 
@@ -88,7 +109,13 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeC
 	for _, reconciler := range []nodeClassStatusReconciler{
 		c.vSwitch,
 		c.securitygroup,
+		c.vpc,
 		c.image,
+		c.keyPair,
+		c.resourceGroup,
+		c.ackNodePool,
+		c.userData,
+		c.capacityReservation,
 	} {
 		res, err := reconciler.Reconcile(ctx, nodeClass)
 		errs = multierr.Append(errs, err)
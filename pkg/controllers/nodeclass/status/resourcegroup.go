@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/resourcegroup"
+)
+
+type ResourceGroup struct {
+	resourceGroupProvider resourcegroup.Provider
+}
+
+func (r *ResourceGroup) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (reconcile.Result, error) {
+	if nodeClass.Spec.ResourceGroupID == nil {
+		nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeResourceGroupReady)
+		return reconcile.Result{}, nil
+	}
+	exists, err := r.resourceGroupProvider.Exists(ctx, *nodeClass.Spec.ResourceGroupID)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting resource group, %w", err)
+	}
+	if !exists {
+		nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeResourceGroupReady, "ResourceGroupNotFound", fmt.Sprintf("ResourceGroup %q does not exist", *nodeClass.Spec.ResourceGroupID))
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeResourceGroupReady)
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
@@ -44,15 +45,69 @@ func (sg *SecurityGroup) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSN
 		nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeSecurityGroupsReady, "SecurityGroupsNotFound", "SecurityGroupSelector did not match any SecurityGroups")
 		return reconcile.Result{}, nil
 	}
+	// The managed security group is only created for live ECSNodeClasses, the termination controller takes care of deleting it
+	if nodeClass.Spec.CreateSecurityGroup && nodeClass.DeletionTimestamp.IsZero() {
+		if len(nodeClass.Status.VSwitches) == 0 || nodeClass.Status.VSwitches[0].VPCID == "" {
+			nodeClass.Status.SecurityGroups = nil
+			nodeClass.StatusConditions().SetUnknownWithReason(v1alpha1.ConditionTypeSecurityGroupsReady, "AwaitingResolution", "Waiting for the VPC of the vSwitches to be resolved")
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+		managed, err := sg.securityGroupProvider.EnsureManaged(ctx, nodeClass, nodeClass.Status.VSwitches[0].VPCID)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("ensuring managed security group, %w", err)
+		}
+		if managed != nil && !lo.ContainsBy(securityGroups, func(s *ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup) bool {
+			return lo.FromPtr(s.SecurityGroupId) == lo.FromPtr(managed.SecurityGroupId)
+		}) {
+			securityGroups = append(securityGroups, managed)
+		}
+	}
 	sort.Slice(securityGroups, func(i, j int) bool {
 		return *securityGroups[i].SecurityGroupId < *securityGroups[j].SecurityGroupId
 	})
 	nodeClass.Status.SecurityGroups = lo.Map(securityGroups, func(securityGroup *ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, _ int) v1alpha1.SecurityGroup {
 		return v1alpha1.SecurityGroup{
-			ID:   *securityGroup.SecurityGroupId,
-			Name: *securityGroup.SecurityGroupName,
+			ID:    *securityGroup.SecurityGroupId,
+			Name:  lo.FromPtr(securityGroup.SecurityGroupName),
+			VPCID: lo.FromPtr(securityGroup.VpcId),
+
+			ECSCount:                lo.FromPtr(securityGroup.EcsCount),
+			AvailableInstanceAmount: lo.FromPtr(securityGroup.AvailableInstanceAmount),
 		}
 	})
 	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeSecurityGroupsReady)
+	setSecurityGroupsCapacityCondition(nodeClass, securityGroups)
 	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
 }
+
+// setSecurityGroupsCapacityCondition blocks launches when any security group is full, instances join every security
+// group so none can be launched, and warns when one is close to its limit
+func setSecurityGroupsCapacityCondition(nodeClass *v1alpha1.ECSNodeClass, securityGroups []*ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup) {
+	// Groups without an AvailableInstanceAmount haven't reported their capacity and are never treated as full
+	reported := lo.Filter(securityGroups, func(securityGroup *ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, _ int) bool {
+		return securityGroup.AvailableInstanceAmount != nil
+	})
+	full := lo.Filter(reported, func(securityGroup *ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, _ int) bool {
+		return lo.FromPtr(securityGroup.AvailableInstanceAmount) <= 0
+	})
+	if len(full) > 0 {
+		nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeSecurityGroupsCapacityAvailable, "SecurityGroupsFull",
+			fmt.Sprintf("SecurityGroups %s have reached their instance limit", securityGroupIDs(full)))
+		return
+	}
+	low := lo.Filter(reported, func(securityGroup *ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, _ int) bool {
+		return lo.FromPtr(securityGroup.AvailableInstanceAmount) <= securitygroup.LowCapacityThreshold
+	})
+	if len(low) > 0 {
+		nodeClass.StatusConditions().SetTrueWithReason(v1alpha1.ConditionTypeSecurityGroupsCapacityAvailable, "SecurityGroupsCapacityLow",
+			fmt.Sprintf("SecurityGroups %s can accept at most %d more instances", securityGroupIDs(low), securitygroup.LowCapacityThreshold))
+		return
+	}
+	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeSecurityGroupsCapacityAvailable)
+}
+
+func securityGroupIDs(securityGroups []*ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup) string {
+	return strings.Join(lo.Map(securityGroups, func(securityGroup *ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, _ int) string {
+		return lo.FromPtr(securityGroup.SecurityGroupId)
+	}), ", ")
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/imagefamily"
+)
+
+type UserData struct{}
+
+func (u *UserData) Reconcile(_ context.Context, nodeClass *v1alpha1.ECSNodeClass) (reconcile.Result, error) {
+	if err := imagefamily.ValidateUserData(nodeClass); err != nil {
+		nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeUserDataReady, "InvalidUserData", err.Error())
+		return reconcile.Result{}, nil
+	}
+	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeUserDataReady)
+	return reconcile.Result{}, nil
+}
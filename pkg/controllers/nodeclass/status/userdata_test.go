@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+func TestUserData(t *testing.T) {
+	invalid := lo.ToPtr("#cloud-config\npackages: [jq\n")
+	for _, tc := range []struct {
+		name             string
+		alias            string
+		bootstrap        *v1alpha1.Bootstrap
+		userData         *string
+		hostNameTemplate *string
+		ready            bool
+	}{
+		{name: "no user data", alias: v1alpha1.ImageFamilyAlibabaCloudLinux3, ready: true},
+		{name: "cloud-config setting bootstrap keys", alias: v1alpha1.ImageFamilyAlibabaCloudLinux3, userData: lo.ToPtr("#cloud-config\nruncmd:\n- echo custom\nhostname: custom\n"), ready: true},
+		{name: "invalid cloud-config", alias: v1alpha1.ImageFamilyAlibabaCloudLinux3, userData: invalid},
+		{name: "cloud-config conflicting with the host name", alias: v1alpha1.ImageFamilyUbuntu2204, userData: lo.ToPtr("#cloud-config\npreserve_hostname: false\n"), hostNameTemplate: lo.ToPtr("{{.NodeClaim}}")},
+		{name: "cloud-config without a host name template", alias: v1alpha1.ImageFamilyUbuntu2204, userData: lo.ToPtr("#cloud-config\npreserve_hostname: false\n"), ready: true},
+		{name: "user data of custom images isn't merged", userData: invalid, ready: true},
+		{name: "user data of windows isn't merged", alias: v1alpha1.ImageFamilyWindows2022, userData: invalid, ready: true},
+		{name: "user data of the ack-nodepool mode isn't merged", alias: v1alpha1.ImageFamilyAlibabaCloudLinux3, userData: invalid, ready: true,
+			bootstrap: &v1alpha1.Bootstrap{Mode: lo.ToPtr(v1alpha1.BootstrapModeACKNodePool), NodePoolID: lo.ToPtr("np1")}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			nodeClass := &v1alpha1.ECSNodeClass{Spec: v1alpha1.ECSNodeClassSpec{
+				ImageSelectorTerms: []v1alpha1.ImageSelectorTerm{{Alias: tc.alias, ID: lo.Ternary(tc.alias == "", "m-1", "")}},
+				Bootstrap:          tc.bootstrap,
+				UserData:           tc.userData,
+				HostNameTemplate:   tc.hostNameTemplate,
+			}}
+			if _, err := (&UserData{}).Reconcile(context.Background(), nodeClass); err != nil {
+				t.Fatalf("reconciling user data, %v", err)
+			}
+			if ready := nodeClass.StatusConditions().IsTrue(v1alpha1.ConditionTypeUserDataReady); ready != tc.ready {
+				t.Errorf("%s = %v, want %v", v1alpha1.ConditionTypeUserDataReady, ready, tc.ready)
+			}
+		})
+	}
+}
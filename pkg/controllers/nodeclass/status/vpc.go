@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils"
+)
+
+// VPC validates that the resolved vSwitches and security groups belong to the same VPC.
+// It relies on the vSwitch and security group reconcilers having already populated the status.
+type VPC struct{}
+
+func (v *VPC) Reconcile(_ context.Context, nodeClass *v1alpha1.ECSNodeClass) (reconcile.Result, error) {
+	if len(nodeClass.Status.VSwitches) == 0 || len(nodeClass.Status.SecurityGroups) == 0 {
+		nodeClass.Status.VPCID = ""
+		nodeClass.StatusConditions().SetUnknownWithReason(v1alpha1.ConditionTypeVPCConsistent, "AwaitingResolution", "Waiting for vSwitches and security groups to be resolved")
+		return reconcile.Result{}, nil
+	}
+
+	vSwitchVPCs := lo.Uniq(lo.Map(nodeClass.Status.VSwitches, func(s v1alpha1.VSwitch, _ int) string { return s.VPCID }))
+	securityGroupVPCs := lo.Uniq(lo.Map(nodeClass.Status.SecurityGroups, func(s v1alpha1.SecurityGroup, _ int) string { return s.VPCID }))
+	if len(vSwitchVPCs) == 1 && len(securityGroupVPCs) == 1 && vSwitchVPCs[0] == securityGroupVPCs[0] && vSwitchVPCs[0] != "" {
+		nodeClass.Status.VPCID = vSwitchVPCs[0]
+		nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeVPCConsistent)
+		return reconcile.Result{}, nil
+	}
+
+	nodeClass.Status.VPCID = ""
+	nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeVPCConsistent, "VPCMismatch", vpcMismatchMessage(nodeClass))
+	return reconcile.Result{}, nil
+}
+
+// vpcMismatchMessage lists every resolved vSwitch and security group grouped by VPC so that users can tell
+// which selector term picked up a resource from the wrong VPC
+func vpcMismatchMessage(nodeClass *v1alpha1.ECSNodeClass) string {
+	byVPC := map[string][]string{}
+	for _, s := range nodeClass.Status.VSwitches {
+		byVPC[s.VPCID] = append(byVPC[s.VPCID], s.ID)
+	}
+	for _, s := range nodeClass.Status.SecurityGroups {
+		byVPC[s.VPCID] = append(byVPC[s.VPCID], s.ID)
+	}
+	vpcs := lo.Keys(byVPC)
+	sort.Strings(vpcs)
+	return fmt.Sprintf("vSwitches and security groups must belong to a single VPC, found %s", utils.PrettySlice(lo.Map(vpcs, func(vpc string, _ int) string {
+		return fmt.Sprintf("%s: [%s]", lo.Ternary(vpc == "", "<no vpc>", vpc), utils.PrettySlice(byVPC[vpc], 5))
+	}), 5))
+}
@@ -25,6 +25,7 @@ import (
 	vpc "github.com/alibabacloud-go/vpc-20160428/v6/client"
 	"github.com/samber/lo"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/events"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/vswitch"
@@ -32,6 +33,7 @@ import (
 
 type VSwitch struct {
 	vSwitchProvider vswitch.Provider
+	recorder        events.Recorder
 }
 
 func (v *VSwitch) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (reconcile.Result, error) {
@@ -39,6 +41,14 @@ func (v *VSwitch) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeClas
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("getting vSwitches, %w", err)
 	}
+	matches := lo.Map(nodeClass.Spec.VSwitchSelectorTerms, func(term v1alpha1.VSwitchSelectorTerm, _ int) int {
+		return lo.CountBy(vSwitches, func(vSwitch *vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch) bool {
+			return vswitch.MatchesSelectorTerm(term, vSwitch)
+		})
+	})
+	if lo.Contains(matches, 0) {
+		v.recorder.Publish(VSwitchSelectorTermsMatchedNothingEvent(nodeClass, matches))
+	}
 	if len(vSwitches) == 0 {
 		nodeClass.Status.VSwitches = nil
 		nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeVSwitchesReady, "vSwitchesNotFound", "VSwitchSelector did not match any VSwitches")
@@ -54,6 +64,7 @@ func (v *VSwitch) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeClas
 		return v1alpha1.VSwitch{
 			ID:     *ecsvSwitch.VSwitchId,
 			ZoneID: *ecsvSwitch.ZoneId,
+			VPCID:  lo.FromPtr(ecsvSwitch.VpcId),
 		}
 	})
 	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeVSwitchesReady)
@@ -41,17 +41,20 @@ import (
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/securitygroup"
 )
 
 type Controller struct {
-	kubeClient client.Client
-	recorder   events.Recorder
+	kubeClient            client.Client
+	recorder              events.Recorder
+	securityGroupProvider securitygroup.Provider
 }
 
-func NewController(kubeClient client.Client, recorder events.Recorder) *Controller {
+func NewController(kubeClient client.Client, recorder events.Recorder, securityGroupProvider securitygroup.Provider) *Controller {
 	return &Controller{
-		kubeClient: kubeClient,
-		recorder:   recorder,
+		kubeClient:            kubeClient,
+		recorder:              recorder,
+		securityGroupProvider: securityGroupProvider,
 	}
 }
 
@@ -77,6 +80,12 @@ func (c *Controller) finalize(ctx context.Context, nodeClass *v1alpha1.ECSNodeCl
 		c.recorder.Publish(WaitingOnNodeClaimTerminationEvent(nodeClass, lo.Map(nodeClaimList.Items, func(nc karpv1.NodeClaim, _ int) string { return nc.Name })))
 		return reconcile.Result{RequeueAfter: time.Minute * 10}, nil // periodically fire the event
 	}
+	// The owned security group is looked up by its tags, it may have been created before createSecurityGroup was
+	// turned off. Instances release the security group asynchronously after their NodeClaims are gone
+	if err := c.securityGroupProvider.DeleteManaged(ctx, nodeClass); err != nil {
+		c.recorder.Publish(WaitingOnSecurityGroupDeletionEvent(nodeClass, err))
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
 	controllerutil.RemoveFinalizer(nodeClass, v1alpha1.TerminationFinalizer)
 	if !equality.Semantic.DeepEqual(stored, nodeClass) {
 		if err := c.kubeClient.Patch(ctx, nodeClass, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
@@ -35,3 +35,13 @@ func WaitingOnNodeClaimTerminationEvent(nodeClass *v1alpha1.ECSNodeClass, names
 		DedupeValues:   []string{string(nodeClass.UID)},
 	}
 }
+
+func WaitingOnSecurityGroupDeletionEvent(nodeClass *v1alpha1.ECSNodeClass, err error) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           corev1.EventTypeNormal,
+		Reason:         "WaitingOnSecurityGroupDeletion",
+		Message:        fmt.Sprintf("Waiting on managed security group deletion, %s", err),
+		DedupeValues:   []string{string(nodeClass.UID)},
+	}
+}
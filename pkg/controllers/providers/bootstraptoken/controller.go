@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstraptoken
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/bootstraptoken"
+)
+
+type Controller struct {
+	bootstrapTokenProvider bootstraptoken.Provider
+}
+
+func NewController(bootstrapTokenProvider bootstraptoken.Provider) *Controller {
+	return &Controller{
+		bootstrapTokenProvider: bootstrapTokenProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.bootstraptoken")
+
+	if err := c.bootstrapTokenProvider.Reconcile(ctx); err != nil {
+		return reconcile.Result{}, fmt.Errorf("reconciling bootstrap tokens, %w", err)
+	}
+	return reconcile.Result{RequeueAfter: 10 * time.Minute}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.bootstraptoken").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
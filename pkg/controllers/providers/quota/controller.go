@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/quota"
+)
+
+type Controller struct {
+	kubeClient    client.Client
+	recorder      events.Recorder
+	quotaProvider quota.Provider
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder, quotaProvider quota.Provider) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		recorder:      recorder,
+		quotaProvider: quotaProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.quota")
+
+	if err := c.quotaProvider.UpdateQuotas(ctx); err != nil {
+		return reconcile.Result{}, fmt.Errorf("updating quotas, %w", err)
+	}
+	nodePoolList := &karpv1.NodePoolList{}
+	if err := c.kubeClient.List(ctx, nodePoolList); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodepools, %w", err)
+	}
+	for _, capacityType := range []string{karpv1.CapacityTypeOnDemand, karpv1.CapacityTypeSpot} {
+		remaining, ok := c.quotaProvider.Remaining(capacityType)
+		if !ok || remaining > 0 {
+			continue
+		}
+		for i := range nodePoolList.Items {
+			nodePool := &nodePoolList.Items[i]
+			requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...)
+			if requirements.Get(karpv1.CapacityTypeLabelKey).Has(capacityType) {
+				c.recorder.Publish(QuotaExceededEvent(nodePool, capacityType, remaining))
+			}
+		}
+	}
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.quota").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func QuotaExceededEvent(nodePool *karpv1.NodePool, capacityType string, remaining int64) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "QuotaExceeded",
+		Message: fmt.Sprintf("The account vCPU quota for %s instances is exhausted (%d vCPUs remaining), "+
+			"%s offerings are unavailable until instances are released or the quota is raised", capacityType, remaining, capacityType),
+		DedupeValues: []string{string(nodePool.UID), capacityType},
+	}
+}
@@ -18,19 +18,32 @@ package operator
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
 	"os"
 
 	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
 	vpc "github.com/alibabacloud-go/vpc-20160428/v6/client"
 	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/karpenter/pkg/operator"
 
 	alicache "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/cache"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/acknodepool"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/bootstraptoken"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/capacityreservation"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/eip"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/imagefamily"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/instance"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/instancetype"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/keypair"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/pricing"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/quota"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/resourcegroup"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/securitygroup"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/version"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/vswitch"
@@ -45,57 +58,111 @@ type Operator struct {
 	PricingProvider       pricing.Provider
 	VSwitchProvider       vswitch.Provider
 	SecurityGroupProvider securitygroup.Provider
+	EIPProvider           eip.Provider
 	ImageProvider         imagefamily.Provider
 	ImageResolver         imagefamily.Resolver
+	KeyPairProvider       keypair.Provider
+	ResourceGroupProvider resourcegroup.Provider
+	QuotaProvider         quota.Provider
 	VersionProvider       version.Provider
 	InstanceTypeProvider  instancetype.Provider
+
+	CapacityReservationProvider capacityreservation.Provider
+	BootstrapTokenProvider      bootstraptoken.Provider
+	ACKNodePoolProvider         acknodepool.Provider
+	ACKClient                   client.ACKAPI
 }
 
 func NewOperator(ctx context.Context, operator *operator.Operator) (context.Context, *Operator) {
-	clientConfig, err := client.NewClientConfig()
+	clientConfig, err := client.NewClientConfig(ctx)
 	if err != nil {
 		log.FromContext(ctx).Error(err, "Failed to create client config")
 		os.Exit(1)
 	}
-	ecsClient, err := ecs.NewClient(clientConfig)
+	ecsClient, err := ecs.NewClient(client.ServiceConfig(ctx, clientConfig, client.ProductECS))
 	if err != nil {
 		log.FromContext(ctx).Error(err, "Failed to create ECS client")
 		os.Exit(1)
 	}
-	vpcClient, err := vpc.NewClient(clientConfig)
+	vpcClient, err := vpc.NewClient(client.ServiceConfig(ctx, clientConfig, client.ProductVPC))
 	if err != nil {
 		log.FromContext(ctx).Error(err, "Failed to create VPC client")
 		os.Exit(1)
 	}
+	ackClient, err := client.NewACKClient(ctx, client.ServiceConfig(ctx, clientConfig, client.ProductACK))
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to create ACK client")
+		os.Exit(1)
+	}
+	resourceManagerClient, err := client.NewResourceManagerClient(ctx, client.ServiceConfig(ctx, clientConfig, client.ProductResourceManager))
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to create Resource Manager client")
+		os.Exit(1)
+	}
 	region := *ecsClient.RegionId
+	// All providers share the rate limits of the API clients
+	ecsapi := client.NewECSClient(ctx, ecsClient)
+	vpcapi := client.NewVPCClient(ctx, vpcClient)
 
-	pricingProvider, err := pricing.NewDefaultProvider(ctx, region)
+	httpClient, err := client.NewHTTPClient(ctx)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to create HTTP client")
+		os.Exit(1)
+	}
+	pricingProvider, err := pricing.NewDefaultProvider(ctx, region, httpClient)
 	if err != nil {
 		log.FromContext(ctx).Error(err, "Failed to create pricing provider")
 		os.Exit(1)
 	}
 
 	versionProvider := version.NewDefaultProvider(operator.KubernetesInterface, cache.New(alicache.KubernetesVersionTTL, alicache.DefaultCleanupInterval))
-	vSwitchProvider := vswitch.NewDefaultProvider(vpcClient, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval), cache.New(alicache.AvailableIPAddressTTL, alicache.DefaultCleanupInterval))
-	securityGroupProvider := securitygroup.NewDefaultProvider(region, ecsClient, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
-	imageProvider := imagefamily.NewDefaultProvider(region, ecsClient, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
-	imageResolver := imagefamily.NewDefaultResolver(region, ecsClient, cache.New(alicache.InstanceTypeAvailableDiskTTL, alicache.DefaultCleanupInterval))
+	vSwitchProvider := vswitch.NewDefaultProvider(vpcapi, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval), cache.New(alicache.AvailableIPAddressTTL, alicache.DefaultCleanupInterval))
+	securityGroupProvider := securitygroup.NewDefaultProvider(region, ecsapi, vpcapi, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval), cache.New(alicache.AvailableSecurityGroupCapacityTTL, alicache.DefaultCleanupInterval))
+	eipProvider := eip.NewDefaultProvider(region, vpcapi)
+	imageProvider := imagefamily.NewDefaultProvider(region, ecsapi, versionProvider, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
+	keyPairProvider := keypair.NewDefaultProvider(region, ecsapi, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
+	resourceGroupProvider := resourcegroup.NewDefaultProvider(resourceManagerClient, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
+	capacityReservationProvider := capacityreservation.NewDefaultProvider(region, ecsapi, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
+	ackNodePoolProvider := acknodepool.NewDefaultProvider(ackClient, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
+	imageResolver := imagefamily.NewDefaultResolver(region, ecsapi, cache.New(alicache.InstanceTypeAvailableDiskTTL, alicache.DefaultCleanupInterval))
+
+	clusterEndpoint, err := ResolveClusterEndpoint(ctx, ackClient, operator.GetConfig())
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to resolve cluster endpoint")
+		os.Exit(1)
+	}
+	caBundle, err := GetCABundle(ctx, operator.GetConfig())
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to get CA bundle for cluster")
+		os.Exit(1)
+	}
 
+	bootstrapTokenProvider := bootstraptoken.NewDefaultProvider(operator.KubernetesInterface, operator.Clock)
+	unavailableOfferingsCache := alicache.NewUnavailableOfferings()
 	instanceProvider := instance.NewDefaultProvider(
 		ctx,
 		region,
-		"",
-		ecsClient,
+		clusterEndpoint,
+		caBundle,
+		ecsapi,
 		imageResolver,
+		versionProvider,
+		bootstrapTokenProvider,
 		vSwitchProvider,
+		securityGroupProvider,
+		eipProvider,
+		capacityReservationProvider,
+		unavailableOfferingsCache,
 	)
 
-	unavailableOfferingsCache := alicache.NewUnavailableOfferings()
+	quotaProvider := quota.NewDefaultProvider(region, ecsapi, instanceProvider)
+
 	instanceTypeProvider := instancetype.NewDefaultProvider(
-		*ecsClient.RegionId, ecsClient,
+		region, ecsapi,
 		cache.New(alicache.InstanceTypesAndZonesTTL, alicache.DefaultCleanupInterval),
 		unavailableOfferingsCache,
-		pricingProvider, nil)
+		pricingProvider, nil,
+		capacityReservationProvider, quotaProvider)
 
 	return ctx, &Operator{
 		Operator: operator,
@@ -104,9 +171,68 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		PricingProvider:       pricingProvider,
 		VSwitchProvider:       vSwitchProvider,
 		SecurityGroupProvider: securityGroupProvider,
+		EIPProvider:           eipProvider,
 		ImageProvider:         imageProvider,
 		ImageResolver:         imageResolver,
+		KeyPairProvider:       keyPairProvider,
+		ResourceGroupProvider: resourceGroupProvider,
+		QuotaProvider:         quotaProvider,
 		VersionProvider:       versionProvider,
 		InstanceTypeProvider:  instanceTypeProvider,
+
+		CapacityReservationProvider: capacityReservationProvider,
+		BootstrapTokenProvider:      bootstrapTokenProvider,
+		ACKNodePoolProvider:         ackNodePoolProvider,
+		ACKClient:                   ackClient,
+	}
+}
+
+// ResolveClusterEndpoint returns the cluster endpoint of the options if set, otherwise the endpoint of the ACK cluster,
+// preferring the VPC endpoint that nodes reach without internet access, and finally the host of the kubeconfig
+func ResolveClusterEndpoint(ctx context.Context, ackapi client.ACKAPI, restConfig *rest.Config) (string, error) {
+	if clusterEndpoint := options.FromContext(ctx).ClusterEndpoint; clusterEndpoint != "" {
+		return clusterEndpoint, nil
+	}
+	clusterEndpoint, err := ackClusterEndpoint(ctx, ackapi)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed discovering cluster endpoint from ACK, falling back to kubeconfig")
+	}
+	if clusterEndpoint != "" {
+		return clusterEndpoint, nil
+	}
+	if restConfig == nil || restConfig.Host == "" {
+		return "", fmt.Errorf("no cluster endpoint found, set the cluster-endpoint option")
+	}
+	return restConfig.Host, nil
+}
+
+func ackClusterEndpoint(ctx context.Context, ackapi client.ACKAPI) (string, error) {
+	cluster, err := client.FindACKCluster(ackapi, options.FromContext(ctx).ClusterID, options.FromContext(ctx).ClusterName)
+	if err != nil {
+		return "", err
+	}
+	intranet, public, err := cluster.APIServerEndpoints()
+	if err != nil {
+		return "", fmt.Errorf("getting endpoints of cluster %s, %w", cluster.ClusterID, err)
+	}
+	return lo.CoalesceOrEmpty(intranet, public), nil
+}
+
+// GetCABundle returns the base64 encoded CA bundle of the options if set, otherwise the CA bundle of the kubeconfig
+func GetCABundle(ctx context.Context, restConfig *rest.Config) (*string, error) {
+	if caBundle := options.FromContext(ctx).ClusterCABundle; caBundle != "" {
+		return lo.ToPtr(caBundle), nil
+	}
+	transportConfig, err := restConfig.TransportConfig()
+	if err != nil {
+		return nil, fmt.Errorf("getting transport config from rest config, %w", err)
+	}
+	// TLSConfigFor loads the CA file of in-cluster configs into CAData
+	if _, err := transport.TLSConfigFor(transportConfig); err != nil {
+		return nil, fmt.Errorf("getting TLS config from transport config, %w", err)
+	}
+	if len(transportConfig.TLS.CAData) == 0 {
+		return nil, nil
 	}
+	return lo.ToPtr(base64.StdEncoding.EncodeToString(transportConfig.TLS.CAData)), nil
 }
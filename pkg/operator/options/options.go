@@ -35,18 +35,51 @@ func init() {
 
 type optionsKey struct{}
 
+const (
+	EndpointTypePublic = "public"
+	EndpointTypeVPC    = "vpc"
+	EndpointTypeIntl   = "intl"
+)
+
 type Options struct {
 	ClusterCABundle         string
 	ClusterName             string
+	ClusterID               string
 	ClusterEndpoint         string
+	Region                  string
 	VMMemoryOverheadPercent float64
+
+	APIEndpointType         string
+	ECSEndpoint             string
+	VPCEndpoint             string
+	STSEndpoint             string
+	ACKEndpoint             string
+	ResourceManagerEndpoint string
+	HTTPProxy               string
+	HTTPSProxy              string
+	NoProxy                 string
+	APICABundleFile         string
+	APIRateLimitScale       float64
 }
 
 func (o *Options) AddFlags(fs *coreoptions.FlagSet) {
 	fs.StringVar(&o.ClusterCABundle, "cluster-ca-bundle", env.WithDefaultString("CLUSTER_CA_BUNDLE", ""), "Cluster CA bundle for nodes to use for TLS connections with the API server. If not set, this is taken from the controller's TLS configuration.")
 	fs.StringVar(&o.ClusterName, "cluster-name", env.WithDefaultString("CLUSTER_NAME", ""), "[REQUIRED] The kubernetes cluster name for resource discovery.")
-	fs.StringVar(&o.ClusterEndpoint, "cluster-endpoint", env.WithDefaultString("CLUSTER_ENDPOINT", ""), "The external kubernetes cluster endpoint for new nodes to connect with. If not specified, will discover the cluster endpoint using DescribeCluster API.")
+	fs.StringVar(&o.ClusterID, "cluster-id", env.WithDefaultString("CLUSTER_ID", ""), "The ID of the ACK cluster used to discover the cluster endpoint. If not specified, the ACK cluster is looked up by the cluster name.")
+	fs.StringVar(&o.ClusterEndpoint, "cluster-endpoint", env.WithDefaultString("CLUSTER_ENDPOINT", ""), "The external kubernetes cluster endpoint for new nodes to connect with. If not specified, will discover the cluster endpoint using the ACK DescribeClusterDetail API, falling back to the controller's kubeconfig.")
+	fs.StringVar(&o.Region, "region", env.WithDefaultString("ALIBABA_CLOUD_REGION_ID", ""), "The region of the cluster. If not specified, the region of the current aliyun-cli profile is used.")
 	fs.Float64Var(&o.VMMemoryOverheadPercent, "vm-memory-overhead-percent", utils.WithDefaultFloat64("VM_MEMORY_OVERHEAD_PERCENT", 0.075), "The VM memory overhead as a percent that will be subtracted from the total memory for all instance types.")
+	fs.StringVar(&o.APIEndpointType, "api-endpoint-type", env.WithDefaultString("API_ENDPOINT_TYPE", EndpointTypePublic), "The type of Alibaba Cloud API endpoints to use, one of public, vpc or intl. Use vpc in VPCs without internet access.")
+	fs.StringVar(&o.ECSEndpoint, "ecs-endpoint", env.WithDefaultString("ECS_ENDPOINT", ""), "The ECS API endpoint to use instead of the endpoint of the api-endpoint-type.")
+	fs.StringVar(&o.VPCEndpoint, "vpc-endpoint", env.WithDefaultString("VPC_ENDPOINT", ""), "The VPC API endpoint to use instead of the endpoint of the api-endpoint-type.")
+	fs.StringVar(&o.STSEndpoint, "sts-endpoint", env.WithDefaultString("STS_ENDPOINT", ""), "The STS API endpoint the RRSA credentials assume their RAM role with instead of the endpoint of the api-endpoint-type.")
+	fs.StringVar(&o.ACKEndpoint, "ack-endpoint", env.WithDefaultString("ACK_ENDPOINT", ""), "The ACK API endpoint to use instead of the endpoint of the api-endpoint-type.")
+	fs.StringVar(&o.ResourceManagerEndpoint, "resource-manager-endpoint", env.WithDefaultString("RESOURCE_MANAGER_ENDPOINT", ""), "The Resource Manager API endpoint to use instead of the endpoint of the api-endpoint-type.")
+	fs.StringVar(&o.HTTPProxy, "http-proxy", env.WithDefaultString("HTTP_PROXY", ""), "The proxy for HTTP requests to cloud APIs.")
+	fs.StringVar(&o.HTTPSProxy, "https-proxy", env.WithDefaultString("HTTPS_PROXY", ""), "The proxy for HTTPS requests to cloud APIs.")
+	fs.StringVar(&o.NoProxy, "no-proxy", env.WithDefaultString("NO_PROXY", ""), "Comma-separated hosts that cloud API requests reach without the proxy.")
+	fs.StringVar(&o.APICABundleFile, "api-ca-bundle-file", env.WithDefaultString("API_CA_BUNDLE_FILE", ""), "Path to a PEM CA bundle to verify cloud API and price query endpoints with instead of the system roots, e.g. the CA of a TLS intercepting proxy.")
+	fs.Float64Var(&o.APIRateLimitScale, "api-rate-limit-scale", utils.WithDefaultFloat64("API_RATE_LIMIT_SCALE", 1), "Factor applied to the request rate and burst of every cloud API Karpenter calls, e.g. 2 in accounts with raised flow control quotas or 0.5 in accounts shared with many other clients.")
 }
 
 func (o *Options) Parse(fs *coreoptions.FlagSet, args ...string) error {
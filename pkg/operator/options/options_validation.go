@@ -26,6 +26,8 @@ import (
 func (o Options) Validate() error {
 	return multierr.Combine(
 		o.validateEndpoint(),
+		o.validateAPIEndpointType(),
+		o.validateAPIRateLimitScale(),
 		o.validateRequiredFields(),
 	)
 }
@@ -43,6 +45,21 @@ func (o Options) validateEndpoint() error {
 	return nil
 }
 
+func (o Options) validateAPIEndpointType() error {
+	switch o.APIEndpointType {
+	case EndpointTypePublic, EndpointTypeVPC, EndpointTypeIntl:
+		return nil
+	}
+	return fmt.Errorf("%q is not a valid api-endpoint-type, must be one of %s, %s or %s", o.APIEndpointType, EndpointTypePublic, EndpointTypeVPC, EndpointTypeIntl)
+}
+
+func (o Options) validateAPIRateLimitScale() error {
+	if o.APIRateLimitScale <= 0 {
+		return fmt.Errorf("%v is not a valid api-rate-limit-scale, must be positive", o.APIRateLimitScale)
+	}
+	return nil
+}
+
 func (o Options) validateRequiredFields() error {
 	if o.ClusterName == "" {
 		return fmt.Errorf("missing field, cluster-name")
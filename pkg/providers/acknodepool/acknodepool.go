@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acknodepool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/patrickmn/go-cache"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+type Provider interface {
+	// ClusterID returns the ID of the ACK cluster, looking it up by the cluster name if the option isn't set
+	ClusterID(context.Context) (string, error)
+	Get(context.Context, string) (*client.ACKNodePool, error)
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	ackapi    client.ACKAPI
+	cache     *cache.Cache
+	clusterID string
+}
+
+func NewDefaultProvider(ackapi client.ACKAPI, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		ackapi: ackapi,
+		cache:  cache,
+	}
+}
+
+func (p *DefaultProvider) ClusterID(ctx context.Context) (string, error) {
+	p.Lock()
+	defer p.Unlock()
+	return p.getClusterID(ctx)
+}
+
+func (p *DefaultProvider) Get(ctx context.Context, id string) (*client.ACKNodePool, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if nodePool, ok := p.cache.Get(id); ok {
+		return nodePool.(*client.ACKNodePool), nil
+	}
+	clusterID, err := p.getClusterID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodePool, err := p.ackapi.DescribeNodePool(clusterID, id)
+	if err != nil {
+		return nil, fmt.Errorf("describing node pool %s, %w", id, err)
+	}
+	p.cache.SetDefault(id, nodePool)
+	return nodePool, nil
+}
+
+func (p *DefaultProvider) getClusterID(ctx context.Context) (string, error) {
+	if p.clusterID != "" {
+		return p.clusterID, nil
+	}
+	if clusterID := options.FromContext(ctx).ClusterID; clusterID != "" {
+		p.clusterID = clusterID
+		return clusterID, nil
+	}
+	cluster, err := client.FindACKCluster(p.ackapi, "", options.FromContext(ctx).ClusterName)
+	if err != nil {
+		return "", fmt.Errorf("resolving ACK cluster, %w", err)
+	}
+	p.clusterID = cluster.ClusterID
+	return p.clusterID, nil
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstraptoken
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis"
+)
+
+// Ref: https://kubernetes.io/docs/reference/access-authn-authz/bootstrap-tokens/
+const (
+	namespace         = metav1.NamespaceSystem
+	secretNamePrefix  = "bootstrap-token-"
+	keyTokenID        = "token-id"
+	keyTokenSecret    = "token-secret"
+	keyExpiration     = "expiration"
+	keyDescription    = "description"
+	keyUsageAuth      = "usage-bootstrap-authentication"
+	keyUsageSigning   = "usage-bootstrap-signing"
+	keyAuthExtraGroup = "auth-extra-groups"
+	// nodeGroup is bound to the CSR creation and auto approval of joining nodes in ACK and kubeadm clusters
+	nodeGroup    = "system:bootstrappers:kubeadm:default-node-token"
+	tokenChars   = "0123456789abcdefghijklmnopqrstuvwxyz"
+	idLength     = 6
+	secretLength = 16
+
+	// TokenTTL is the lifetime of a token, it only has to outlive the boot of the instances launched with it
+	TokenTTL = 4 * time.Hour
+	// RotationWindow is the remaining lifetime under which a new token replaces the current one
+	RotationWindow = time.Hour
+)
+
+// ManagedByLabelKey scopes the bootstrap tokens that Karpenter creates, rotates and deletes
+var ManagedByLabelKey = apis.Group + "/bootstrap-token"
+
+type Provider interface {
+	// Token returns the current bootstrap token, creating one if there is none with enough lifetime left
+	Token(context.Context) (string, error)
+	// Reconcile rotates the current token before it expires and deletes the expired tokens
+	Reconcile(context.Context) error
+}
+
+type token struct {
+	id         string
+	secret     string
+	expiration time.Time
+}
+
+func (t *token) String() string {
+	return t.id + "." + t.secret
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	kubernetesInterface kubernetes.Interface
+	clk                 clock.Clock
+	current             *token
+}
+
+func NewDefaultProvider(kubernetesInterface kubernetes.Interface, clk clock.Clock) *DefaultProvider {
+	return &DefaultProvider{
+		kubernetesInterface: kubernetesInterface,
+		clk:                 clk,
+	}
+}
+
+func (p *DefaultProvider) Token(ctx context.Context) (string, error) {
+	p.Lock()
+	defer p.Unlock()
+	if p.valid(p.current) {
+		return p.current.String(), nil
+	}
+	if err := p.rotate(ctx); err != nil {
+		return "", err
+	}
+	return p.current.String(), nil
+}
+
+func (p *DefaultProvider) Reconcile(ctx context.Context) error {
+	p.Lock()
+	defer p.Unlock()
+	tokens, err := p.list(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, t := range tokens {
+		if p.clk.Now().Before(t.expiration) {
+			continue
+		}
+		if err := p.kubernetesInterface.CoreV1().Secrets(namespace).Delete(ctx, secretNamePrefix+t.id, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("deleting bootstrap token %s, %w", t.id, err))
+			continue
+		}
+		log.FromContext(ctx).WithValues("token-id", t.id).V(1).Info("deleted expired bootstrap token")
+	}
+	if err := multierr.Combine(errs...); err != nil {
+		return err
+	}
+	// Adopt the newest token after a restart rather than creating one
+	if !p.valid(p.current) && len(tokens) != 0 && p.valid(tokens[len(tokens)-1]) {
+		p.current = tokens[len(tokens)-1]
+	}
+	if p.valid(p.current) {
+		return nil
+	}
+	return p.rotate(ctx)
+}
+
+// valid returns whether the token is usable for launches, instances still boot with it after the rotation
+func (p *DefaultProvider) valid(t *token) bool {
+	return t != nil && p.clk.Now().Add(RotationWindow).Before(t.expiration)
+}
+
+// rotate creates a new token and makes it the current one, the previous token expires on its own
+func (p *DefaultProvider) rotate(ctx context.Context) error {
+	t, err := newToken(p.clk.Now().Add(TokenTTL))
+	if err != nil {
+		return fmt.Errorf("generating bootstrap token, %w", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretNamePrefix + t.id,
+			Namespace: namespace,
+			Labels:    map[string]string{ManagedByLabelKey: "true"},
+		},
+		Type: corev1.SecretTypeBootstrapToken,
+		Data: map[string][]byte{
+			keyTokenID:        []byte(t.id),
+			keyTokenSecret:    []byte(t.secret),
+			keyExpiration:     []byte(t.expiration.UTC().Format(time.RFC3339)),
+			keyDescription:    []byte("Bootstrap token for nodes launched by Karpenter"),
+			keyUsageAuth:      []byte("true"),
+			keyUsageSigning:   []byte("true"),
+			keyAuthExtraGroup: []byte(nodeGroup),
+		},
+	}
+	if _, err := p.kubernetesInterface.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("creating bootstrap token, %w", err)
+	}
+	p.current = t
+	log.FromContext(ctx).WithValues("token-id", t.id, "expiration", t.expiration.UTC().Format(time.RFC3339)).Info("created bootstrap token")
+	return nil
+}
+
+// list returns the bootstrap tokens created by Karpenter, sorted by expiration
+func (p *DefaultProvider) list(ctx context.Context) ([]*token, error) {
+	secrets, err := p.kubernetesInterface.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: ManagedByLabelKey + "=true",
+		FieldSelector: fields.OneTermEqualSelector("type", string(corev1.SecretTypeBootstrapToken)).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing bootstrap tokens, %w", err)
+	}
+	var tokens []*token
+	for i := range secrets.Items {
+		data := secrets.Items[i].Data
+		expiration, err := time.Parse(time.RFC3339, string(data[keyExpiration]))
+		if err != nil {
+			// Tokens without a valid expiration are expired, they must not be left behind
+			expiration = time.Time{}
+		}
+		tokens = append(tokens, &token{id: string(data[keyTokenID]), secret: string(data[keyTokenSecret]), expiration: expiration})
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].expiration.Before(tokens[j].expiration) })
+	return tokens, nil
+}
+
+func newToken(expiration time.Time) (*token, error) {
+	id, err := randomString(idLength)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomString(secretLength)
+	if err != nil {
+		return nil, err
+	}
+	return &token{id: id, secret: secret, expiration: expiration}, nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(tokenChars))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = tokenChars[idx.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstraptoken
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clock "k8s.io/utils/clock/testing"
+)
+
+func secretNames(t *testing.T, kubernetesInterface *fake.Clientset) []string {
+	t.Helper()
+	secrets, err := kubernetesInterface.CoreV1().Secrets(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("listing secrets, %v", err)
+	}
+	var names []string
+	for i := range secrets.Items {
+		names = append(names, secrets.Items[i].Name)
+	}
+	return names
+}
+
+func TestTokenRotation(t *testing.T) {
+	ctx := context.Background()
+	// Tokens that Karpenter didn't create are never touched
+	kubernetesInterface := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretNamePrefix + "abcdef", Namespace: namespace},
+		Type:       corev1.SecretTypeBootstrapToken,
+		Data:       map[string][]byte{keyTokenID: []byte("abcdef"), keyExpiration: []byte("2000-01-01T00:00:00Z")},
+	})
+	clk := clock.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	p := NewDefaultProvider(kubernetesInterface, clk)
+
+	first, err := p.Token(ctx)
+	if err != nil {
+		t.Fatalf("getting token, %v", err)
+	}
+	if len(first) != idLength+1+secretLength {
+		t.Errorf("token %q doesn't have the bootstrap token format", first)
+	}
+
+	// The token is kept until its remaining lifetime reaches the rotation window
+	clk.Step(TokenTTL - RotationWindow - time.Second)
+	if token, err := p.Token(ctx); err != nil || token != first {
+		t.Errorf("token before the rotation window = %q, %v, want %q", token, err, first)
+	}
+	clk.Step(time.Second)
+	second, err := p.Token(ctx)
+	if err != nil {
+		t.Fatalf("getting token, %v", err)
+	}
+	if second == first {
+		t.Errorf("token wasn't rotated at the rotation window")
+	}
+	// The previous token still works for the instances booting with it
+	if names := secretNames(t, kubernetesInterface); len(names) != 3 {
+		t.Errorf("secrets after the rotation = %v, want both tokens and the unmanaged one", names)
+	}
+
+	// Expired tokens are deleted
+	clk.Step(RotationWindow)
+	if err := p.Reconcile(ctx); err != nil {
+		t.Fatalf("reconciling tokens, %v", err)
+	}
+	names := secretNames(t, kubernetesInterface)
+	if len(names) != 2 {
+		t.Errorf("secrets after the first token expired = %v, want the second token and the unmanaged one", names)
+	}
+	for _, name := range names {
+		if name == secretNamePrefix+first[:idLength] {
+			t.Errorf("expired token %s wasn't deleted", name)
+		}
+	}
+
+	// The newest token is adopted after a restart
+	p = NewDefaultProvider(kubernetesInterface, clk)
+	if err := p.Reconcile(ctx); err != nil {
+		t.Fatalf("reconciling tokens after a restart, %v", err)
+	}
+	if token, err := p.Token(ctx); err != nil || token != second {
+		t.Errorf("token after a restart = %q, %v, want %q", token, err, second)
+	}
+	if names := secretNames(t, kubernetesInterface); len(names) != 2 {
+		t.Errorf("secrets after a restart = %v, want no new token", names)
+	}
+}
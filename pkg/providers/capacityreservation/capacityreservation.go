@@ -0,0 +1,235 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityreservation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/mitchellh/hashstructure/v2"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+const statusActive = "Active"
+
+type Provider interface {
+	List(context.Context, *v1alpha1.ECSNodeClass) ([]v1alpha1.CapacityReservation, error)
+	AvailableInstanceCount(v1alpha1.CapacityReservation) int32
+	MarkLaunched(v1alpha1.CapacityReservation)
+	MarkExhausted(v1alpha1.CapacityReservation)
+	SeqNum() uint64
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	region string
+	ecsapi client.ECSAPI
+	cache  *cache.Cache
+	cm     *pretty.ChangeMonitor
+	// The amounts returned by the API lag behind launches, so launched and exhausted reservations are tracked in
+	// memory until the private pool is described again
+	inflightCount map[string]int32
+	seqNum        uint64
+}
+
+func NewDefaultProvider(region string, ecsapi client.ECSAPI, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		region:        region,
+		ecsapi:        ecsapi,
+		cache:         cache,
+		cm:            pretty.NewChangeMonitor(),
+		inflightCount: map[string]int32{},
+	}
+}
+
+// List returns an entry for every instance type and zone of the active capacity reservations and elasticity
+// assurances selected by the ECSNodeClass
+func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) ([]v1alpha1.CapacityReservation, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	hash, err := hashstructure.Hash(nodeClass.Spec.CapacityReservationSelectorTerms, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	if err != nil {
+		return nil, err
+	}
+	if reservations, ok := p.cache.Get(fmt.Sprint(hash)); ok {
+		return append([]v1alpha1.CapacityReservation{}, reservations.([]v1alpha1.CapacityReservation)...), nil
+	}
+	reservations := map[string]v1alpha1.CapacityReservation{}
+	for _, term := range nodeClass.Spec.CapacityReservationSelectorTerms {
+		if term.ID == "" || strings.HasPrefix(term.ID, "crp-") {
+			if err := p.describeCapacityReservations(term, reservations); err != nil {
+				return nil, fmt.Errorf("describing capacity reservations, %w", err)
+			}
+		}
+		if term.ID == "" || strings.HasPrefix(term.ID, "eap-") {
+			if err := p.describeElasticityAssurances(term, reservations); err != nil {
+				return nil, fmt.Errorf("describing elasticity assurances, %w", err)
+			}
+		}
+	}
+	for k := range reservations {
+		// remove any previously tracked count since we just refreshed from ECS
+		delete(p.inflightCount, k)
+	}
+	atomic.AddUint64(&p.seqNum, 1)
+	result := lo.Values(reservations)
+	if p.cm.HasChanged(fmt.Sprintf("capacity-reservations/%s", nodeClass.Name), lo.Keys(reservations)) {
+		log.FromContext(ctx).
+			WithValues("capacity-reservations", lo.Uniq(lo.Map(result, func(r v1alpha1.CapacityReservation, _ int) string { return r.ID }))).
+			V(1).Info("discovered capacity reservations")
+	}
+	p.cache.SetDefault(fmt.Sprint(hash), result)
+	return append([]v1alpha1.CapacityReservation{}, result...), nil
+}
+
+// AvailableInstanceCount returns the number of instances that can still be launched for the reserved instance type
+// and zone, preferring the in-flight accounting over the last API response
+func (p *DefaultProvider) AvailableInstanceCount(reservation v1alpha1.CapacityReservation) int32 {
+	p.Lock()
+	defer p.Unlock()
+
+	if count, ok := p.inflightCount[key(reservation)]; ok {
+		return count
+	}
+	return reservation.AvailableInstanceCount
+}
+
+// MarkLaunched deducts an instance launched into the private pool
+func (p *DefaultProvider) MarkLaunched(reservation v1alpha1.CapacityReservation) {
+	p.Lock()
+	defer p.Unlock()
+
+	count, ok := p.inflightCount[key(reservation)]
+	if !ok {
+		count = reservation.AvailableInstanceCount
+	}
+	p.inflightCount[key(reservation)] = lo.Max([]int32{count - 1, 0})
+	atomic.AddUint64(&p.seqNum, 1)
+}
+
+// MarkExhausted treats the private pool as full until it's described again, e.g. after a failed launch
+func (p *DefaultProvider) MarkExhausted(reservation v1alpha1.CapacityReservation) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.inflightCount[key(reservation)] = 0
+	atomic.AddUint64(&p.seqNum, 1)
+}
+
+// SeqNum changes whenever the available instance counts may have changed
+func (p *DefaultProvider) SeqNum() uint64 {
+	return atomic.LoadUint64(&p.seqNum)
+}
+
+func (p *DefaultProvider) describeCapacityReservations(term v1alpha1.CapacityReservationSelectorTerm, reservations map[string]v1alpha1.CapacityReservation) error {
+	request := &ecs.DescribeCapacityReservationsRequest{
+		RegionId:   tea.String(p.region),
+		Status:     tea.String(statusActive),
+		MaxResults: tea.Int32(100),
+		Tag: lo.MapToSlice(term.Tags, func(k, v string) *ecs.DescribeCapacityReservationsRequestTag {
+			return &ecs.DescribeCapacityReservationsRequestTag{Key: tea.String(k), Value: lo.Ternary(v == "*", nil, tea.String(v))}
+		}),
+	}
+	if term.ID != "" {
+		request.PrivatePoolOptions = &ecs.DescribeCapacityReservationsRequestPrivatePoolOptions{Ids: tea.String(fmt.Sprintf("[%q]", term.ID))}
+	}
+	for {
+		output, err := p.ecsapi.DescribeCapacityReservationsWithOptions(request, &util.RuntimeOptions{})
+		if err != nil {
+			return err
+		} else if output.Body == nil || output.Body.CapacityReservationSet == nil {
+			return fmt.Errorf("unexpected null value was returned")
+		}
+		for _, item := range output.Body.CapacityReservationSet.CapacityReservationItem {
+			if item.AllocatedResources == nil {
+				continue
+			}
+			for _, resource := range item.AllocatedResources.AllocatedResource {
+				reservation := v1alpha1.CapacityReservation{
+					ID:                     lo.FromPtr(item.PrivatePoolOptionsId),
+					Type:                   v1alpha1.CapacityReservationTypeCapacityReservation,
+					InstanceType:           lo.FromPtr(resource.InstanceType),
+					ZoneID:                 lo.FromPtr(resource.ZoneId),
+					AvailableInstanceCount: lo.FromPtr(resource.AvailableAmount),
+				}
+				reservations[key(reservation)] = reservation
+			}
+		}
+		request.NextToken = output.Body.NextToken
+		if lo.FromPtr(request.NextToken) == "" {
+			return nil
+		}
+	}
+}
+
+func (p *DefaultProvider) describeElasticityAssurances(term v1alpha1.CapacityReservationSelectorTerm, reservations map[string]v1alpha1.CapacityReservation) error {
+	request := &ecs.DescribeElasticityAssurancesRequest{
+		RegionId:   tea.String(p.region),
+		Status:     tea.String(statusActive),
+		MaxResults: tea.Int32(100),
+		Tag: lo.MapToSlice(term.Tags, func(k, v string) *ecs.DescribeElasticityAssurancesRequestTag {
+			return &ecs.DescribeElasticityAssurancesRequestTag{Key: tea.String(k), Value: lo.Ternary(v == "*", nil, tea.String(v))}
+		}),
+	}
+	if term.ID != "" {
+		request.PrivatePoolOptions = &ecs.DescribeElasticityAssurancesRequestPrivatePoolOptions{Ids: tea.String(fmt.Sprintf("[%q]", term.ID))}
+	}
+	for {
+		output, err := p.ecsapi.DescribeElasticityAssurancesWithOptions(request, &util.RuntimeOptions{})
+		if err != nil {
+			return err
+		} else if output.Body == nil || output.Body.ElasticityAssuranceSet == nil {
+			return fmt.Errorf("unexpected null value was returned")
+		}
+		for _, item := range output.Body.ElasticityAssuranceSet.ElasticityAssuranceItem {
+			if item.AllocatedResources == nil {
+				continue
+			}
+			for _, resource := range item.AllocatedResources.AllocatedResource {
+				reservation := v1alpha1.CapacityReservation{
+					ID:                     lo.FromPtr(item.PrivatePoolOptionsId),
+					Type:                   v1alpha1.CapacityReservationTypeElasticityAssurance,
+					InstanceType:           lo.FromPtr(resource.InstanceType),
+					ZoneID:                 lo.FromPtr(resource.ZoneId),
+					AvailableInstanceCount: lo.Max([]int32{lo.FromPtr(resource.TotalAmount) - lo.FromPtr(resource.UsedAmount), 0}),
+				}
+				reservations[key(reservation)] = reservation
+			}
+		}
+		request.NextToken = output.Body.NextToken
+		if lo.FromPtr(request.NextToken) == "" {
+			return nil
+		}
+	}
+}
+
+func key(reservation v1alpha1.CapacityReservation) string {
+	return fmt.Sprintf("%s/%s/%s", reservation.ID, reservation.InstanceType, reservation.ZoneID)
+}
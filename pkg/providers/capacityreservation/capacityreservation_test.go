@@ -0,0 +1,174 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityreservation
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+// fakeECSAPI returns a capacity reservation over two pages and an elasticity assurance, the embedded interface
+// panics on any other call
+type fakeECSAPI struct {
+	client.ECSAPI
+	calls int
+}
+
+func (f *fakeECSAPI) DescribeCapacityReservationsWithOptions(request *ecs.DescribeCapacityReservationsRequest, _ *util.RuntimeOptions) (*ecs.DescribeCapacityReservationsResponse, error) {
+	f.calls++
+	resource := &ecs.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItemAllocatedResourcesAllocatedResource{
+		InstanceType:    tea.String("ecs.g7.large"),
+		ZoneId:          tea.String("cn-hangzhou-h"),
+		AvailableAmount: tea.Int32(2),
+	}
+	nextToken := "page-2"
+	if tea.StringValue(request.NextToken) == "page-2" {
+		resource.ZoneId = tea.String("cn-hangzhou-i")
+		nextToken = ""
+	}
+	return &ecs.DescribeCapacityReservationsResponse{Body: &ecs.DescribeCapacityReservationsResponseBody{
+		NextToken: tea.String(nextToken),
+		CapacityReservationSet: &ecs.DescribeCapacityReservationsResponseBodyCapacityReservationSet{
+			CapacityReservationItem: []*ecs.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItem{{
+				PrivatePoolOptionsId: tea.String("crp-1"),
+				AllocatedResources: &ecs.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItemAllocatedResources{
+					AllocatedResource: []*ecs.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItemAllocatedResourcesAllocatedResource{resource},
+				},
+			}},
+		},
+	}}, nil
+}
+
+func (f *fakeECSAPI) DescribeElasticityAssurancesWithOptions(_ *ecs.DescribeElasticityAssurancesRequest, _ *util.RuntimeOptions) (*ecs.DescribeElasticityAssurancesResponse, error) {
+	f.calls++
+	return &ecs.DescribeElasticityAssurancesResponse{Body: &ecs.DescribeElasticityAssurancesResponseBody{
+		ElasticityAssuranceSet: &ecs.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSet{
+			ElasticityAssuranceItem: []*ecs.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItem{{
+				PrivatePoolOptionsId: tea.String("eap-1"),
+				AllocatedResources: &ecs.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItemAllocatedResources{
+					AllocatedResource: []*ecs.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItemAllocatedResourcesAllocatedResource{{
+						InstanceType: tea.String("ecs.g7.xlarge"),
+						ZoneId:       tea.String("cn-hangzhou-h"),
+						TotalAmount:  tea.Int32(3),
+						UsedAmount:   tea.Int32(1),
+					}},
+				},
+			}},
+		},
+	}}, nil
+}
+
+func newNodeClass() *v1alpha1.ECSNodeClass {
+	return &v1alpha1.ECSNodeClass{Spec: v1alpha1.ECSNodeClassSpec{
+		CapacityReservationSelectorTerms: []v1alpha1.CapacityReservationSelectorTerm{{Tags: map[string]string{"team": "*"}}},
+	}}
+}
+
+func TestList(t *testing.T) {
+	ecsapi := &fakeECSAPI{}
+	p := NewDefaultProvider("cn-hangzhou", ecsapi, cache.New(time.Minute, time.Minute))
+
+	reservations, err := p.List(context.Background(), newNodeClass())
+	if err != nil {
+		t.Fatalf("listing capacity reservations, %v", err)
+	}
+	sort.Slice(reservations, func(i, j int) bool { return key(reservations[i]) < key(reservations[j]) })
+	want := []v1alpha1.CapacityReservation{
+		{ID: "crp-1", Type: v1alpha1.CapacityReservationTypeCapacityReservation, InstanceType: "ecs.g7.large", ZoneID: "cn-hangzhou-h", AvailableInstanceCount: 2},
+		{ID: "crp-1", Type: v1alpha1.CapacityReservationTypeCapacityReservation, InstanceType: "ecs.g7.large", ZoneID: "cn-hangzhou-i", AvailableInstanceCount: 2},
+		{ID: "eap-1", Type: v1alpha1.CapacityReservationTypeElasticityAssurance, InstanceType: "ecs.g7.xlarge", ZoneID: "cn-hangzhou-h", AvailableInstanceCount: 2},
+	}
+	if len(reservations) != len(want) {
+		t.Fatalf("listed %v, want %v", reservations, want)
+	}
+	for i := range want {
+		if reservations[i] != want[i] {
+			t.Errorf("listed %v, want %v", reservations[i], want[i])
+		}
+	}
+
+	// The reservations of the same selector terms are cached
+	calls := ecsapi.calls
+	if _, err := p.List(context.Background(), newNodeClass()); err != nil {
+		t.Fatalf("listing capacity reservations again, %v", err)
+	}
+	if ecsapi.calls != calls {
+		t.Errorf("listing again called the API %d times, want the cached reservations", ecsapi.calls-calls)
+	}
+}
+
+func TestAvailableInstanceCount(t *testing.T) {
+	p := NewDefaultProvider("cn-hangzhou", &fakeECSAPI{}, cache.New(time.Minute, time.Minute))
+	reservations, err := p.List(context.Background(), newNodeClass())
+	if err != nil {
+		t.Fatalf("listing capacity reservations, %v", err)
+	}
+	reservation := reservations[0]
+	other := reservations[1]
+
+	seqNum := p.SeqNum()
+	p.MarkLaunched(reservation)
+	if count := p.AvailableInstanceCount(reservation); count != reservation.AvailableInstanceCount-1 {
+		t.Errorf("available instances after a launch = %d, want %d", count, reservation.AvailableInstanceCount-1)
+	}
+	if count := p.AvailableInstanceCount(other); count != other.AvailableInstanceCount {
+		t.Errorf("available instances of another reservation = %d, want %d", count, other.AvailableInstanceCount)
+	}
+	if p.SeqNum() == seqNum {
+		t.Errorf("sequence number didn't change after a launch")
+	}
+
+	// Launches beyond the reserved amount don't count below zero
+	for i := int32(0); i < reservation.AvailableInstanceCount; i++ {
+		p.MarkLaunched(reservation)
+	}
+	if count := p.AvailableInstanceCount(reservation); count != 0 {
+		t.Errorf("available instances after launching all of them = %d, want 0", count)
+	}
+
+	seqNum = p.SeqNum()
+	p.MarkExhausted(other)
+	if count := p.AvailableInstanceCount(other); count != 0 {
+		t.Errorf("available instances of an exhausted reservation = %d, want 0", count)
+	}
+	if p.SeqNum() == seqNum {
+		t.Errorf("sequence number didn't change after exhausting a reservation")
+	}
+
+	// Describing the reservations again drops the in-flight counts
+	p.cache.Flush()
+	seqNum = p.SeqNum()
+	if _, err := p.List(context.Background(), newNodeClass()); err != nil {
+		t.Fatalf("listing capacity reservations again, %v", err)
+	}
+	if count := p.AvailableInstanceCount(reservation); count != reservation.AvailableInstanceCount {
+		t.Errorf("available instances after describing again = %d, want %d", count, reservation.AvailableInstanceCount)
+	}
+	if p.SeqNum() == seqNum {
+		t.Errorf("sequence number didn't change after describing again")
+	}
+}
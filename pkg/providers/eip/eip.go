@@ -0,0 +1,278 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eip
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	vpc "github.com/alibabacloud-go/vpc-20160428/v6/client"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+const (
+	// Ref: https://api.aliyun.com/api/Vpc/2016-04-28/DescribeEipAddresses
+	EIPStatusAvailable = "Available"
+
+	instanceTypeECS = "EcsInstance"
+	resourceTypeEIP = "EIP"
+)
+
+type Provider interface {
+	Associate(context.Context, *v1alpha1.ECSNodeClass, string) error
+	Disassociate(context.Context, string) error
+	List(context.Context) ([]*EIP, error)
+	Release(context.Context, *EIP) error
+}
+
+// EIP is an EIP from a pool that Karpenter bound to one of its instances
+type EIP struct {
+	AllocationID string
+	IPAddress    string
+	// InstanceID is the instance Karpenter bound the EIP to, recorded in the TagEIPInstance tag
+	InstanceID string
+	// AssociatedInstanceID is the instance the EIP is currently bound to, if any
+	AssociatedInstanceID string
+}
+
+type DefaultProvider struct {
+	region string
+	vpcapi client.VPCAPI
+
+	// mu guards claimed, the EIPs bound or being bound by this process, so that concurrent launches don't race for
+	// the same EIP while DescribeEipAddresses still reports it as available
+	mu      sync.Mutex
+	claimed map[string]struct{}
+}
+
+func NewDefaultProvider(region string, vpcapi client.VPCAPI) *DefaultProvider {
+	return &DefaultProvider{
+		region:  region,
+		vpcapi:  vpcapi,
+		claimed: map[string]struct{}{},
+	}
+}
+
+// Associate binds an available EIP from the pool selected by the ECSNodeClass to the instance. The EIP is tagged with
+// the cluster and the instance before it's bound, so that a bound EIP can always be found again on deletion or garbage
+// collection. An EIP already tagged with the instance is bound instead of a new one, so that Associate can be retried.
+func (p *DefaultProvider) Associate(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, instanceID string) error {
+	if nodeClass.Spec.PublicIP == nil || len(nodeClass.Spec.PublicIP.EIPPoolTags) == 0 {
+		return nil
+	}
+	tagged, err := p.list(ctx, &vpc.DescribeEipAddressesRequestTag{Key: tea.String(v1alpha1.TagEIPInstance), Value: tea.String(instanceID)})
+	if err != nil {
+		return err
+	}
+	if eip, ok := lo.Find(tagged, func(eip *EIP) bool { return eip.AssociatedInstanceID == instanceID }); ok {
+		log.FromContext(ctx).WithValues("allocation-id", eip.AllocationID, "ip", eip.IPAddress).V(1).Info("eip is already associated")
+		return nil
+	}
+	for _, eip := range tagged {
+		// The EIP was tagged but not bound by an earlier attempt
+		if eip.AssociatedInstanceID != "" || !p.claim(eip.AllocationID) {
+			continue
+		}
+		if err := p.associate(ctx, eip.AllocationID, instanceID); err != nil {
+			p.unclaim(eip.AllocationID)
+			log.FromContext(ctx).WithValues("allocation-id", eip.AllocationID).V(1).Info(fmt.Sprintf("failed associating eip, %s", err))
+			continue
+		}
+		return nil
+	}
+	request := &vpc.DescribeEipAddressesRequest{
+		Status: tea.String(EIPStatusAvailable),
+		Tag: lo.MapToSlice(nodeClass.Spec.PublicIP.EIPPoolTags, func(k, v string) *vpc.DescribeEipAddressesRequestTag {
+			return &vpc.DescribeEipAddressesRequestTag{Key: tea.String(k), Value: tea.String(v)}
+		}),
+	}
+	var available []*vpc.DescribeEipAddressesResponseBodyEipAddressesEipAddress
+	if err := p.describeEipAddresses(request, func(eip *vpc.DescribeEipAddressesResponseBodyEipAddressesEipAddress) {
+		available = append(available, eip)
+	}); err != nil {
+		return fmt.Errorf("describing eip pool, %w", err)
+	}
+	for _, eip := range available {
+		allocationID := lo.FromPtr(eip.AllocationId)
+		if !p.claim(allocationID) {
+			continue
+		}
+		if err := p.associate(ctx, allocationID, instanceID); err != nil {
+			p.unclaim(allocationID)
+			// The EIP may have been taken by someone else since it was described, try the next one
+			log.FromContext(ctx).WithValues("allocation-id", allocationID).V(1).Info(fmt.Sprintf("failed associating eip, %s", err))
+			continue
+		}
+		log.FromContext(ctx).WithValues("allocation-id", allocationID, "ip", lo.FromPtr(eip.IpAddress)).V(1).Info("associated eip")
+		return nil
+	}
+	return fmt.Errorf("no available eip in pool %v", nodeClass.Spec.PublicIP.EIPPoolTags)
+}
+
+// associate tags the EIP and binds it to the instance, the tags are removed again if it can't be bound
+func (p *DefaultProvider) associate(ctx context.Context, allocationID, instanceID string) error {
+	if _, err := p.vpcapi.TagResourcesWithOptions(&vpc.TagResourcesRequest{
+		RegionId:     tea.String(p.region),
+		ResourceType: tea.String(resourceTypeEIP),
+		ResourceId:   []*string{tea.String(allocationID)},
+		Tag: []*vpc.TagResourcesRequestTag{
+			{Key: tea.String(clusterTagKey(ctx)), Value: tea.String("owned")},
+			{Key: tea.String(v1alpha1.TagEIPInstance), Value: tea.String(instanceID)},
+		},
+	}, &util.RuntimeOptions{}); err != nil {
+		return fmt.Errorf("tagging eip, %w", err)
+	}
+	if _, err := p.vpcapi.AssociateEipAddressWithOptions(&vpc.AssociateEipAddressRequest{
+		RegionId:     tea.String(p.region),
+		AllocationId: tea.String(allocationID),
+		InstanceId:   tea.String(instanceID),
+		InstanceType: tea.String(instanceTypeECS),
+	}, &util.RuntimeOptions{}); err != nil {
+		// An EIP left tagged is released by the garbage collection once the instance is gone
+		return multierr.Append(fmt.Errorf("associating eip, %w", err), p.untag(ctx, allocationID))
+	}
+	return nil
+}
+
+// claim reserves the EIP for a launch of this process, it returns false if it's already claimed
+func (p *DefaultProvider) claim(allocationID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.claimed[allocationID]; ok {
+		return false
+	}
+	p.claimed[allocationID] = struct{}{}
+	return true
+}
+
+func (p *DefaultProvider) unclaim(allocationID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.claimed, allocationID)
+}
+
+// Disassociate unbinds every EIP Karpenter bound to the instance and returns them to their pool
+func (p *DefaultProvider) Disassociate(ctx context.Context, instanceID string) error {
+	eips, err := p.list(ctx, &vpc.DescribeEipAddressesRequestTag{Key: tea.String(v1alpha1.TagEIPInstance), Value: tea.String(instanceID)})
+	if err != nil {
+		return err
+	}
+	for _, eip := range eips {
+		if err := p.Release(ctx, eip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns all the EIPs Karpenter bound to instances of the cluster
+func (p *DefaultProvider) List(ctx context.Context) ([]*EIP, error) {
+	return p.list(ctx, &vpc.DescribeEipAddressesRequestTag{Key: tea.String(clusterTagKey(ctx))})
+}
+
+// Release unbinds the EIP from its instance and removes the tags added by Karpenter, the EIP itself stays in its pool
+func (p *DefaultProvider) Release(ctx context.Context, eip *EIP) error {
+	if eip.AssociatedInstanceID != "" {
+		if _, err := p.vpcapi.UnassociateEipAddressWithOptions(&vpc.UnassociateEipAddressRequest{
+			RegionId:     tea.String(p.region),
+			AllocationId: tea.String(eip.AllocationID),
+			InstanceId:   tea.String(eip.AssociatedInstanceID),
+			InstanceType: tea.String(instanceTypeECS),
+		}, &util.RuntimeOptions{}); err != nil {
+			return fmt.Errorf("unassociating eip %s, %w", eip.AllocationID, err)
+		}
+	}
+	if err := p.untag(ctx, eip.AllocationID); err != nil {
+		return err
+	}
+	p.unclaim(eip.AllocationID)
+	log.FromContext(ctx).WithValues("allocation-id", eip.AllocationID, "instance", eip.InstanceID).V(1).Info("released eip")
+	return nil
+}
+
+func (p *DefaultProvider) untag(ctx context.Context, allocationID string) error {
+	if _, err := p.vpcapi.UnTagResourcesWithOptions(&vpc.UnTagResourcesRequest{
+		RegionId:     tea.String(p.region),
+		ResourceType: tea.String(resourceTypeEIP),
+		ResourceId:   []*string{tea.String(allocationID)},
+		TagKey:       []*string{tea.String(clusterTagKey(ctx)), tea.String(v1alpha1.TagEIPInstance)},
+	}, &util.RuntimeOptions{}); err != nil {
+		return fmt.Errorf("untagging eip %s, %w", allocationID, err)
+	}
+	return nil
+}
+
+func (p *DefaultProvider) list(_ context.Context, tag *vpc.DescribeEipAddressesRequestTag) ([]*EIP, error) {
+	var eips []*EIP
+	if err := p.describeEipAddresses(&vpc.DescribeEipAddressesRequest{Tag: []*vpc.DescribeEipAddressesRequestTag{tag}}, func(eip *vpc.DescribeEipAddressesResponseBodyEipAddressesEipAddress) {
+		eips = append(eips, newEIP(eip))
+	}); err != nil {
+		return nil, fmt.Errorf("describing eips, %w", err)
+	}
+	return eips, nil
+}
+
+func (p *DefaultProvider) describeEipAddresses(request *vpc.DescribeEipAddressesRequest, process func(*vpc.DescribeEipAddressesResponseBodyEipAddressesEipAddress)) error {
+	runtime := &util.RuntimeOptions{}
+	request.RegionId = tea.String(p.region)
+	request.PageSize = tea.Int32(100)
+	for pageNumber := int32(1); ; pageNumber++ {
+		request.PageNumber = tea.Int32(pageNumber)
+		output, err := p.vpcapi.DescribeEipAddressesWithOptions(request, runtime)
+		if err != nil {
+			return err
+		} else if output.Body == nil || output.Body.TotalCount == nil || output.Body.EipAddresses == nil {
+			return fmt.Errorf("unexpected null value was returned")
+		}
+		for i := range output.Body.EipAddresses.EipAddress {
+			process(output.Body.EipAddresses.EipAddress[i])
+		}
+		if *output.Body.TotalCount <= pageNumber*100 || len(output.Body.EipAddresses.EipAddress) < 100 {
+			break
+		}
+	}
+	return nil
+}
+
+func newEIP(eip *vpc.DescribeEipAddressesResponseBodyEipAddressesEipAddress) *EIP {
+	out := &EIP{
+		AllocationID:         lo.FromPtr(eip.AllocationId),
+		IPAddress:            lo.FromPtr(eip.IpAddress),
+		AssociatedInstanceID: lo.FromPtr(eip.InstanceId),
+	}
+	if eip.Tags != nil {
+		if tag, ok := lo.Find(eip.Tags.Tag, func(t *vpc.DescribeEipAddressesResponseBodyEipAddressesEipAddressTagsTag) bool {
+			return lo.FromPtr(t.Key) == v1alpha1.TagEIPInstance
+		}); ok {
+			out.InstanceID = lo.FromPtr(tag.Value)
+		}
+	}
+	return out
+}
+
+func clusterTagKey(ctx context.Context) string {
+	return fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eip
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	vpc "github.com/alibabacloud-go/vpc-20160428/v6/client"
+	"github.com/samber/lo"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+const clusterTag = "kubernetes.io/cluster/cluster"
+
+type fakeEIP struct {
+	ip         string
+	instanceID string
+	tags       map[string]string
+}
+
+// fakeVPCAPI keeps the EIPs in memory, the embedded interface panics on any other call
+type fakeVPCAPI struct {
+	client.VPCAPI
+	eips map[string]*fakeEIP
+	// associateErrs fails binding the EIPs with these allocation IDs
+	associateErrs map[string]error
+}
+
+func (f *fakeVPCAPI) DescribeEipAddressesWithOptions(request *vpc.DescribeEipAddressesRequest, _ *util.RuntimeOptions) (*vpc.DescribeEipAddressesResponse, error) {
+	var eips []*vpc.DescribeEipAddressesResponseBodyEipAddressesEipAddress
+	for _, id := range lo.Keys(f.eips) {
+		eip := f.eips[id]
+		status := lo.Ternary(eip.instanceID == "", EIPStatusAvailable, "InUse")
+		if request.Status != nil && *request.Status != status {
+			continue
+		}
+		if !lo.EveryBy(request.Tag, func(tag *vpc.DescribeEipAddressesRequestTag) bool {
+			value, ok := eip.tags[lo.FromPtr(tag.Key)]
+			return ok && (tag.Value == nil || *tag.Value == value)
+		}) {
+			continue
+		}
+		eips = append(eips, &vpc.DescribeEipAddressesResponseBodyEipAddressesEipAddress{
+			AllocationId: tea.String(id),
+			IpAddress:    tea.String(eip.ip),
+			InstanceId:   tea.String(eip.instanceID),
+			Status:       tea.String(status),
+			Tags: &vpc.DescribeEipAddressesResponseBodyEipAddressesEipAddressTags{
+				Tag: lo.MapToSlice(eip.tags, func(k, v string) *vpc.DescribeEipAddressesResponseBodyEipAddressesEipAddressTagsTag {
+					return &vpc.DescribeEipAddressesResponseBodyEipAddressesEipAddressTagsTag{Key: tea.String(k), Value: tea.String(v)}
+				}),
+			},
+		})
+	}
+	sort.Slice(eips, func(i, j int) bool { return *eips[i].AllocationId < *eips[j].AllocationId })
+	return &vpc.DescribeEipAddressesResponse{Body: &vpc.DescribeEipAddressesResponseBody{
+		TotalCount:   tea.Int32(int32(len(eips))),
+		EipAddresses: &vpc.DescribeEipAddressesResponseBodyEipAddresses{EipAddress: eips},
+	}}, nil
+}
+
+func (f *fakeVPCAPI) TagResourcesWithOptions(request *vpc.TagResourcesRequest, _ *util.RuntimeOptions) (*vpc.TagResourcesResponse, error) {
+	for _, id := range request.ResourceId {
+		for _, tag := range request.Tag {
+			f.eips[*id].tags[*tag.Key] = *tag.Value
+		}
+	}
+	return &vpc.TagResourcesResponse{}, nil
+}
+
+func (f *fakeVPCAPI) UnTagResourcesWithOptions(request *vpc.UnTagResourcesRequest, _ *util.RuntimeOptions) (*vpc.UnTagResourcesResponse, error) {
+	for _, id := range request.ResourceId {
+		for _, key := range request.TagKey {
+			delete(f.eips[*id].tags, *key)
+		}
+	}
+	return &vpc.UnTagResourcesResponse{}, nil
+}
+
+func (f *fakeVPCAPI) AssociateEipAddressWithOptions(request *vpc.AssociateEipAddressRequest, _ *util.RuntimeOptions) (*vpc.AssociateEipAddressResponse, error) {
+	if err := f.associateErrs[*request.AllocationId]; err != nil {
+		return nil, err
+	}
+	f.eips[*request.AllocationId].instanceID = *request.InstanceId
+	return &vpc.AssociateEipAddressResponse{}, nil
+}
+
+func (f *fakeVPCAPI) UnassociateEipAddressWithOptions(request *vpc.UnassociateEipAddressRequest, _ *util.RuntimeOptions) (*vpc.UnassociateEipAddressResponse, error) {
+	f.eips[*request.AllocationId].instanceID = ""
+	return &vpc.UnassociateEipAddressResponse{}, nil
+}
+
+// boundTo returns the allocation IDs of the EIPs bound to the instance
+func (f *fakeVPCAPI) boundTo(instanceID string) []string {
+	ids := lo.Filter(lo.Keys(f.eips), func(id string, _ int) bool { return f.eips[id].instanceID == instanceID })
+	sort.Strings(ids)
+	return ids
+}
+
+func newPool() map[string]*fakeEIP {
+	return map[string]*fakeEIP{
+		"eip-1":     {ip: "1.1.1.1", tags: map[string]string{"pool": "edge"}},
+		"eip-2":     {ip: "2.2.2.2", tags: map[string]string{"pool": "edge"}},
+		"eip-other": {ip: "3.3.3.3", tags: map[string]string{"pool": "other"}},
+	}
+}
+
+func newNodeClass(eipPoolTags map[string]string) *v1alpha1.ECSNodeClass {
+	return &v1alpha1.ECSNodeClass{Spec: v1alpha1.ECSNodeClassSpec{PublicIP: &v1alpha1.PublicIP{EIPPoolTags: eipPoolTags}}}
+}
+
+func TestAssociate(t *testing.T) {
+	ctx := options.ToContext(context.Background(), &options.Options{ClusterName: "cluster"})
+	vpcapi := &fakeVPCAPI{eips: newPool(), associateErrs: map[string]error{"eip-1": errors.New("IncorrectEipStatus")}}
+	p := NewDefaultProvider("cn-hangzhou", vpcapi)
+	nodeClass := newNodeClass(map[string]string{"pool": "edge"})
+
+	// The EIP that can't be bound is skipped and left without the tags of Karpenter
+	if err := p.Associate(ctx, nodeClass, "i-1"); err != nil {
+		t.Fatalf("associating eip, %v", err)
+	}
+	if got := vpcapi.boundTo("i-1"); len(got) != 1 || got[0] != "eip-2" {
+		t.Errorf("eips bound to i-1 = %v, want [eip-2]", got)
+	}
+	if tags := vpcapi.eips["eip-2"].tags; tags[clusterTag] != "owned" || tags[v1alpha1.TagEIPInstance] != "i-1" {
+		t.Errorf("tags of eip-2 = %v, want the cluster and the instance", tags)
+	}
+	if tags := vpcapi.eips["eip-1"].tags; len(tags) != 1 {
+		t.Errorf("tags of eip-1 = %v, want only the pool tag", tags)
+	}
+
+	// Associating again doesn't bind another EIP
+	if err := p.Associate(ctx, nodeClass, "i-1"); err != nil {
+		t.Fatalf("associating eip again, %v", err)
+	}
+	if got := vpcapi.boundTo("i-1"); len(got) != 1 {
+		t.Errorf("eips bound to i-1 = %v, want a single one", got)
+	}
+
+	// No EIP of the pool is left
+	if err := p.Associate(ctx, nodeClass, "i-2"); err == nil {
+		t.Errorf("associating eip of an exhausted pool, want error")
+	}
+	if got := vpcapi.boundTo("i-2"); len(got) != 0 {
+		t.Errorf("eips bound to i-2 = %v, want none", got)
+	}
+
+	// NodeClasses without a pool don't bind EIPs
+	if err := p.Associate(ctx, &v1alpha1.ECSNodeClass{}, "i-3"); err != nil {
+		t.Fatalf("associating eip without a pool, %v", err)
+	}
+	if got := vpcapi.boundTo("i-3"); len(got) != 0 {
+		t.Errorf("eips bound to i-3 = %v, want none", got)
+	}
+}
+
+func TestAssociateTaggedEIP(t *testing.T) {
+	ctx := options.ToContext(context.Background(), &options.Options{ClusterName: "cluster"})
+	vpcapi := &fakeVPCAPI{eips: newPool()}
+	// An earlier attempt tagged eip-2 for the instance but didn't bind it
+	vpcapi.eips["eip-2"].tags = lo.Assign(vpcapi.eips["eip-2"].tags, map[string]string{clusterTag: "owned", v1alpha1.TagEIPInstance: "i-1"})
+	p := NewDefaultProvider("cn-hangzhou", vpcapi)
+
+	if err := p.Associate(ctx, newNodeClass(map[string]string{"pool": "edge"}), "i-1"); err != nil {
+		t.Fatalf("associating eip, %v", err)
+	}
+	if got := vpcapi.boundTo("i-1"); len(got) != 1 || got[0] != "eip-2" {
+		t.Errorf("eips bound to i-1 = %v, want [eip-2]", got)
+	}
+}
+
+func TestDisassociate(t *testing.T) {
+	ctx := options.ToContext(context.Background(), &options.Options{ClusterName: "cluster"})
+	vpcapi := &fakeVPCAPI{eips: newPool()}
+	p := NewDefaultProvider("cn-hangzhou", vpcapi)
+	nodeClass := newNodeClass(map[string]string{"pool": "edge"})
+
+	for _, instanceID := range []string{"i-1", "i-2"} {
+		if err := p.Associate(ctx, nodeClass, instanceID); err != nil {
+			t.Fatalf("associating eip, %v", err)
+		}
+	}
+	eips, err := p.List(ctx)
+	if err != nil {
+		t.Fatalf("listing eips, %v", err)
+	}
+	if len(eips) != 2 {
+		t.Fatalf("listed %d eips, want 2", len(eips))
+	}
+
+	if err := p.Disassociate(ctx, "i-1"); err != nil {
+		t.Fatalf("disassociating eip, %v", err)
+	}
+	if got := vpcapi.boundTo("i-1"); len(got) != 0 {
+		t.Errorf("eips bound to i-1 = %v, want none", got)
+	}
+	eips, err = p.List(ctx)
+	if err != nil {
+		t.Fatalf("listing eips, %v", err)
+	}
+	if len(eips) != 1 || eips[0].InstanceID != "i-2" || eips[0].AssociatedInstanceID != "i-2" {
+		t.Errorf("listed eips = %v, want the one of i-2", eips)
+	}
+
+	// The released EIP is back in the pool
+	if err := p.Associate(ctx, nodeClass, "i-3"); err != nil {
+		t.Fatalf("associating released eip, %v", err)
+	}
+}
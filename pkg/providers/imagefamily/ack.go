@@ -0,0 +1,259 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+const (
+	// attachScriptURL is the ACK script joining an ECS instance to the cluster, it's served from the internal OSS
+	// endpoint of the region and versioned by the ACK release of the cluster, e.g. 1.30.1-aliyun.1
+	attachScriptURL = "http://aliacs-k8s-%[1]s.oss-%[1]s-internal.aliyuncs.com/public/pkg/run/attach/%[2]s/attach_node.sh"
+	// attachScriptFile is where the attach script is downloaded to, so that a failed download doesn't run a partial script
+	attachScriptFile = "/var/lib/karpenter/attach_node.sh"
+	// kubeletCustomizedArgsFile is sourced by the kubelet unit that the attach script installs
+	kubeletCustomizedArgsFile = "/etc/kubernetes/kubelet-customized-args.conf"
+	clusterCAFile             = "/etc/kubernetes/pki/ca.crt"
+
+	// kubeletConfigDir holds the kubelet configuration drop-ins, the kubelet merges them into its configuration file
+	kubeletConfigDir        = "/etc/kubernetes/kubelet.conf.d"
+	kubeletConfigDropInFile = kubeletConfigDir + "/50-karpenter.conf"
+)
+
+// kubeletConfigDirVersion is the first Kubernetes version whose kubelet reads the drop-ins of --config-dir without
+// enabling a feature gate
+var kubeletConfigDirVersion = version.MustParseGeneric("1.30")
+
+// ackBootstrap renders the user data joining an ECS instance to an ACK cluster through attach_node.sh.
+// The parts of the custom user data of the ECSNodeClass run first, so they can prepare the node before the kubelet starts.
+type ackBootstrap struct {
+	*Options
+	KubeletConfig  *v1alpha1.KubeletConfiguration
+	Taints         []corev1.Taint
+	Labels         map[string]string
+	CustomUserData *string
+}
+
+// Script returns the base64 encoded user data merging the custom user data with the bootstrap script
+func (b ackBootstrap) Script() (string, error) {
+	if err := validateKubeletConfigDropIn(b.KubeletConfig, b.KubernetesVersion); err != nil {
+		return "", err
+	}
+	return mergeUserData(b.CustomUserData, append(hostNameParts(b.HostName), shellScriptPart(b.render()))...)
+}
+
+func (b ackBootstrap) render() string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/bash\nset -o errexit -o pipefail\n")
+	sb.WriteString(hostNameScript(b.HostName))
+	sb.WriteString(caScript(b.CABundle))
+	sb.WriteString(kubeletConfigDropInScript(b.KubeletConfig))
+	if args := kubeletArgs(b.KubeletConfig); len(args) != 0 {
+		fmt.Fprintf(&sb, "mkdir -p %s\n", path.Dir(kubeletCustomizedArgsFile))
+		fmt.Fprintf(&sb, "cat > %s <<'EOF'\nKUBELET_CUSTOMIZED_ARGS=\"%s\"\nEOF\n", kubeletCustomizedArgsFile, strings.Join(args, " "))
+	}
+	fmt.Fprintf(&sb, "mkdir -p %s\n", path.Dir(attachScriptFile))
+	fmt.Fprintf(&sb, "curl -fsSL --retry 5 -o %s %s\n", attachScriptFile, fmt.Sprintf(attachScriptURL, b.Region, b.KubernetesVersion))
+	fmt.Fprintf(&sb, "bash %s \\\n", attachScriptFile)
+	flags := [][2]string{
+		{"--token", b.BootstrapToken},
+		{"--endpoint", strings.TrimPrefix(b.ClusterEndpoint, "https://")},
+	}
+	if b.KubeletConfig != nil && len(b.KubeletConfig.ClusterDNS) != 0 {
+		flags = append(flags, [2]string{"--cluster-dns", strings.Join(b.KubeletConfig.ClusterDNS, ",")})
+	}
+	if len(b.Labels) != 0 {
+		flags = append(flags, [2]string{"--labels", joinMap(b.Labels, "=")})
+	}
+	if len(b.Taints) != 0 {
+		flags = append(flags, [2]string{"--taints", joinTaints(b.Taints)})
+	}
+	for i, flag := range flags {
+		fmt.Fprintf(&sb, "  %s %s%s\n", flag[0], shellQuote(flag[1]), lo.Ternary(i == len(flags)-1, "", " \\"))
+	}
+	return sb.String()
+}
+
+// caScript returns the shell lines writing the CA bundle of the API server
+func caScript(caBundle *string) string {
+	if lo.FromPtr(caBundle) == "" {
+		return ""
+	}
+	return fmt.Sprintf("mkdir -p %s\necho %s | base64 -d > %s\n", path.Dir(clusterCAFile), shellQuote(*caBundle), clusterCAFile)
+}
+
+// kubeletArgs returns the kubelet flags of the KubeletConfiguration, cluster DNS is passed to the attach script instead
+//
+//nolint:gocyclo
+func kubeletArgs(kubeletConfig *v1alpha1.KubeletConfiguration) []string {
+	if kubeletConfig == nil {
+		return nil
+	}
+	var args []string
+	if kubeletConfig.MaxPods != nil {
+		args = append(args, fmt.Sprintf("--max-pods=%d", *kubeletConfig.MaxPods))
+	}
+	if kubeletConfig.PodsPerCore != nil {
+		args = append(args, fmt.Sprintf("--pods-per-core=%d", *kubeletConfig.PodsPerCore))
+	}
+	if len(kubeletConfig.SystemReserved) != 0 {
+		args = append(args, fmt.Sprintf("--system-reserved=%s", joinMap(kubeletConfig.SystemReserved, "=")))
+	}
+	if len(kubeletConfig.KubeReserved) != 0 {
+		args = append(args, fmt.Sprintf("--kube-reserved=%s", joinMap(kubeletConfig.KubeReserved, "=")))
+	}
+	if len(kubeletConfig.EvictionHard) != 0 {
+		args = append(args, fmt.Sprintf("--eviction-hard=%s", joinMap(kubeletConfig.EvictionHard, "<")))
+	}
+	if len(kubeletConfig.EvictionSoft) != 0 {
+		args = append(args, fmt.Sprintf("--eviction-soft=%s", joinMap(kubeletConfig.EvictionSoft, "<")))
+	}
+	if len(kubeletConfig.EvictionSoftGracePeriod) != 0 {
+		gracePeriods := lo.MapValues(kubeletConfig.EvictionSoftGracePeriod, func(d metav1.Duration, _ string) string { return d.Duration.String() })
+		args = append(args, fmt.Sprintf("--eviction-soft-grace-period=%s", joinMap(gracePeriods, "=")))
+	}
+	if kubeletConfig.EvictionMaxPodGracePeriod != nil {
+		args = append(args, fmt.Sprintf("--eviction-max-pod-grace-period=%d", *kubeletConfig.EvictionMaxPodGracePeriod))
+	}
+	if kubeletConfig.ImageGCHighThresholdPercent != nil {
+		args = append(args, fmt.Sprintf("--image-gc-high-threshold=%d", *kubeletConfig.ImageGCHighThresholdPercent))
+	}
+	if kubeletConfig.ImageGCLowThresholdPercent != nil {
+		args = append(args, fmt.Sprintf("--image-gc-low-threshold=%d", *kubeletConfig.ImageGCLowThresholdPercent))
+	}
+	if kubeletConfig.CPUCFSQuota != nil {
+		args = append(args, fmt.Sprintf("--cpu-cfs-quota=%t", *kubeletConfig.CPUCFSQuota))
+	}
+	if kubeletConfig.CPUManagerPolicy != nil {
+		args = append(args, fmt.Sprintf("--cpu-manager-policy=%s", *kubeletConfig.CPUManagerPolicy))
+	}
+	if kubeletConfig.TopologyManagerPolicy != nil {
+		args = append(args, fmt.Sprintf("--topology-manager-policy=%s", *kubeletConfig.TopologyManagerPolicy))
+	}
+	if kubeletConfig.TopologyManagerScope != nil {
+		args = append(args, fmt.Sprintf("--topology-manager-scope=%s", *kubeletConfig.TopologyManagerScope))
+	}
+	if kubeletConfig.MemoryManagerPolicy != nil {
+		args = append(args, fmt.Sprintf("--memory-manager-policy=%s", *kubeletConfig.MemoryManagerPolicy))
+	}
+	if len(kubeletConfig.ReservedMemory) != 0 {
+		reservations := lo.Map(kubeletConfig.ReservedMemory, func(r v1alpha1.MemoryReservation, _ int) string {
+			return fmt.Sprintf("%d:%s", r.NUMANode, joinMap(r.Limits, "="))
+		})
+		sort.Strings(reservations)
+		args = append(args, fmt.Sprintf("--reserved-memory=%s", strings.Join(reservations, ";")))
+	}
+	if kubeletConfig.ContainerLogMaxSize != nil {
+		args = append(args, fmt.Sprintf("--container-log-max-size=%s", *kubeletConfig.ContainerLogMaxSize))
+	}
+	if kubeletConfig.ContainerLogMaxFiles != nil {
+		args = append(args, fmt.Sprintf("--container-log-max-files=%d", *kubeletConfig.ContainerLogMaxFiles))
+	}
+	if kubeletConfig.RegistryPullQPS != nil {
+		args = append(args, fmt.Sprintf("--registry-qps=%d", *kubeletConfig.RegistryPullQPS))
+	}
+	if kubeletConfig.RegistryBurst != nil {
+		args = append(args, fmt.Sprintf("--registry-burst=%d", *kubeletConfig.RegistryBurst))
+	}
+	if len(kubeletConfig.AllowedUnsafeSysctls) != 0 {
+		args = append(args, fmt.Sprintf("--allowed-unsafe-sysctls=%s", strings.Join(kubeletConfig.AllowedUnsafeSysctls, ",")))
+	}
+	if kubeletConfigDropIn(kubeletConfig) != "" {
+		args = append(args, fmt.Sprintf("--config-dir=%s", kubeletConfigDir))
+	}
+	return args
+}
+
+// kubeletConfigDropIn returns the kubelet configuration drop-in of the settings without a kubelet flag, it's empty
+// if none of them are set
+func kubeletConfigDropIn(kubeletConfig *v1alpha1.KubeletConfiguration) string {
+	if kubeletConfig == nil {
+		return ""
+	}
+	config := map[string]interface{}{}
+	if kubeletConfig.ShutdownGracePeriod != nil {
+		config["shutdownGracePeriod"] = kubeletConfig.ShutdownGracePeriod.Duration.String()
+	}
+	if kubeletConfig.ShutdownGracePeriodCriticalPods != nil {
+		config["shutdownGracePeriodCriticalPods"] = kubeletConfig.ShutdownGracePeriodCriticalPods.Duration.String()
+	}
+	if len(config) == 0 {
+		return ""
+	}
+	config["apiVersion"] = "kubelet.config.k8s.io/v1beta1"
+	config["kind"] = "KubeletConfiguration"
+	return string(lo.Must(yaml.Marshal(config)))
+}
+
+// validateKubeletConfigDropIn returns an error if the KubeletConfiguration has settings of the drop-in but the kubelet
+// of the Kubernetes version doesn't read drop-ins, the settings would be ignored otherwise
+func validateKubeletConfigDropIn(kubeletConfig *v1alpha1.KubeletConfiguration, kubernetesVersion string) error {
+	if kubeletConfigDropIn(kubeletConfig) == "" {
+		return nil
+	}
+	v, err := version.ParseGeneric(kubernetesVersion)
+	if err != nil {
+		return fmt.Errorf("parsing kubernetes version, %w", err)
+	}
+	if v.LessThan(kubeletConfigDirVersion) {
+		return fmt.Errorf("shutdownGracePeriod and shutdownGracePeriodCriticalPods require Kubernetes %s or later", kubeletConfigDirVersion)
+	}
+	return nil
+}
+
+// kubeletConfigDropInScript returns the shell lines writing the kubelet configuration drop-in
+func kubeletConfigDropInScript(kubeletConfig *v1alpha1.KubeletConfiguration) string {
+	dropIn := kubeletConfigDropIn(kubeletConfig)
+	if dropIn == "" {
+		return ""
+	}
+	return fmt.Sprintf("mkdir -p %s\ncat > %s <<'EOF'\n%sEOF\n", kubeletConfigDir, kubeletConfigDropInFile, dropIn)
+}
+
+// joinMap joins the sorted entries of the map, so the rendered user data is stable across launches
+func joinMap(m map[string]string, sep string) string {
+	entries := lo.MapToSlice(m, func(k, v string) string { return k + sep + v })
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+func joinTaints(taints []corev1.Taint) string {
+	entries := lo.Map(taints, func(t corev1.Taint, _ int) string {
+		if t.Value == "" {
+			return fmt.Sprintf("%s:%s", t.Key, t.Effect)
+		}
+		return fmt.Sprintf("%s=%s:%s", t.Key, t.Value, t.Effect)
+	})
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"encoding/base64"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the user data")
+
+func TestACKBootstrap(t *testing.T) {
+	options := &Options{
+		ClusterName:       "test-cluster",
+		ClusterEndpoint:   "https://192.168.0.10:6443",
+		CABundle:          lo.ToPtr(base64.StdEncoding.EncodeToString([]byte("ca-data"))),
+		BootstrapToken:    "abcdef.0123456789abcdef",
+		Region:            "cn-hangzhou",
+		KubernetesVersion: "1.30.1-aliyun.1",
+	}
+	tests := []struct {
+		name      string
+		bootstrap ackBootstrap
+	}{
+		{
+			name: "minimal",
+			bootstrap: ackBootstrap{
+				Options: options,
+				Taints:  []corev1.Taint{karpv1.UnregisteredNoExecuteTaint},
+			},
+		},
+		{
+			name: "kubelet",
+			bootstrap: ackBootstrap{
+				Options: options,
+				KubeletConfig: &v1alpha1.KubeletConfiguration{
+					ClusterDNS:                  []string{"172.16.0.10"},
+					MaxPods:                     lo.ToPtr[int32](110),
+					PodsPerCore:                 lo.ToPtr[int32](10),
+					SystemReserved:              map[string]string{"memory": "200Mi", "cpu": "100m"},
+					KubeReserved:                map[string]string{"cpu": "200m"},
+					EvictionHard:                map[string]string{"memory.available": "5%", "nodefs.available": "10%"},
+					EvictionSoft:                map[string]string{"memory.available": "10%"},
+					EvictionSoftGracePeriod:     map[string]metav1.Duration{"memory.available": {Duration: time.Minute}},
+					EvictionMaxPodGracePeriod:   lo.ToPtr[int32](60),
+					ImageGCHighThresholdPercent: lo.ToPtr[int32](85),
+					ImageGCLowThresholdPercent:  lo.ToPtr[int32](80),
+					CPUCFSQuota:                 lo.ToPtr(false),
+					CPUManagerPolicy:            lo.ToPtr("static"),
+					TopologyManagerPolicy:       lo.ToPtr("single-numa-node"),
+					TopologyManagerScope:        lo.ToPtr("pod"),
+					MemoryManagerPolicy:         lo.ToPtr("Static"),
+					ReservedMemory: []v1alpha1.MemoryReservation{
+						{NUMANode: 1, Limits: map[string]string{"memory": "100Mi"}},
+						{NUMANode: 0, Limits: map[string]string{"memory": "200Mi", "hugepages-2Mi": "0"}},
+					},
+					ContainerLogMaxSize:             lo.ToPtr("50Mi"),
+					ContainerLogMaxFiles:            lo.ToPtr[int32](3),
+					RegistryPullQPS:                 lo.ToPtr[int32](10),
+					RegistryBurst:                   lo.ToPtr[int32](20),
+					ShutdownGracePeriod:             &metav1.Duration{Duration: time.Minute},
+					ShutdownGracePeriodCriticalPods: &metav1.Duration{Duration: 20 * time.Second},
+					AllowedUnsafeSysctls:            []string{"net.core.somaxconn", "kernel.msg*"},
+				},
+				Taints: []corev1.Taint{
+					karpv1.UnregisteredNoExecuteTaint,
+					{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+				},
+				Labels: map[string]string{
+					karpv1.NodePoolLabelKey:     "default",
+					karpv1.CapacityTypeLabelKey: karpv1.CapacityTypeSpot,
+					"team":                      "o'brien",
+				},
+			},
+		},
+		{
+			name: "custom-user-data",
+			bootstrap: ackBootstrap{
+				Options: &Options{
+					ClusterEndpoint:   options.ClusterEndpoint,
+					BootstrapToken:    options.BootstrapToken,
+					Region:            options.Region,
+					KubernetesVersion: options.KubernetesVersion,
+					HostName:          "karpenter-default-abcde",
+				},
+				Taints:         []corev1.Taint{karpv1.UnregisteredNoExecuteTaint},
+				CustomUserData: lo.ToPtr("#!/bin/bash\necho 'preparing node'\nmkdir -p /data\n"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userData, err := tt.bootstrap.Script()
+			if err != nil {
+				t.Fatalf("generating user data, %v", err)
+			}
+			expectGolden(t, "ack-"+tt.name, userData)
+		})
+	}
+}
+
+func TestKubeletConfigDropInVersion(t *testing.T) {
+	shutdown := &v1alpha1.KubeletConfiguration{ShutdownGracePeriod: &metav1.Duration{Duration: time.Minute}}
+	for _, tt := range []struct {
+		kubeletConfig     *v1alpha1.KubeletConfiguration
+		kubernetesVersion string
+		valid             bool
+	}{
+		{kubeletConfig: shutdown, kubernetesVersion: "1.30.1-aliyun.1", valid: true},
+		{kubeletConfig: shutdown, kubernetesVersion: "1.31.1-aliyun.1", valid: true},
+		// The kubelet ignores the drop-ins before 1.30
+		{kubeletConfig: shutdown, kubernetesVersion: "1.28.9-aliyun.1"},
+		{kubeletConfig: &v1alpha1.KubeletConfiguration{MaxPods: lo.ToPtr[int32](64)}, kubernetesVersion: "1.28.9-aliyun.1", valid: true},
+	} {
+		if err := validateKubeletConfigDropIn(tt.kubeletConfig, tt.kubernetesVersion); (err == nil) != tt.valid {
+			t.Errorf("validating kubelet config drop-in on %s, got error %v, want valid %t", tt.kubernetesVersion, err, tt.valid)
+		}
+	}
+}
+
+// expectGolden compares the decoded user data with the golden file of the name, run with -update to rewrite it
+func expectGolden(t *testing.T, name, userData string) {
+	t.Helper()
+	golden := filepath.Join("testdata", name+".golden")
+	decoded, err := base64.StdEncoding.DecodeString(userData)
+	if err != nil {
+		t.Fatalf("decoding user data, %v", err)
+	}
+	if *update {
+		if err := os.WriteFile(golden, decoded, 0o600); err != nil {
+			t.Fatalf("writing %s, %v", golden, err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading %s, %v", golden, err)
+	}
+	if string(decoded) != string(want) {
+		t.Errorf("user data mismatch, got:\n%s\nwant:\n%s", decoded, want)
+	}
+}
@@ -18,7 +18,6 @@ package imagefamily
 
 import (
 	"context"
-	"encoding/base64"
 	"regexp"
 
 	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
@@ -38,21 +37,21 @@ type AlibabaCloudLinux3 struct {
 	*Options
 }
 
-func (a AlibabaCloudLinux3) UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, instanceTypes []*cloudprovider.InstanceType, customUserData *string) string {
-	// in-cluster user data
-	// curl http://aliacs-k8s-{region}.oss-{region}-internal.aliyuncs.com/public/pkg/run/attach/{clusterversion}/attach_node.sh | bash -s -- --token **** --endpoint 1.x.x.x --cluster-dns 1.x.x.x --cluster-id ...
-	//
-	// token: secret/bootstrap-token
-
-	// TODO: implement me
-	return base64.StdEncoding.EncodeToString([]byte("sleep 300"))
+func (a AlibabaCloudLinux3) UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, instanceTypes []*cloudprovider.InstanceType, customUserData *string) (string, error) {
+	return ackBootstrap{
+		Options:        a.Options,
+		KubeletConfig:  kubeletConfig,
+		Taints:         taints,
+		Labels:         labels,
+		CustomUserData: customUserData,
+	}.Script()
 }
 
 func alibabaCloudLinux3ImageFilterFunc(imageID string) bool {
 	return alibabaCloudLinux3ImageIDRegex.Match([]byte(imageID))
 }
 
-func (a AlibabaCloudLinux3) DescribeImageQuery(_ context.Context) (DescribeImageQuery, error) {
+func (a AlibabaCloudLinux3) DescribeImageQuery(_ context.Context, _ string) (DescribeImageQuery, error) {
 	return DescribeImageQuery{
 		BaseQuery: DescribeImageQueryBase{
 			DescribeImagesRequest: &ecs.DescribeImagesRequest{
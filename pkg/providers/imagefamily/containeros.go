@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+var (
+	// The format should look like lifsea_3_x64_10G_containerd_1_30_1_20240814.vhd, the kubelet of the image
+	// is built for the Kubernetes version in the name
+	containerOSImageIDRegex = regexp.MustCompile(`^lifsea_3_(x64|arm64)_\d+G_containerd_(\d+)_(\d+)_\d+_\d+\.vhd$`)
+
+	// ContainerOS keeps the container images on the system disk, which is shared with the read-only OS partitions
+	containerOSDefaultSystemDisk = v1alpha1.SystemDisk{
+		Category: tea.String("cloud_auto"),
+		Size:     tea.Int32(40),
+	}
+)
+
+const (
+	bootstrapKubeconfigFile = "/etc/kubernetes/bootstrap-kubelet.conf"
+	kubeletDropInFile       = "/etc/systemd/system/kubelet.service.d/20-karpenter.conf"
+)
+
+// ContainerOS is the immutable OS of ACK with the kubelet and containerd preinstalled, so nodes join through
+// the TLS bootstrap of the kubelet rather than the ACK attach script, which installs packages.
+type ContainerOS struct {
+	*Options
+}
+
+func (c ContainerOS) UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, instanceTypes []*cloudprovider.InstanceType, customUserData *string) (string, error) {
+	return containerOSBootstrap{
+		Options:        c.Options,
+		KubeletConfig:  kubeletConfig,
+		Taints:         taints,
+		Labels:         labels,
+		CustomUserData: customUserData,
+	}.Script()
+}
+
+func (c ContainerOS) DescribeImageQuery(_ context.Context, k8sVersion string) (DescribeImageQuery, error) {
+	major, minor, ok := strings.Cut(k8sVersion, ".")
+	if !ok {
+		return DescribeImageQuery{}, fmt.Errorf("parsing kubernetes version %q", k8sVersion)
+	}
+	return DescribeImageQuery{
+		BaseQuery: DescribeImageQueryBase{
+			DescribeImagesRequest: &ecs.DescribeImagesRequest{
+				ImageOwnerAlias: tea.String("system"),
+				OSType:          tea.String("linux"),
+				ActionType:      tea.String("CreateEcs"),
+			},
+			KubernetesVersion: k8sVersion,
+		},
+		FilterFunc: func(imageID string) bool {
+			matches := containerOSImageIDRegex.FindStringSubmatch(imageID)
+			return matches != nil && matches[2] == major && matches[3] == minor
+		},
+	}, nil
+}
+
+func (c ContainerOS) DefaultSystemDisk() *v1alpha1.SystemDisk {
+	return &containerOSDefaultSystemDisk
+}
+
+// containerOSBootstrap renders the user data writing the bootstrap kubeconfig and the flags of the preinstalled
+// kubelet, the custom user data of the ECSNodeClass runs first.
+type containerOSBootstrap struct {
+	*Options
+	KubeletConfig  *v1alpha1.KubeletConfiguration
+	Taints         []corev1.Taint
+	Labels         map[string]string
+	CustomUserData *string
+}
+
+// Script returns the base64 encoded user data merging the custom user data with the bootstrap script
+func (b containerOSBootstrap) Script() (string, error) {
+	if err := validateKubeletConfigDropIn(b.KubeletConfig, b.KubernetesVersion); err != nil {
+		return "", err
+	}
+	return mergeUserData(b.CustomUserData, append(hostNameParts(b.HostName), shellScriptPart(b.render()))...)
+}
+
+func (b containerOSBootstrap) render() string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/bash\n")
+	sb.WriteString(metadataScript())
+	sb.WriteString(hostNameScript(b.HostName))
+	sb.WriteString(providerIDScript())
+	sb.WriteString(caScript(b.CABundle))
+	sb.WriteString(kubeletConfigDropInScript(b.KubeletConfig))
+	fmt.Fprintf(&sb, "cat > %s <<'EOF'\n%sEOF\n", bootstrapKubeconfigFile, bootstrapKubeconfig(b.ClusterEndpoint, b.BootstrapToken))
+
+	args := append(kubeletArgs(b.KubeletConfig), "--provider-id="+providerIDVariable)
+	if b.KubeletConfig != nil && len(b.KubeletConfig.ClusterDNS) != 0 {
+		args = append(args, fmt.Sprintf("--cluster-dns=%s", strings.Join(b.KubeletConfig.ClusterDNS, ",")))
+	}
+	if len(b.Labels) != 0 {
+		args = append(args, fmt.Sprintf("--node-labels=%s", joinMap(b.Labels, "=")))
+	}
+	if len(b.Taints) != 0 {
+		args = append(args, fmt.Sprintf("--register-with-taints=%s", joinTaints(b.Taints)))
+	}
+	fmt.Fprintf(&sb, "mkdir -p %s\n", path.Dir(kubeletDropInFile))
+	// The heredoc isn't quoted to expand the provider ID, labels, taints and kubelet flags have no shell expansions
+	fmt.Fprintf(&sb, "cat > %s <<EOF\n[Service]\nEnvironment=\"KUBELET_EXTRA_ARGS=%s\"\nEOF\n", kubeletDropInFile, strings.Join(args, " "))
+	sb.WriteString("systemctl daemon-reload\nsystemctl enable --now kubelet\n")
+	return sb.String()
+}
+
+// bootstrapKubeconfig returns the kubeconfig the kubelet requests its client certificate with
+func bootstrapKubeconfig(endpoint, token string) string {
+	if !strings.HasPrefix(endpoint, "https://") {
+		endpoint = "https://" + endpoint
+	}
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: kubernetes
+  cluster:
+    certificate-authority: %s
+    server: %s
+contexts:
+- name: kubelet-bootstrap
+  context:
+    cluster: kubernetes
+    user: kubelet-bootstrap
+current-context: kubelet-bootstrap
+users:
+- name: kubelet-bootstrap
+  user:
+    token: %s
+`, clusterCAFile, endpoint, token)
+}
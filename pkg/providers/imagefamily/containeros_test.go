@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+func TestContainerOSBootstrap(t *testing.T) {
+	options := &Options{
+		ClusterEndpoint: "192.168.0.10:6443",
+		CABundle:        lo.ToPtr(base64.StdEncoding.EncodeToString([]byte("ca-data"))),
+		BootstrapToken:  "abcdef.0123456789abcdef",
+	}
+	userData, err := ContainerOS{Options: options}.UserData(
+		&v1alpha1.KubeletConfiguration{
+			ClusterDNS: []string{"172.16.0.10"},
+			MaxPods:    lo.ToPtr[int32](64),
+		},
+		[]corev1.Taint{karpv1.UnregisteredNoExecuteTaint},
+		map[string]string{karpv1.NodePoolLabelKey: "default"},
+		nil,
+		lo.ToPtr("#!/bin/bash\necho 'preparing node'\n"),
+	)
+	if err != nil {
+		t.Fatalf("generating user data, %v", err)
+	}
+	expectGolden(t, "containeros", userData)
+}
+
+func TestContainerOSImageFilter(t *testing.T) {
+	query, err := ContainerOS{}.DescribeImageQuery(context.Background(), "1.30")
+	if err != nil {
+		t.Fatalf("describing image query, %v", err)
+	}
+	for imageID, want := range map[string]bool{
+		"lifsea_3_x64_10G_containerd_1_30_1_20240814.vhd":   true,
+		"lifsea_3_arm64_10G_containerd_1_30_7_20241120.vhd": true,
+		"lifsea_3_x64_10G_containerd_1_28_3_20240129.vhd":   false,
+		"lifsea_3_x64_10G_containerd_1_3_0_20240129.vhd":    false,
+		"aliyun_3_x64_20G_alibase_20240819.vhd":             false,
+	} {
+		if got := query.FilterFunc(imageID); got != want {
+			t.Errorf("FilterFunc(%s) = %t, want %t", imageID, got, want)
+		}
+	}
+}
@@ -31,12 +31,12 @@ type Custom struct {
 	*Options
 }
 
-// UserData returns the default userdata script for the Image Family
-func (c Custom) UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, instanceTypes []*cloudprovider.InstanceType, customUserData *string) string {
-	return base64.StdEncoding.EncodeToString([]byte(tea.StringValue(customUserData)))
+// UserData returns the custom user data as is, it's neither parsed nor merged with a bootstrap
+func (c Custom) UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, instanceTypes []*cloudprovider.InstanceType, customUserData *string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(tea.StringValue(customUserData))), nil
 }
 
-func (c Custom) DescribeImageQuery(_ context.Context) (DescribeImageQuery, error) {
+func (c Custom) DescribeImageQuery(_ context.Context, _ string) (DescribeImageQuery, error) {
 	return DescribeImageQuery{}, nil
 }
 
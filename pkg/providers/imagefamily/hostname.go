@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"fmt"
+)
+
+// metadataScript returns the shell lines defining the metadata function the other scripts resolve instance values with.
+// The metadata service is queried in security hardening mode, which works whether or not tokens are required.
+func metadataScript() string {
+	return `METADATA_TOKEN=$(curl -s -X PUT "http://100.100.100.200/latest/api/token" -H "X-aliyun-ecs-metadata-token-ttl-seconds: 300")
+metadata() { curl -s -H "X-aliyun-ecs-metadata-token: ${METADATA_TOKEN}" "http://100.100.100.200/latest/meta-data/$1"; }
+`
+}
+
+// hostNameScript returns the shell lines setting the host name before the kubelet starts, the kubelet then registers under it.
+// The instance is launched with the host name already, setting it again keeps images that reset it at boot in line.
+func hostNameScript(hostName string) string {
+	if hostName == "" {
+		return ""
+	}
+	return fmt.Sprintf(`hostnamectl set-hostname "%s"
+`, hostName)
+}
+
+// providerIDVariable is the provider ID of the instance, the kubelet registers the node with it rather than relying on
+// a cloud controller manager, which self-managed clusters don't run. It has the <region>.<instance id> format of ACK nodes.
+const providerIDVariable = "${PROVIDER_ID}"
+
+// providerIDScript returns the shell lines resolving the provider ID, the metadata function must be defined first
+func providerIDScript() string {
+	return "PROVIDER_ID=\"$(metadata region-id).$(metadata instance-id)\"\n"
+}
@@ -31,6 +31,8 @@ import (
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/version"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
 )
 
 type Provider interface {
@@ -39,18 +41,20 @@ type Provider interface {
 
 type DefaultProvider struct {
 	sync.Mutex
-	cache  *cache.Cache
-	region string
-	ecsapi *ecs.Client
-	cm     *pretty.ChangeMonitor
+	cache           *cache.Cache
+	region          string
+	ecsapi          client.ECSAPI
+	versionProvider version.Provider
+	cm              *pretty.ChangeMonitor
 }
 
-func NewDefaultProvider(region string, ecsapi *ecs.Client, cache *cache.Cache) *DefaultProvider {
+func NewDefaultProvider(region string, ecsapi client.ECSAPI, versionProvider version.Provider, cache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
-		cache:  cache,
-		region: region,
-		ecsapi: ecsapi,
-		cm:     pretty.NewChangeMonitor(),
+		cache:           cache,
+		region:          region,
+		ecsapi:          ecsapi,
+		versionProvider: versionProvider,
+		cm:              pretty.NewChangeMonitor(),
 	}
 }
 
@@ -80,8 +84,12 @@ func (p *DefaultProvider) DescribeImageQueries(ctx context.Context, nodeClass *v
 	if term, ok := lo.Find(nodeClass.Spec.ImageSelectorTerms, func(term v1alpha1.ImageSelectorTerm) bool {
 		return term.Alias != ""
 	}); ok {
+		k8sVersion, err := p.versionProvider.Get(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting kubernetes version, %w", err)
+		}
 		imageFamily := GetImageFamily(v1alpha1.ImageFamilyFromAlias(term.Alias), nil)
-		query, err := imageFamily.DescribeImageQuery(ctx)
+		query, err := imageFamily.DescribeImageQuery(ctx, k8sVersion)
 		if err != nil {
 			return nil, err
 		}
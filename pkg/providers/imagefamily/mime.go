@@ -0,0 +1,284 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+const (
+	contentTypeMultipart   = "multipart/mixed"
+	contentTypeShellScript = "text/x-shellscript"
+	contentTypeCloudConfig = "text/cloud-config"
+	contentTypeBoothook    = "text/cloud-boothook"
+	contentTypeIncludeURL  = "text/x-include-url"
+
+	// mimeBoundary is fixed so that the same parts always render the same user data
+	mimeBoundary = "==KARPENTER-BOUNDARY=="
+	// cloudConfigMergeType makes cloud-init append the lists of a cloud-config part, like runcmd and write_files, to the
+	// lists of the previous parts and keep the keys they set, rather than replacing them
+	// Ref: https://cloudinit.readthedocs.io/en/latest/reference/merging.html
+	cloudConfigMergeType = "list(append)+dict(no_replace,recurse_list)+str()"
+)
+
+var (
+	// userDataMarkers map the first line of a single part user data to its content type, like cloud-init does
+	userDataMarkers = []lo.Tuple2[string, string]{
+		{A: "#!", B: contentTypeShellScript},
+		{A: "#cloud-config", B: contentTypeCloudConfig},
+		{A: "#cloud-boothook", B: contentTypeBoothook},
+		{A: "#include", B: contentTypeIncludeURL},
+	}
+)
+
+// mimePart is a part of a MIME multipart user data archive
+type mimePart struct {
+	ContentType string
+	// MergeType is the Merge-Type header of a cloud-config part, cloudConfigMergeType unless the custom part sets one
+	MergeType string
+	Content   string
+}
+
+func shellScriptPart(script string) mimePart {
+	return mimePart{ContentType: contentTypeShellScript, Content: script}
+}
+
+func cloudConfigPart(config string) mimePart {
+	return mimePart{ContentType: contentTypeCloudConfig, Content: config}
+}
+
+// hostNameParts keeps cloud-init from resetting the host name the bootstrap script sets on later boots
+func hostNameParts(hostName string) []mimePart {
+	if hostName == "" {
+		return nil
+	}
+	return []mimePart{cloudConfigPart("#cloud-config\npreserve_hostname: true\n")}
+}
+
+// ValidateUserData returns why the custom user data of an ECSNodeClass can't be merged with the bootstrap user data.
+// The custom user data of the Custom and Windows families and of the ack-nodepool bootstrap mode isn't merged.
+func ValidateUserData(nodeClass *v1alpha1.ECSNodeClass) error {
+	if nodeClass.BootstrapMode() == v1alpha1.BootstrapModeACKNodePool {
+		return nil
+	}
+	switch nodeClass.ImageFamily() {
+	case v1alpha1.ImageFamilyCustom, v1alpha1.ImageFamilyWindows2019, v1alpha1.ImageFamilyWindows2022:
+		return nil
+	}
+	// The scalars of the bootstrap cloud-config only depend on the host name
+	_, err := mergeUserData(nodeClass.Spec.UserData, hostNameParts(lo.FromPtr(nodeClass.Spec.HostNameTemplate))...)
+	return err
+}
+
+// mergeUserData returns the base64 encoded MIME multipart archive of the custom user data parts followed by the
+// bootstrap parts. The custom user data is either a MIME multipart archive or a single part like a shell script or
+// cloud-config, cloud-init merges the cloud-config parts with the Merge-Type of each part. Custom cloud-config parts must
+// not set scalars of the bootstrap parts to other values.
+func mergeUserData(customUserData *string, bootstrap ...mimePart) (string, error) {
+	parts, err := parseUserData(lo.FromPtr(customUserData))
+	if err != nil {
+		return "", fmt.Errorf("parsing user data, %w", err)
+	}
+	if err := validateCloudConfigs(parts, bootstrap); err != nil {
+		return "", err
+	}
+	parts = append(parts, bootstrap...)
+	archive, err := writeMIMEMultipart(parts)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(archive)), nil
+}
+
+// parseUserData returns the parts of the user data, the empty user data has no parts
+func parseUserData(userData string) ([]mimePart, error) {
+	if strings.TrimSpace(userData) == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(userData, "MIME-Version:") || strings.HasPrefix(userData, "Content-Type:") {
+		return parseMIME(userData)
+	}
+	for _, marker := range userDataMarkers {
+		if strings.HasPrefix(userData, marker.A) {
+			return []mimePart{{ContentType: marker.B, Content: userData}}, nil
+		}
+	}
+	// Plain commands were embedded in the bootstrap script before, they keep running with bash
+	return []mimePart{{ContentType: contentTypeShellScript, Content: "#!/bin/bash\n" + userData}}, nil
+}
+
+func parseMIME(userData string) ([]mimePart, error) {
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(userData)))
+	if err != nil {
+		return nil, fmt.Errorf("reading MIME headers, %w", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing content type, %w", err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		content, err := decodeMIMEContent(msg.Body, msg.Header.Get("Content-Transfer-Encoding"))
+		if err != nil {
+			return nil, err
+		}
+		return []mimePart{{ContentType: mime.FormatMediaType(mediaType, params), MergeType: msg.Header.Get("Merge-Type"), Content: content}}, nil
+	}
+	if mediaType != contentTypeMultipart {
+		return nil, fmt.Errorf("unsupported content type %s", mediaType)
+	}
+
+	var parts []mimePart
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading MIME part, %w", err)
+		}
+		contentType := lo.CoalesceOrEmpty(part.Header.Get("Content-Type"), "text/plain")
+		partMediaType, partParams, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, fmt.Errorf("parsing content type of MIME part %d, %w", len(parts)+1, err)
+		}
+		if strings.HasPrefix(partMediaType, "multipart/") {
+			return nil, fmt.Errorf("nested multipart content in MIME part %d is not supported", len(parts)+1)
+		}
+		content, err := decodeMIMEContent(part, part.Header.Get("Content-Transfer-Encoding"))
+		if err != nil {
+			return nil, fmt.Errorf("decoding MIME part %d, %w", len(parts)+1, err)
+		}
+		parts = append(parts, mimePart{ContentType: mime.FormatMediaType(partMediaType, partParams), MergeType: part.Header.Get("Merge-Type"), Content: content})
+	}
+	return parts, nil
+}
+
+func decodeMIMEContent(r io.Reader, transferEncoding string) (string, error) {
+	switch strings.ToLower(transferEncoding) {
+	case "", "7bit", "8bit", "binary":
+	case "base64":
+		// The decoder ignores the line breaks of the encoded content
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	default:
+		return "", fmt.Errorf("unsupported content transfer encoding %s", transferEncoding)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("reading content, %w", err)
+	}
+	return string(content), nil
+}
+
+// validateCloudConfigs returns an error for cloud-config parts that cloud-init can't parse and for custom parts setting
+// scalars of the bootstrap parts to other values, since the merge type of the bootstrap parts keeps the custom ones
+func validateCloudConfigs(custom, bootstrap []mimePart) error {
+	customConfigs, err := parseCloudConfigs(custom, 0)
+	if err != nil {
+		return err
+	}
+	bootstrapConfigs, err := parseCloudConfigs(bootstrap, len(custom))
+	if err != nil {
+		return err
+	}
+	for _, bootstrapConfig := range bootstrapConfigs {
+		for _, customConfig := range customConfigs {
+			if key, ok := conflictingScalar(customConfig, bootstrapConfig, ""); ok {
+				return fmt.Errorf("cloud-config key %q of the user data conflicts with the bootstrap user data", key)
+			}
+		}
+	}
+	return nil
+}
+
+// parseCloudConfigs returns the cloud-configs of the parts, offset is the number of parts before them
+func parseCloudConfigs(parts []mimePart, offset int) ([]map[string]interface{}, error) {
+	var configs []map[string]interface{}
+	for i, part := range parts {
+		if mediaType, _, _ := mime.ParseMediaType(part.ContentType); mediaType != contentTypeCloudConfig {
+			continue
+		}
+		config := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(part.Content), &config); err != nil {
+			return nil, fmt.Errorf("parsing cloud-config of part %d, %w", offset+i+1, err)
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// conflictingScalar returns the first key whose scalar is set to different values by both cloud-configs, dicts are
+// compared key by key and lists are appended by cloud-init
+func conflictingScalar(custom, bootstrap map[string]interface{}, prefix string) (string, bool) {
+	keys := lo.Keys(bootstrap)
+	sort.Strings(keys)
+	for _, key := range keys {
+		customValue, ok := custom[key]
+		if !ok {
+			continue
+		}
+		path := lo.Ternary(prefix == "", key, prefix+"."+key)
+		switch value := bootstrap[key].(type) {
+		case map[string]interface{}:
+			if customDict, ok := customValue.(map[string]interface{}); ok {
+				if conflict, ok := conflictingScalar(customDict, value, path); ok {
+					return conflict, true
+				}
+			}
+		case []interface{}:
+		default:
+			if !reflect.DeepEqual(customValue, value) {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// writeMIMEMultipart renders the parts with the fixed boundary, the parts are written as is in their order
+func writeMIMEMultipart(parts []mimePart) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "MIME-Version: 1.0\nContent-Type: %s\n\n", mime.FormatMediaType(contentTypeMultipart, map[string]string{"boundary": mimeBoundary}))
+	for i, part := range parts {
+		if strings.HasPrefix(part.Content, "--"+mimeBoundary) || strings.Contains(part.Content, "\n--"+mimeBoundary) {
+			return "", fmt.Errorf("part %d of the user data contains the MIME boundary %s", i+1, mimeBoundary)
+		}
+		fmt.Fprintf(&sb, "--%s\nContent-Type: %s\n", mimeBoundary, part.ContentType)
+		if mediaType, _, _ := mime.ParseMediaType(part.ContentType); mediaType == contentTypeCloudConfig {
+			fmt.Fprintf(&sb, "Merge-Type: %s\n", lo.CoalesceOrEmpty(part.MergeType, cloudConfigMergeType))
+		}
+		// The line break before a boundary belongs to the boundary, the content is kept as is
+		fmt.Fprintf(&sb, "\n%s\n", part.Content)
+	}
+	fmt.Fprintf(&sb, "--%s--\n", mimeBoundary)
+	return sb.String(), nil
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samber/lo"
+)
+
+const (
+	shellUserData       = "#!/bin/bash\necho 'preparing node'\n"
+	cloudConfigUserData = "#cloud-config\npackages:\n- jq\n"
+	multipartUserData   = `MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="//"
+
+--//
+Content-Type: text/x-shellscript; charset="us-ascii"
+
+#!/bin/bash
+echo 'preparing node'
+
+--//
+Content-Type: text/cloud-config
+Content-Transfer-Encoding: base64
+
+I2Nsb3VkLWNvbmZpZwpw
+YWNrYWdlczoKLSBqcQo=
+--//--
+`
+)
+
+func TestMergeUserData(t *testing.T) {
+	userData := []struct {
+		name     string
+		userData *string
+		parts    []mimePart
+	}{
+		{name: "none"},
+		{name: "blank", userData: lo.ToPtr(" \n")},
+		{
+			name:     "shell script",
+			userData: lo.ToPtr(shellUserData),
+			parts:    []mimePart{{ContentType: contentTypeShellScript, Content: shellUserData}},
+		},
+		{
+			name:     "commands without interpreter",
+			userData: lo.ToPtr("mkdir -p /data\n"),
+			parts:    []mimePart{{ContentType: contentTypeShellScript, Content: "#!/bin/bash\nmkdir -p /data\n"}},
+		},
+		{
+			name:     "cloud-config",
+			userData: lo.ToPtr(cloudConfigUserData),
+			parts:    []mimePart{{ContentType: contentTypeCloudConfig, Content: cloudConfigUserData}},
+		},
+		{
+			name:     "boothook",
+			userData: lo.ToPtr("#cloud-boothook\necho early\n"),
+			parts:    []mimePart{{ContentType: contentTypeBoothook, Content: "#cloud-boothook\necho early\n"}},
+		},
+		{
+			name:     "single part MIME",
+			userData: lo.ToPtr("MIME-Version: 1.0\nContent-Type: text/x-shellscript\n\n" + shellUserData),
+			parts:    []mimePart{{ContentType: contentTypeShellScript, Content: shellUserData}},
+		},
+		{
+			name:     "multipart MIME",
+			userData: lo.ToPtr(multipartUserData),
+			parts: []mimePart{
+				{ContentType: `text/x-shellscript; charset=us-ascii`, Content: shellUserData},
+				{ContentType: contentTypeCloudConfig, Content: cloudConfigUserData},
+			},
+		},
+		{
+			name:     "multipart MIME with merge type",
+			userData: lo.ToPtr("Content-Type: multipart/mixed; boundary=b\n\n--b\nContent-Type: text/cloud-config\nMerge-Type: dict(replace)+list()+str()\n\npackages: [jq]\n--b--\n"),
+			parts:    []mimePart{{ContentType: contentTypeCloudConfig, MergeType: "dict(replace)+list()+str()", Content: "packages: [jq]"}},
+		},
+		{
+			name:     "multipart MIME without part content type",
+			userData: lo.ToPtr("Content-Type: multipart/mixed; boundary=b\n\n--b\n\necho plain\n--b--\n"),
+			parts:    []mimePart{{ContentType: "text/plain", Content: "echo plain"}},
+		},
+	}
+	bootstraps := []struct {
+		name  string
+		parts []mimePart
+	}{
+		{name: "shell script", parts: []mimePart{shellScriptPart("#!/bin/bash\necho bootstrap\n")}},
+		{name: "shell script with host name", parts: append(hostNameParts("node-default-abcde"), shellScriptPart("#!/bin/bash\necho bootstrap\n"))},
+		{name: "cloud-config", parts: []mimePart{cloudConfigPart("#cloud-config\nruncmd:\n- /bootstrap.sh\npreserve_hostname: true\n")}},
+	}
+	for _, ud := range userData {
+		for _, bootstrap := range bootstraps {
+			t.Run(ud.name+" with "+bootstrap.name+" bootstrap", func(t *testing.T) {
+				merged, err := mergeUserData(ud.userData, bootstrap.parts...)
+				if err != nil {
+					t.Fatalf("merging user data, %v", err)
+				}
+				again, err := mergeUserData(ud.userData, bootstrap.parts...)
+				if err != nil {
+					t.Fatalf("merging user data, %v", err)
+				}
+				if merged != again {
+					t.Errorf("merged user data isn't deterministic")
+				}
+				archive, err := base64.StdEncoding.DecodeString(merged)
+				if err != nil {
+					t.Fatalf("decoding user data, %v", err)
+				}
+				parts, err := parseUserData(string(archive))
+				if err != nil {
+					t.Fatalf("parsing merged user data, %v", err)
+				}
+				// cloud-config parts are merged with the default merge type unless they set one
+				want := lo.Map(append(append([]mimePart{}, ud.parts...), bootstrap.parts...), func(p mimePart, _ int) mimePart {
+					if p.ContentType == contentTypeCloudConfig {
+						p.MergeType = lo.CoalesceOrEmpty(p.MergeType, cloudConfigMergeType)
+					}
+					return p
+				})
+				if !reflect.DeepEqual(parts, want) {
+					t.Errorf("merged parts = %+v, want %+v", parts, want)
+				}
+			})
+		}
+	}
+}
+
+func TestMergeUserDataIgnoresBoundary(t *testing.T) {
+	bootstrap := shellScriptPart("#!/bin/bash\necho bootstrap\n")
+	merged := lo.Must(mergeUserData(lo.ToPtr(multipartUserData), bootstrap))
+	rebounded := lo.Must(mergeUserData(lo.ToPtr(strings.ReplaceAll(multipartUserData, "//", "==other==")), bootstrap))
+	if merged != rebounded {
+		t.Errorf("merged user data depends on the boundary of the custom user data")
+	}
+}
+
+func TestMergeUserDataRejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		userData  string
+		bootstrap []mimePart
+		err       string
+	}{
+		{
+			name:      "preserve_hostname with host name bootstrap",
+			userData:  "#cloud-config\npreserve_hostname: false\n",
+			bootstrap: hostNameParts("node-default-abcde"),
+			err:       `cloud-config key "preserve_hostname" of the user data conflicts with the bootstrap user data`,
+		},
+		{
+			name:      "nested scalar of a cloud-config bootstrap",
+			userData:  "Content-Type: multipart/mixed; boundary=b\n\n--b\nContent-Type: text/cloud-config\n\nntp:\n  enabled: false\n--b--\n",
+			bootstrap: []mimePart{cloudConfigPart("#cloud-config\nntp:\n  enabled: true\n")},
+			err:       `cloud-config key "ntp.enabled" of the user data conflicts with the bootstrap user data`,
+		},
+		{
+			name:     "invalid cloud-config",
+			userData: "#cloud-config\npackages: [jq\n",
+			err:      "parsing cloud-config of part 1",
+		},
+		{
+			name:     "nested multipart",
+			userData: "Content-Type: multipart/mixed; boundary=b\n\n--b\nContent-Type: multipart/mixed; boundary=c\n\n--c--\n--b--\n",
+			err:      "nested multipart content in MIME part 1 is not supported",
+		},
+		{
+			name:     "unsupported multipart",
+			userData: "Content-Type: multipart/alternative; boundary=b\n\n--b--\n",
+			err:      "unsupported content type multipart/alternative",
+		},
+		{
+			name:     "unsupported transfer encoding",
+			userData: "Content-Type: multipart/mixed; boundary=b\n\n--b\nContent-Type: text/x-shellscript\nContent-Transfer-Encoding: x-uuencode\n\nbegin\n--b--\n",
+			err:      "unsupported content transfer encoding x-uuencode",
+		},
+		{
+			name:     "boundary in content",
+			userData: "#!/bin/bash\necho '\n--" + mimeBoundary + "'\n",
+			err:      "part 1 of the user data contains the MIME boundary",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bootstrap := append(tt.bootstrap, shellScriptPart("#!/bin/bash\necho bootstrap\n"))
+			if _, err := mergeUserData(lo.ToPtr(tt.userData), bootstrap...); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("merging user data, got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMergeUserDataMergesCloudConfigs(t *testing.T) {
+	// cloud-init appends the runcmd of the bootstrap part to the custom one rather than replacing it, scalars set to
+	// the same value don't conflict
+	merged, err := mergeUserData(lo.ToPtr("#cloud-config\nruncmd:\n- echo custom\npreserve_hostname: true\n"),
+		cloudConfigPart("#cloud-config\nruncmd:\n- /bootstrap.sh\npreserve_hostname: true\n"))
+	if err != nil {
+		t.Fatalf("merging user data, %v", err)
+	}
+	archive := string(lo.Must(base64.StdEncoding.DecodeString(merged)))
+	if n := strings.Count(archive, "Merge-Type: "+cloudConfigMergeType+"\n"); n != 2 {
+		t.Errorf("found merge type on %d cloud-config parts, want 2:\n%s", n, archive)
+	}
+}
@@ -34,22 +34,40 @@ import (
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
 )
 
+// DefaultSystemDisk keeps the size ECS defaults to when the launch request leaves it out
 var DefaultSystemDisk = v1alpha1.SystemDisk{
 	Category: tea.String("cloud_auto"),
-	Size:     tea.Int32(20),
+	Size:     tea.Int32(40),
+}
+
+// DefaultMetadataOptions mirrors the CRD default, it is used for ECSNodeClasses stored before the default existed
+var DefaultMetadataOptions = v1alpha1.MetadataOptions{
+	HTTPEndpoint: tea.String("enabled"),
+	HTTPTokens:   tea.String("required"),
 }
 
 // Options define the static launch template parameters
 type Options struct {
 	ClusterName     string
 	ClusterEndpoint string
+	// CABundle is the base64 encoded CA bundle of the API server
+	CABundle *string
 	// Level-triggered fields that may change out of sync.
 	SecurityGroups []v1alpha1.SecurityGroup
 	Tags           map[string]string
 	Labels         map[string]string `hash:"ignore"`
 	NodeClassName  string
+	// HostName is the rendered host name template
+	HostName string
+
+	Region string
+	// KubernetesVersion is the ACK release of the cluster, e.g. 1.30.1-aliyun.1
+	KubernetesVersion string
+	// BootstrapToken authenticates the kubelet while joining the cluster
+	BootstrapToken string `hash:"ignore"`
 }
 
 // LaunchTemplate holds the dynamically generated launch template parameters
@@ -60,7 +78,9 @@ type LaunchTemplate struct {
 	InstanceTypes []*cloudprovider.InstanceType `hash:"ignore"`
 	SystemDisk    *v1alpha1.SystemDisk
 	CapacityType  string
-	// TODO: need more field, HttpTokens, RamRole, NetworkInterface, DataDisk, ...
+	// MetadataOptions is never nil, DefaultMetadataOptions fills in the unset fields
+	MetadataOptions *v1alpha1.MetadataOptions
+	// TODO: need more field, RamRole, NetworkInterface, DataDisk, ...
 }
 
 type InstanceTypeAvailableSystemDisk struct {
@@ -89,12 +109,12 @@ type Resolver interface {
 type DefaultResolver struct {
 	sync.Mutex
 	region string
-	ecsapi *ecs.Client
+	ecsapi client.ECSAPI
 	cache  *cache.Cache
 }
 
 // NewDefaultResolver constructs a new launch template DefaultResolver
-func NewDefaultResolver(region string, ecsapi *ecs.Client, cache *cache.Cache) *DefaultResolver {
+func NewDefaultResolver(region string, ecsapi client.ECSAPI, cache *cache.Cache) *DefaultResolver {
 	return &DefaultResolver{
 		region: region,
 		ecsapi: ecsapi,
@@ -125,7 +145,10 @@ func (r *DefaultResolver) Resolve(ctx context.Context, nodeClass *v1alpha1.ECSNo
 	var resolvedTemplates []*LaunchTemplate
 	for imageID, instanceTypes := range mappedImages {
 		// TODO: instanceTypes group by MaxPod
-		resolved := r.resolveLaunchTemplate(nodeClass, nodeClaim, instanceTypes, capacityType, imageFamily, imageID, options)
+		resolved, err := r.resolveLaunchTemplate(nodeClass, nodeClaim, instanceTypes, capacityType, imageFamily, imageID, options)
+		if err != nil {
+			return nil, err
+		}
 		resolvedTemplates = append(resolvedTemplates, resolved)
 	}
 	return resolvedTemplates, nil
@@ -139,6 +162,16 @@ func GetImageFamily(family string, options *Options) ImageFamily {
 		return &AlibabaCloudLinux3{Options: options}
 	case v1alpha1.ImageFamilyAlibabaCloudLinux2:
 		return &AlibabaCloudLinux2{Options: options}
+	case v1alpha1.ImageFamilyContainerOS:
+		return &ContainerOS{Options: options}
+	case v1alpha1.ImageFamilyUbuntu2204:
+		return &Ubuntu{Options: options, Version: "22.04"}
+	case v1alpha1.ImageFamilyUbuntu2404:
+		return &Ubuntu{Options: options, Version: "24.04"}
+	case v1alpha1.ImageFamilyWindows2019:
+		return &Windows{Options: options, Build: WindowsBuild2019}
+	case v1alpha1.ImageFamilyWindows2022:
+		return &Windows{Options: options, Build: WindowsBuild2022}
 	default:
 		return nil
 	}
@@ -162,7 +195,7 @@ func MapToInstanceTypes(instanceTypes []*cloudprovider.InstanceType, images []v1
 }
 
 func (r *DefaultResolver) resolveLaunchTemplate(nodeClass *v1alpha1.ECSNodeClass, nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string,
-	imageFamily ImageFamily, imageID string, options *Options) *LaunchTemplate {
+	imageFamily ImageFamily, imageID string, options *Options) (*LaunchTemplate, error) {
 	kubeletConfig := &v1alpha1.KubeletConfiguration{}
 	if nodeClass.Spec.KubeletConfiguration != nil {
 		kubeletConfig = nodeClass.Spec.KubeletConfiguration.DeepCopy()
@@ -178,24 +211,47 @@ func (r *DefaultResolver) resolveLaunchTemplate(nodeClass *v1alpha1.ECSNodeClass
 		taints = append(taints, karpv1.UnregisteredNoExecuteTaint)
 	}
 
+	// ACK installs and configures the instances attached to a node pool, only the custom user data is passed on. The
+	// node pool registers the nodes with the unregistered taint, which the NodeClass readiness verifies, and Karpenter
+	// syncs the labels and taints of the NodeClaim to the nodes once they register.
+	if nodeClass.BootstrapMode() == v1alpha1.BootstrapModeACKNodePool {
+		imageFamily = &Custom{Options: options}
+	}
+	userData, err := imageFamily.UserData(
+		kubeletConfig,
+		taints,
+		options.Labels,
+		instanceTypes,
+		nodeClass.Spec.UserData,
+	)
+	if err != nil {
+		// The user data only fails to render with settings of the NodeClass or the operator that launches can't fix
+		return nil, cloudprovider.NewNodeClassNotReadyError(fmt.Errorf("generating user data, %w", err))
+	}
 	resolved := &LaunchTemplate{
-		Options: options,
-		UserData: imageFamily.UserData(
-			kubeletConfig,
-			taints,
-			options.Labels,
-			instanceTypes,
-			nodeClass.Spec.UserData,
-		),
+		Options:       options,
+		UserData:      userData,
 		SystemDisk:    nodeClass.Spec.SystemDisk,
 		ImageID:       imageID,
 		InstanceTypes: instanceTypes,
 		CapacityType:  capacityType,
+		MetadataOptions: &v1alpha1.MetadataOptions{
+			HTTPEndpoint: DefaultMetadataOptions.HTTPEndpoint,
+			HTTPTokens:   DefaultMetadataOptions.HTTPTokens,
+		},
 	}
 	if resolved.SystemDisk == nil {
 		resolved.SystemDisk = imageFamily.DefaultSystemDisk()
 	}
-	return resolved
+	if metadataOptions := nodeClass.Spec.MetadataOptions; metadataOptions != nil {
+		if metadataOptions.HTTPEndpoint != nil {
+			resolved.MetadataOptions.HTTPEndpoint = metadataOptions.HTTPEndpoint
+		}
+		if metadataOptions.HTTPTokens != nil {
+			resolved.MetadataOptions.HTTPTokens = metadataOptions.HTTPTokens
+		}
+	}
+	return resolved, nil
 }
 
 // todo: check system disk stock, currently only checking compatibility
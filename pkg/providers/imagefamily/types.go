@@ -76,6 +76,8 @@ type DescribeImageQueryBase struct {
 	// When discovering image IDs via OOS we know additional requirements which aren't surfaced by ecs:DescribeImage (e.g. GPU / Neuron compatibility)
 	// Sometimes, an image may have multiple sets of known requirements.
 	KnownRequirements []scheduling.Requirements
+	// KubernetesVersion is set when the FilterFunc selects images by the cluster version, it keeps the cached images apart
+	KubernetesVersion string
 }
 
 func (q *DescribeImageQuery) RequirementsForImageWithArchitecture(arch string) []scheduling.Requirements {
@@ -91,7 +93,7 @@ func (q *DescribeImageQuery) RequirementsForImageWithArchitecture(arch string) [
 // ImageFamily can be implemented to override the default logic for generating dynamic launch template parameters
 // TODO: add OOSProvider
 type ImageFamily interface {
-	DescribeImageQuery(ctx context.Context) (DescribeImageQuery, error)
-	UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, instanceTypes []*cloudprovider.InstanceType, customUserData *string) string
+	DescribeImageQuery(ctx context.Context, k8sVersion string) (DescribeImageQuery, error)
+	UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, instanceTypes []*cloudprovider.InstanceType, customUserData *string) (string, error)
 	DefaultSystemDisk() *v1alpha1.SystemDisk
 }
@@ -0,0 +1,233 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"strings"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+const (
+	kubeadmJoinConfigFile = "/var/lib/karpenter/kubeadm-join.yaml"
+	bootstrapScriptFile   = "/var/lib/karpenter/bootstrap.sh"
+)
+
+// Ubuntu joins self-managed clusters with kubeadm, the kubelet, kubeadm and containerd are installed at boot
+// unless the image already has them.
+type Ubuntu struct {
+	*Options
+	// Version is the Ubuntu release of the images, e.g. 22.04
+	Version string
+}
+
+func (u Ubuntu) UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, instanceTypes []*cloudprovider.InstanceType, customUserData *string) (string, error) {
+	return ubuntuBootstrap{
+		Options:        u.Options,
+		KubeletConfig:  kubeletConfig,
+		Taints:         taints,
+		Labels:         labels,
+		CustomUserData: customUserData,
+	}.Script()
+}
+
+func (u Ubuntu) DescribeImageQuery(_ context.Context, _ string) (DescribeImageQuery, error) {
+	// The format should look like ubuntu_22_04_x64_20G_alibase_20240926.vhd
+	imageIDRegex, err := regexp.Compile(fmt.Sprintf(`^ubuntu_%s_(x64|arm64)_\d+G_alibase_\d+\.vhd$`, strings.ReplaceAll(u.Version, ".", "_")))
+	if err != nil {
+		return DescribeImageQuery{}, fmt.Errorf("compiling image id pattern of ubuntu %s, %w", u.Version, err)
+	}
+	return DescribeImageQuery{
+		BaseQuery: DescribeImageQueryBase{
+			DescribeImagesRequest: &ecs.DescribeImagesRequest{
+				ImageOwnerAlias: tea.String("system"),
+				OSType:          tea.String("linux"),
+				ActionType:      tea.String("CreateEcs"),
+			},
+		},
+		FilterFunc: imageIDRegex.MatchString,
+	}, nil
+}
+
+func (u Ubuntu) DefaultSystemDisk() *v1alpha1.SystemDisk {
+	return &DefaultSystemDisk
+}
+
+// ubuntuBootstrap renders the cloud-init config installing the node components and running kubeadm join,
+// the parts of the custom user data of the ECSNodeClass run first.
+type ubuntuBootstrap struct {
+	*Options
+	KubeletConfig  *v1alpha1.KubeletConfiguration
+	Taints         []corev1.Taint
+	Labels         map[string]string
+	CustomUserData *string
+}
+
+type cloudConfig struct {
+	WriteFiles []cloudConfigFile `json:"write_files"`
+	RunCmd     []string          `json:"runcmd"`
+	// PreserveHostname keeps cloud-init from resetting the host name the bootstrap script sets
+	PreserveHostname bool `json:"preserve_hostname,omitempty"`
+}
+
+type cloudConfigFile struct {
+	Path        string `json:"path"`
+	Permissions string `json:"permissions"`
+	Content     string `json:"content"`
+}
+
+// Script returns the base64 encoded user data merging the custom user data with the bootstrap cloud-config
+func (b ubuntuBootstrap) Script() (string, error) {
+	if err := validateKubeletConfigDropIn(b.KubeletConfig, b.KubernetesVersion); err != nil {
+		return "", err
+	}
+	config, err := b.render()
+	if err != nil {
+		return "", err
+	}
+	return mergeUserData(b.CustomUserData, cloudConfigPart(config))
+}
+
+func (b ubuntuBootstrap) render() (string, error) {
+	joinConfiguration, err := b.joinConfiguration()
+	if err != nil {
+		return "", err
+	}
+	config := cloudConfig{
+		WriteFiles: []cloudConfigFile{
+			{Path: "/etc/modules-load.d/k8s.conf", Permissions: "0644", Content: "overlay\nbr_netfilter\n"},
+			{Path: "/etc/sysctl.d/k8s.conf", Permissions: "0644", Content: "net.bridge.bridge-nf-call-iptables = 1\nnet.bridge.bridge-nf-call-ip6tables = 1\nnet.ipv4.ip_forward = 1\n"},
+			{Path: kubeadmJoinConfigFile, Permissions: "0600", Content: joinConfiguration},
+			{Path: bootstrapScriptFile, Permissions: "0755", Content: b.bootstrapScript()},
+		},
+		RunCmd:           []string{bootstrapScriptFile},
+		PreserveHostname: b.HostName != "",
+	}
+	if dropIn := kubeletConfigDropIn(b.KubeletConfig); dropIn != "" {
+		config.WriteFiles = append(config.WriteFiles, cloudConfigFile{Path: kubeletConfigDropInFile, Permissions: "0644", Content: dropIn})
+	}
+	return "#cloud-config\n" + string(lo.Must(yaml.Marshal(config))), nil
+}
+
+// joinConfiguration returns the kubeadm configuration joining the node with the bootstrap token, the bootstrap script
+// replaces the provider ID variable before joining
+// Ref: https://kubernetes.io/docs/reference/config-api/kubeadm-config.v1beta3/#kubeadm-k8s-io-v1beta3-JoinConfiguration
+func (b ubuntuBootstrap) joinConfiguration() (string, error) {
+	hashes, err := caCertHashes(b.CABundle)
+	if err != nil {
+		return "", fmt.Errorf("pinning cluster CA, %w", err)
+	}
+	kubeletExtraArgs := lo.SliceToMap(kubeletArgs(b.KubeletConfig), func(arg string) (string, string) {
+		k, v, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		return k, v
+	})
+	if b.KubeletConfig != nil && len(b.KubeletConfig.ClusterDNS) != 0 {
+		kubeletExtraArgs["cluster-dns"] = strings.Join(b.KubeletConfig.ClusterDNS, ",")
+	}
+	if len(b.Labels) != 0 {
+		kubeletExtraArgs["node-labels"] = joinMap(b.Labels, "=")
+	}
+	kubeletExtraArgs["provider-id"] = providerIDVariable
+	bootstrapToken := map[string]interface{}{
+		"apiServerEndpoint": strings.TrimPrefix(b.ClusterEndpoint, "https://"),
+		"token":             b.BootstrapToken,
+		"caCertHashes":      hashes,
+	}
+	return string(lo.Must(yaml.Marshal(map[string]interface{}{
+		"apiVersion": "kubeadm.k8s.io/v1beta3",
+		"kind":       "JoinConfiguration",
+		"discovery":  map[string]interface{}{"bootstrapToken": bootstrapToken},
+		"nodeRegistration": map[string]interface{}{
+			"criSocket":        "unix:///run/containerd/containerd.sock",
+			"kubeletExtraArgs": kubeletExtraArgs,
+			// An empty list keeps kubeadm from tainting the node on its own
+			"taints": lo.Ternary(b.Taints == nil, []corev1.Taint{}, b.Taints),
+		},
+	}))), nil
+}
+
+func (b ubuntuBootstrap) bootstrapScript() string {
+	// Package versions of pkgs.k8s.io look like 1.30.4-1.1, the repository is per minor version
+	version, _, _ := strings.Cut(strings.TrimPrefix(b.KubernetesVersion, "v"), "-")
+	minor := strings.Join(lo.Slice(strings.Split(version, "."), 0, 2), ".")
+
+	var sb strings.Builder
+	sb.WriteString("#!/bin/bash\nset -o errexit -o pipefail\n")
+	sb.WriteString(metadataScript())
+	sb.WriteString(hostNameScript(b.HostName))
+	sb.WriteString(providerIDScript())
+	fmt.Fprintf(&sb, "sed -i \"s/%s/${PROVIDER_ID}/\" %s\n", strings.ReplaceAll(providerIDVariable, "$", `\$`), kubeadmJoinConfigFile)
+	fmt.Fprintf(&sb, `modprobe overlay
+modprobe br_netfilter
+sysctl --system
+swapoff -a
+if ! command -v kubeadm >/dev/null || ! command -v containerd >/dev/null; then
+  export DEBIAN_FRONTEND=noninteractive
+  apt-get update
+  apt-get install -y apt-transport-https ca-certificates curl gpg containerd
+  mkdir -p /etc/apt/keyrings
+  curl -fsSL https://pkgs.k8s.io/core:/stable:/v%[1]s/deb/Release.key | gpg --dearmor -o /etc/apt/keyrings/kubernetes-apt-keyring.gpg
+  echo 'deb [signed-by=/etc/apt/keyrings/kubernetes-apt-keyring.gpg] https://pkgs.k8s.io/core:/stable:/v%[1]s/deb/ /' > /etc/apt/sources.list.d/kubernetes.list
+  apt-get update
+  apt-get install -y kubelet='%[2]s-*' kubeadm='%[2]s-*'
+  apt-mark hold kubelet kubeadm
+fi
+mkdir -p /etc/containerd
+containerd config default | sed 's/SystemdCgroup = false/SystemdCgroup = true/' > /etc/containerd/config.toml
+systemctl restart containerd
+systemctl enable kubelet
+kubeadm join --config %[3]s
+`, minor, version, kubeadmJoinConfigFile)
+	return sb.String()
+}
+
+// caCertHashes returns the sha256 hashes of the public keys in the CA bundle, which kubeadm pins the discovered CA to.
+// kubeadm refuses to join without them, so a CA bundle without certificates is an error.
+func caCertHashes(caBundle *string) ([]string, error) {
+	data, err := base64.StdEncoding.DecodeString(lo.FromPtr(caBundle))
+	if err != nil {
+		return nil, fmt.Errorf("decoding CA bundle, %w", err)
+	}
+	var hashes []string
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing CA certificate, %w", err)
+		}
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		hashes = append(hashes, "sha256:"+hex.EncodeToString(sum[:]))
+	}
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("no certificates in CA bundle")
+	}
+	return hashes, nil
+}
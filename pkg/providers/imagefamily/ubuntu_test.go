@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+func TestUbuntuBootstrap(t *testing.T) {
+	ca, err := os.ReadFile(filepath.Join("testdata", "ca.crt"))
+	if err != nil {
+		t.Fatalf("reading CA, %v", err)
+	}
+	options := &Options{
+		ClusterEndpoint:   "https://192.168.0.10:6443",
+		CABundle:          lo.ToPtr(base64.StdEncoding.EncodeToString(ca)),
+		BootstrapToken:    "abcdef.0123456789abcdef",
+		KubernetesVersion: "1.30.4",
+		HostName:          "karpenter-default-abcde",
+	}
+	userData, err := Ubuntu{Options: options, Version: "22.04"}.UserData(
+		&v1alpha1.KubeletConfiguration{
+			ClusterDNS:     []string{"10.96.0.10"},
+			MaxPods:        lo.ToPtr[int32](110),
+			SystemReserved: map[string]string{"cpu": "100m"},
+		},
+		[]corev1.Taint{
+			karpv1.UnregisteredNoExecuteTaint,
+			{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+		},
+		map[string]string{karpv1.NodePoolLabelKey: "default", karpv1.CapacityTypeLabelKey: karpv1.CapacityTypeOnDemand},
+		nil,
+		lo.ToPtr("#!/bin/bash\necho 'preparing node'\n"),
+	)
+	if err != nil {
+		t.Fatalf("generating user data, %v", err)
+	}
+	expectGolden(t, "ubuntu", userData)
+}
+
+func TestUbuntuBootstrapWithoutCA(t *testing.T) {
+	// kubeadm can't pin the cluster CA, the user data isn't rendered rather than failing to join
+	for _, caBundle := range []*string{nil, lo.ToPtr("not base64"), lo.ToPtr(base64.StdEncoding.EncodeToString([]byte("no certificates")))} {
+		options := &Options{ClusterEndpoint: "https://192.168.0.10:6443", CABundle: caBundle, KubernetesVersion: "1.30.4"}
+		if _, err := (Ubuntu{Options: options, Version: "22.04"}).UserData(&v1alpha1.KubeletConfiguration{}, nil, nil, nil, nil); err == nil {
+			t.Errorf("generating user data with CA bundle %q, want error", lo.FromPtr(caBundle))
+		}
+	}
+}
+
+func TestUbuntuImageFilter(t *testing.T) {
+	query, err := Ubuntu{Version: "22.04"}.DescribeImageQuery(context.Background(), "1.30")
+	if err != nil {
+		t.Fatalf("describing image query, %v", err)
+	}
+	for imageID, want := range map[string]bool{
+		"ubuntu_22_04_x64_20G_alibase_20240926.vhd":      true,
+		"ubuntu_22_04_arm64_20G_alibase_20240926.vhd":    true,
+		"ubuntu_24_04_x64_20G_alibase_20240926.vhd":      false,
+		"ubuntu_22_04_uefi_x64_20G_alibase_20240926.vhd": false,
+		"aliyun_3_x64_20G_alibase_20240819.vhd":          false,
+	} {
+		if got := query.FilterFunc(imageID); got != want {
+			t.Errorf("FilterFunc(%s) = %t, want %t", imageID, got, want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+const (
+	WindowsBuild2019 = "10.0.17763"
+	WindowsBuild2022 = "10.0.20348"
+
+	// containerdWindowsVersion is installed on images without containerd
+	containerdWindowsVersion = "1.7.22"
+	windowsKubeDir           = `C:\k`
+	windowsCAFile            = `C:\etc\kubernetes\pki\ca.crt`
+)
+
+var (
+	// The format should look like win2022_21H2_x64_dtc_en-us_40G_alibase_20240815.vhd
+	windowsImageIDRegexes = map[string]*regexp.Regexp{
+		WindowsBuild2019: regexp.MustCompile(`^win2019_1809_x64_dtc_en-us_\d+G_alibase_\d+\.vhd$`),
+		WindowsBuild2022: regexp.MustCompile(`^win2022_21H2_x64_dtc_en-us_\d+G_alibase_\d+\.vhd$`),
+	}
+
+	windowsDefaultSystemDisk = v1alpha1.SystemDisk{
+		Category: tea.String("cloud_auto"),
+		Size:     tea.Int32(60),
+	}
+
+	// WindowsDefaultSystemReserved is reserved for the OS unless the KubeletConfiguration sets system reserved,
+	// Windows services use considerably more memory than a minimal Linux distribution
+	WindowsDefaultSystemReserved = map[string]string{
+		string(corev1.ResourceMemory): "1536Mi",
+	}
+	// WindowsDefaultEvictionHard matches the eviction default of the kubelet on Windows
+	WindowsDefaultEvictionHard = map[string]string{
+		"memory.available": "500Mi",
+		"nodefs.available": "10%",
+	}
+)
+
+// Windows joins Windows Server nodes through the TLS bootstrap of the kubelet, which runs as a Windows service.
+// Node names are the computer names, since renaming the host requires a reboot.
+type Windows struct {
+	*Options
+	// Build is the OS build of the images, e.g. 10.0.20348 for Windows Server 2022
+	Build string
+}
+
+func (w Windows) UserData(kubeletConfig *v1alpha1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, instanceTypes []*cloudprovider.InstanceType, customUserData *string) (string, error) {
+	return windowsBootstrap{
+		Options:        w.Options,
+		KubeletConfig:  kubeletConfig,
+		Taints:         taints,
+		Labels:         labels,
+		CustomUserData: customUserData,
+	}.Script(), nil
+}
+
+func (w Windows) DescribeImageQuery(_ context.Context, _ string) (DescribeImageQuery, error) {
+	imageIDRegex, ok := windowsImageIDRegexes[w.Build]
+	if !ok {
+		return DescribeImageQuery{}, fmt.Errorf("unsupported windows build %s", w.Build)
+	}
+	return DescribeImageQuery{
+		BaseQuery: DescribeImageQueryBase{
+			DescribeImagesRequest: &ecs.DescribeImagesRequest{
+				ImageOwnerAlias: tea.String("system"),
+				OSType:          tea.String("windows"),
+				ActionType:      tea.String("CreateEcs"),
+			},
+		},
+		FilterFunc: imageIDRegex.MatchString,
+	}, nil
+}
+
+func (w Windows) DefaultSystemDisk() *v1alpha1.SystemDisk {
+	return &windowsDefaultSystemDisk
+}
+
+// windowsBootstrap renders the PowerShell user data installing containerd and the kubelet when the image lacks
+// them, the custom user data of the ECSNodeClass runs first. Windows doesn't run cloud-init, so the custom user data
+// is embedded into the script instead of merged as a MIME part.
+type windowsBootstrap struct {
+	*Options
+	KubeletConfig  *v1alpha1.KubeletConfiguration
+	Taints         []corev1.Taint
+	Labels         map[string]string
+	CustomUserData *string
+}
+
+// Script returns the base64 encoded user data
+func (b windowsBootstrap) Script() string {
+	return base64.StdEncoding.EncodeToString([]byte(b.render()))
+}
+
+func (b windowsBootstrap) render() string {
+	version, _, _ := strings.Cut(strings.TrimPrefix(b.KubernetesVersion, "v"), "-")
+
+	var sb strings.Builder
+	// ECS runs user data starting with [powershell] with PowerShell
+	sb.WriteString("[powershell]\n$ErrorActionPreference = 'Stop'\n")
+	if custom := customPowerShell(b.CustomUserData); custom != "" {
+		sb.WriteString(custom)
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "New-Item -ItemType Directory -Force -Path '%s', 'C:\\etc\\kubernetes\\pki', 'C:\\var\\lib\\kubelet' | Out-Null\n", windowsKubeDir)
+	if caBundle := lo.FromPtr(b.CABundle); caBundle != "" {
+		fmt.Fprintf(&sb, "[IO.File]::WriteAllBytes('%s', [Convert]::FromBase64String('%s'))\n", windowsCAFile, caBundle)
+	}
+	kubeconfig := strings.ReplaceAll(bootstrapKubeconfig(b.ClusterEndpoint, b.BootstrapToken), clusterCAFile, windowsCAFile)
+	fmt.Fprintf(&sb, "Set-Content -Path 'C:\\etc\\kubernetes\\bootstrap-kubelet.conf' -Value @'\n%s'@\n", kubeconfig)
+	fmt.Fprintf(&sb, `if (-not (Get-Service containerd -ErrorAction SilentlyContinue)) {
+  Invoke-WebRequest -UseBasicParsing -Uri 'https://github.com/containerd/containerd/releases/download/v%[1]s/containerd-%[1]s-windows-amd64.tar.gz' -OutFile "$env:TEMP\containerd.tar.gz"
+  New-Item -ItemType Directory -Force -Path "$env:ProgramFiles\containerd" | Out-Null
+  tar.exe -xf "$env:TEMP\containerd.tar.gz" --strip-components 1 -C "$env:ProgramFiles\containerd"
+  & "$env:ProgramFiles\containerd\containerd.exe" config default | Out-File "$env:ProgramFiles\containerd\config.toml" -Encoding ascii
+  & "$env:ProgramFiles\containerd\containerd.exe" --register-service
+  Start-Service containerd
+}
+if (-not (Test-Path '%[2]s\kubelet.exe')) {
+  Invoke-WebRequest -UseBasicParsing -Uri 'https://dl.k8s.io/v%[3]s/bin/windows/amd64/kubelet.exe' -OutFile '%[2]s\kubelet.exe'
+}
+`, containerdWindowsVersion, windowsKubeDir, version)
+	sb.WriteString("$KubeletArgs = @(\n")
+	for _, arg := range b.kubeletArgs() {
+		fmt.Fprintf(&sb, "  '%s'\n", strings.ReplaceAll(arg, "'", "''"))
+	}
+	sb.WriteString(")\n")
+	// The provider ID has the <region>.<instance id> format of the Linux nodes, see providerIDVariable
+	sb.WriteString(`$MetadataToken = Invoke-RestMethod -Method Put -Uri 'http://100.100.100.200/latest/api/token' -Headers @{'X-aliyun-ecs-metadata-token-ttl-seconds' = '300'}
+function Get-Metadata($Path) { Invoke-RestMethod -Uri "http://100.100.100.200/latest/meta-data/$Path" -Headers @{'X-aliyun-ecs-metadata-token' = $MetadataToken} }
+$KubeletArgs += "--provider-id=$(Get-Metadata region-id).$(Get-Metadata instance-id)"
+`)
+	fmt.Fprintf(&sb, "New-Service -Name kubelet -StartupType Automatic -BinaryPathName \"%s\\kubelet.exe --windows-service $($KubeletArgs -join ' ')\"\n", windowsKubeDir)
+	sb.WriteString("Start-Service kubelet\n")
+	return sb.String()
+}
+
+// kubeletArgs returns the kubelet flags, the Windows defaults apply to reserved resources and eviction unless the
+// KubeletConfiguration sets them, so the allocatable of the node matches the overhead Karpenter computes
+func (b windowsBootstrap) kubeletArgs() []string {
+	kubeletConfig := lo.FromPtr(b.KubeletConfig.DeepCopy())
+	if len(kubeletConfig.SystemReserved) == 0 {
+		kubeletConfig.SystemReserved = WindowsDefaultSystemReserved
+	}
+	if len(kubeletConfig.EvictionHard) == 0 {
+		kubeletConfig.EvictionHard = WindowsDefaultEvictionHard
+	}
+	// The resource managers, graceful node shutdown and sysctls are only supported on Linux
+	kubeletConfig.CPUManagerPolicy, kubeletConfig.TopologyManagerPolicy, kubeletConfig.TopologyManagerScope = nil, nil, nil
+	kubeletConfig.MemoryManagerPolicy, kubeletConfig.ReservedMemory = nil, nil
+	kubeletConfig.ShutdownGracePeriod, kubeletConfig.ShutdownGracePeriodCriticalPods = nil, nil
+	kubeletConfig.AllowedUnsafeSysctls = nil
+	args := []string{
+		`--bootstrap-kubeconfig=C:\etc\kubernetes\bootstrap-kubelet.conf`,
+		`--kubeconfig=C:\etc\kubernetes\kubelet.conf`,
+		`--cert-dir=C:\var\lib\kubelet\pki`,
+		`--root-dir=C:\var\lib\kubelet`,
+		"--container-runtime-endpoint=npipe:////./pipe/containerd-containerd",
+		// Windows has no cgroups, node allocatable is only accounted for in scheduling
+		"--cgroups-per-qos=false",
+		`--enforce-node-allocatable=""`,
+		`--resolv-conf=""`,
+	}
+	args = append(args, kubeletArgs(&kubeletConfig)...)
+	if len(kubeletConfig.ClusterDNS) != 0 {
+		args = append(args, fmt.Sprintf("--cluster-dns=%s", strings.Join(kubeletConfig.ClusterDNS, ",")))
+	}
+	if len(b.Labels) != 0 {
+		args = append(args, fmt.Sprintf("--node-labels=%s", joinMap(b.Labels, "=")))
+	}
+	if len(b.Taints) != 0 {
+		args = append(args, fmt.Sprintf("--register-with-taints=%s", joinTaints(b.Taints)))
+	}
+	return args
+}
+
+// customPowerShell returns the custom user data without its [powershell] header, it's embedded in the bootstrap script
+func customPowerShell(customUserData *string) string {
+	custom := strings.TrimSpace(lo.FromPtr(customUserData))
+	custom = strings.TrimSpace(strings.TrimPrefix(custom, "[powershell]"))
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(custom, "<powershell>"), "</powershell>"))
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+func TestWindowsBootstrap(t *testing.T) {
+	options := &Options{
+		ClusterEndpoint:   "https://192.168.0.10:6443",
+		CABundle:          lo.ToPtr("Y2EtYnVuZGxl"),
+		BootstrapToken:    "abcdef.0123456789abcdef",
+		KubernetesVersion: "1.30.4-aliyun.1",
+	}
+	userData, err := Windows{Options: options, Build: WindowsBuild2022}.UserData(
+		&v1alpha1.KubeletConfiguration{
+			ClusterDNS: []string{"10.96.0.10"},
+			MaxPods:    lo.ToPtr[int32](110),
+		},
+		[]corev1.Taint{
+			karpv1.UnregisteredNoExecuteTaint,
+			{Key: "os", Value: "windows", Effect: corev1.TaintEffectNoSchedule},
+		},
+		map[string]string{karpv1.NodePoolLabelKey: "windows", karpv1.CapacityTypeLabelKey: karpv1.CapacityTypeOnDemand},
+		nil,
+		lo.ToPtr("[powershell]\nWrite-Output 'preparing node'\n"),
+	)
+	if err != nil {
+		t.Fatalf("generating user data, %v", err)
+	}
+	expectGolden(t, "windows", userData)
+}
+
+func TestWindowsImageFilter(t *testing.T) {
+	query, err := Windows{Build: WindowsBuild2022}.DescribeImageQuery(context.Background(), "1.30")
+	if err != nil {
+		t.Fatalf("describing image query, %v", err)
+	}
+	for imageID, want := range map[string]bool{
+		"win2022_21H2_x64_dtc_en-us_40G_alibase_20240815.vhd": true,
+		"win2022_21H2_x64_dtc_zh-cn_40G_alibase_20240815.vhd": false,
+		"win2019_1809_x64_dtc_en-us_40G_alibase_20240815.vhd": false,
+		"ubuntu_22_04_x64_20G_alibase_20240926.vhd":           false,
+	} {
+		if got := query.FilterFunc(imageID); got != want {
+			t.Errorf("FilterFunc(%s) = %t, want %t", imageID, got, want)
+		}
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -36,10 +37,18 @@ import (
 	"sigs.k8s.io/karpenter/pkg/utils/resources"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	alicache "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/cache"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/bootstraptoken"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/capacityreservation"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/eip"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/imagefamily"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/securitygroup"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/version"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/vswitch"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/alierrors"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
 )
 
 const (
@@ -57,26 +66,48 @@ type Provider interface {
 }
 
 type DefaultProvider struct {
-	ecsClient       *ecsclient.Client
+	ecsClient       client.ECSAPI
 	region          string
 	clusterEndpoint string
+	caBundle        *string
 
-	imageFamily imagefamily.Resolver
+	imageFamily     imagefamily.Resolver
+	versionProvider version.Provider
 
-	vSwitchProvider vswitch.Provider
+	bootstrapTokenProvider bootstraptoken.Provider
+
+	vSwitchProvider             vswitch.Provider
+	securityGroupProvider       securitygroup.Provider
+	eipProvider                 eip.Provider
+	capacityReservationProvider capacityreservation.Provider
+	unavailableOfferings        *alicache.UnavailableOfferings
 }
 
-func NewDefaultProvider(ctx context.Context, region, clusterEndpoint string, ecsClient *ecsclient.Client,
+func NewDefaultProvider(ctx context.Context, region, clusterEndpoint string, caBundle *string, ecsClient client.ECSAPI,
 	imageFamily imagefamily.Resolver,
-	vSwitchProvider vswitch.Provider) *DefaultProvider {
+	versionProvider version.Provider,
+	bootstrapTokenProvider bootstraptoken.Provider,
+	vSwitchProvider vswitch.Provider,
+	securityGroupProvider securitygroup.Provider,
+	eipProvider eip.Provider,
+	capacityReservationProvider capacityreservation.Provider,
+	unavailableOfferings *alicache.UnavailableOfferings) *DefaultProvider {
 	return &DefaultProvider{
 		ecsClient:       ecsClient,
 		region:          region,
 		clusterEndpoint: clusterEndpoint,
+		caBundle:        caBundle,
+
+		imageFamily:     imageFamily,
+		versionProvider: versionProvider,
 
-		imageFamily: imageFamily,
+		bootstrapTokenProvider: bootstrapTokenProvider,
 
-		vSwitchProvider: vSwitchProvider,
+		vSwitchProvider:             vSwitchProvider,
+		securityGroupProvider:       securityGroupProvider,
+		eipProvider:                 eipProvider,
+		capacityReservationProvider: capacityReservationProvider,
+		unavailableOfferings:        unavailableOfferings,
 	}
 }
 
@@ -93,12 +124,45 @@ func (p *DefaultProvider) Create(ctx context.Context, nodeClass *v1alpha1.ECSNod
 		return nil, fmt.Errorf("truncating instance types, %w", err)
 	}
 	tags := getTags(ctx, nodeClass, nodeClaim)
-	launchInstance, err := p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, tags)
+	instanceID, err := p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, tags)
 	if err != nil {
 		return nil, err
 	}
 
-	return p.Get(ctx, *launchInstance.InstanceIds.InstanceId[0])
+	return p.Get(ctx, instanceID)
+}
+
+func nameTemplateData(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, nodeClaim *karpv1.NodeClaim, capacityType string) utils.NameTemplateData {
+	return utils.NameTemplateData{
+		ClusterName:  options.FromContext(ctx).ClusterName,
+		NodePool:     nodeClaim.Labels[karpv1.NodePoolLabelKey],
+		NodeClaim:    nodeClaim.Name,
+		NodeClass:    nodeClass.Name,
+		CapacityType: capacityType,
+	}
+}
+
+// renderNames renders the instance name and host name templates of the NodeClass, names that break the ECS naming
+// rules fail the launch until the NodeClass is fixed
+func renderNames(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, nodeClaim *karpv1.NodeClaim, capacityType string) (instanceName, hostName string, err error) {
+	data := nameTemplateData(ctx, nodeClass, nodeClaim, capacityType)
+	if nodeClass.Spec.InstanceNameTemplate != nil {
+		if instanceName, err = utils.RenderNameTemplate(*nodeClass.Spec.InstanceNameTemplate, data); err != nil {
+			return "", "", err
+		}
+		if err := utils.ValidateInstanceName(instanceName); err != nil {
+			return "", "", cloudprovider.NewNodeClassNotReadyError(fmt.Errorf("validating instance name template, %w", err))
+		}
+	}
+	if nodeClass.Spec.HostNameTemplate != nil {
+		if hostName, err = utils.RenderNameTemplate(*nodeClass.Spec.HostNameTemplate, data); err != nil {
+			return "", "", err
+		}
+		if err := utils.ValidateHostName(hostName); err != nil {
+			return "", "", cloudprovider.NewNodeClassNotReadyError(fmt.Errorf("validating host name template, %w", err))
+		}
+	}
+	return instanceName, hostName, nil
 }
 
 func (p *DefaultProvider) Get(ctx context.Context, id string) (*Instance, error) {
@@ -124,6 +188,8 @@ func (p *DefaultProvider) Get(ctx context.Context, id string) (*Instance, error)
 	return NewInstance(resp.Body.Instances.Instance[0]), nil
 }
 
+// List returns the instances launched by Karpenter across all resource groups, instances that moved to another
+// group than the one of their ECSNodeClass are still found
 func (p *DefaultProvider) List(ctx context.Context) ([]*Instance, error) {
 	var instances []*Instance
 
@@ -140,7 +206,7 @@ func (p *DefaultProvider) List(ctx context.Context) ([]*Instance, error) {
 				Value: tea.String(options.FromContext(ctx).ClusterName),
 			},
 		},
-		RegionId: p.ecsClient.RegionId,
+		RegionId: tea.String(p.region),
 	}
 
 	runtime := &util.RuntimeOptions{}
@@ -172,6 +238,12 @@ func (p *DefaultProvider) List(ctx context.Context) ([]*Instance, error) {
 }
 
 func (p *DefaultProvider) Delete(ctx context.Context, id string) error {
+	// Return bound EIPs to their pool before the instance goes away, EIPs which can't be returned now are unbound with
+	// the instance and released by the garbage collection on one of its next runs
+	if err := p.eipProvider.Disassociate(ctx, id); err != nil {
+		log.FromContext(ctx).WithValues("instance", id).Error(err, "failed disassociating eips, leaving them to the garbage collection")
+	}
+
 	deleteInstanceRequest := &ecsclient.DeleteInstanceRequest{
 		InstanceId: tea.String(id),
 	}
@@ -323,35 +395,329 @@ func getTags(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, nodeClaim *k
 }
 
 func (p *DefaultProvider) launchInstance(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType,
-	tags map[string]string) (*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult, error) {
+	tags map[string]string) (string, error) {
 	if err := p.checkODFallback(nodeClaim, instanceTypes); err != nil {
 		log.FromContext(ctx).Error(err, "failed while checking on-demand fallback")
 	}
 	capacityType := p.getCapacityType(nodeClaim, instanceTypes)
+	if capacityType == v1alpha1.CapacityTypeReserved {
+		instanceID, err := p.launchReservedInstance(ctx, nodeClass, nodeClaim, instanceTypes, tags)
+		if err == nil {
+			return instanceID, nil
+		}
+		// Only launches without reserved capacity left fall back, other errors would fail on-demand launches just as well
+		if !cloudprovider.IsInsufficientCapacityError(err) ||
+			!scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(karpv1.CapacityTypeLabelKey).Has(karpv1.CapacityTypeOnDemand) {
+			return "", fmt.Errorf("launching reserved instance, %w", err)
+		}
+		log.FromContext(ctx).Error(err, "failed launching reserved instance, falling back to on-demand")
+		capacityType = karpv1.CapacityTypeOnDemand
+	}
 	zonalVSwitchs, err := p.vSwitchProvider.ZonalVSwitchesForLaunch(ctx, nodeClass, instanceTypes, capacityType)
 	if err != nil {
-		return nil, fmt.Errorf("getting vSwitches, %w", err)
+		return "", fmt.Errorf("getting vSwitches, %w", err)
 	}
 
-	createAutoProvisioningGroupRequest, err := p.getProvisioningGroup(ctx, nodeClass, nodeClaim, instanceTypes, zonalVSwitchs, capacityType, tags)
+	createAutoProvisioningGroupRequest, launchTemplate, err := p.getProvisioningGroup(ctx, nodeClass, nodeClaim, instanceTypes, zonalVSwitchs, capacityType, tags)
 	if err != nil {
-		return nil, fmt.Errorf("getting provisioning group, %w", err)
+		return "", fmt.Errorf("getting provisioning group, %w", err)
 	}
 
-	runtime := &util.RuntimeOptions{}
-	resp, err := p.ecsClient.CreateAutoProvisioningGroupWithOptions(createAutoProvisioningGroupRequest, runtime)
+	securityGroupIDs := lo.Map(createAutoProvisioningGroupRequest.LaunchConfiguration.SecurityGroupIds, func(id *string, _ int) string { return lo.FromPtr(id) })
+	if err := p.securityGroupProvider.ReserveCapacityForLaunch(ctx, securityGroupIDs); err != nil {
+		return "", fmt.Errorf("reserving security group capacity, %w", err)
+	}
+
+	instanceID, err := p.createAutoProvisioningGroupFromLaunchTemplate(ctx, nodeClaim, createAutoProvisioningGroupRequest, launchTemplate, capacityType, tags)
+	p.securityGroupProvider.UpdateInflightCapacity(securityGroupIDs, err == nil)
+	if err != nil {
+		return "", err
+	}
+	return instanceID, nil
+}
+
+// createAutoProvisioningGroupFromLaunchTemplate launches the instance with a provisioning group whose launch
+// configuration comes from a launch template. The launch configuration of CreateAutoProvisioningGroup can't set the
+// metadata options, a launch template can, so one is created for every launch and deleted once the group is created.
+func (p *DefaultProvider) createAutoProvisioningGroupFromLaunchTemplate(ctx context.Context, nodeClaim *karpv1.NodeClaim,
+	request *ecsclient.CreateAutoProvisioningGroupRequest, launchTemplate *LaunchTemplate, capacityType string, tags map[string]string) (string, error) {
+	launchTemplateID, version, err := p.createLaunchTemplate(ctx, nodeClaim, request.LaunchConfiguration, launchTemplate, tags)
+	if err != nil {
+		return "", err
+	}
+	defer p.deleteLaunchTemplate(ctx, launchTemplateID)
+
+	// The launch template takes precedence over the launch configuration, which is dropped so that both can't diverge
+	request.LaunchTemplateId = tea.String(launchTemplateID)
+	request.LaunchTemplateVersion = tea.String(version)
+	request.LaunchConfiguration = nil
+	return p.createAutoProvisioningGroup(ctx, request, capacityType)
+}
+
+// createLaunchTemplate creates the launch template of a single launch with the launch configuration of the provisioning
+// group. The name is unique to the launch so that a template that failed to be deleted doesn't block the next launch.
+func (p *DefaultProvider) createLaunchTemplate(ctx context.Context, nodeClaim *karpv1.NodeClaim, launchConfiguration *ecsclient.CreateAutoProvisioningGroupRequestLaunchConfiguration,
+	launchTemplate *LaunchTemplate, tags map[string]string) (string, string, error) {
+	request := &ecsclient.CreateLaunchTemplateRequest{
+		RegionId:                tea.String(p.region),
+		LaunchTemplateName:      tea.String(fmt.Sprintf("karpenter-%s-%s", nodeClaim.Name, rand.String(5))),
+		ImageId:                 launchConfiguration.ImageId,
+		SecurityGroupIds:        launchConfiguration.SecurityGroupIds,
+		UserData:                launchConfiguration.UserData,
+		InstanceName:            launchConfiguration.InstanceName,
+		HostName:                launchConfiguration.HostName,
+		SystemDisk:              launchTemplateSystemDisk(launchTemplate.SystemDisk),
+		ResourceGroupId:         launchConfiguration.ResourceGroupId,
+		TemplateResourceGroupId: launchConfiguration.ResourceGroupId,
+		KeyPairName:             launchConfiguration.KeyPairName,
+		PasswordInherit:         launchConfiguration.PasswordInherit,
+		CreditSpecification:     launchConfiguration.CreditSpecification,
+		InternetMaxBandwidthOut: launchConfiguration.InternetMaxBandwidthOut,
+		InternetChargeType:      launchConfiguration.InternetChargeType,
+		Tag: lo.MapToSlice(tags, func(k, v string) *ecsclient.CreateLaunchTemplateRequestTag {
+			return &ecsclient.CreateLaunchTemplateRequestTag{Key: tea.String(k), Value: tea.String(v)}
+		}),
+		TemplateTag: []*ecsclient.CreateLaunchTemplateRequestTemplateTag{
+			{Key: tea.String(v1alpha1.TagManagedLaunchTemplate), Value: tea.String(options.FromContext(ctx).ClusterName)},
+		},
+	}
+	if metadataOptions := launchTemplate.MetadataOptions; metadataOptions != nil {
+		request.HttpEndpoint = metadataOptions.HTTPEndpoint
+		request.HttpTokens = metadataOptions.HTTPTokens
+	}
+	resp, err := p.ecsClient.CreateLaunchTemplateWithOptions(request, &util.RuntimeOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("creating launch template, %w", err)
+	}
+	if resp.Body == nil || resp.Body.LaunchTemplateId == nil || resp.Body.LaunchTemplateVersionNumber == nil {
+		return "", "", fmt.Errorf("unexpected null value was returned")
+	}
+	return *resp.Body.LaunchTemplateId, fmt.Sprint(*resp.Body.LaunchTemplateVersionNumber), nil
+}
+
+// deleteLaunchTemplate deletes the launch template of a launch, the instances it launched don't depend on it
+func (p *DefaultProvider) deleteLaunchTemplate(ctx context.Context, launchTemplateID string) {
+	if _, err := p.ecsClient.DeleteLaunchTemplateWithOptions(&ecsclient.DeleteLaunchTemplateRequest{
+		RegionId:         tea.String(p.region),
+		LaunchTemplateId: tea.String(launchTemplateID),
+	}, &util.RuntimeOptions{}); err != nil {
+		log.FromContext(ctx).WithValues("launch-template-id", launchTemplateID).Error(err, "failed deleting launch template")
+	}
+}
+
+// launchTemplateSystemDisk returns the launch template system disk with the settings of the system disk of the NodeClass
+func launchTemplateSystemDisk(systemDisk *v1alpha1.SystemDisk) *ecsclient.CreateLaunchTemplateRequestSystemDisk {
+	if systemDisk == nil {
+		return nil
+	}
+	return &ecsclient.CreateLaunchTemplateRequestSystemDisk{
+		Category:             systemDisk.Category,
+		Size:                 systemDisk.Size,
+		PerformanceLevel:     systemDisk.PerformanceLevel,
+		DiskName:             systemDisk.DiskName,
+		AutoSnapshotPolicyId: systemDisk.AutoSnapshotPolicyID,
+	}
+}
+
+// createAutoProvisioningGroup launches the instance with an instant provisioning group. Instance types and zones
+// that fail to launch don't fail the request but come back as launch results with an error code, the offerings out of
+// stock or quota are marked unavailable and the launch fails with insufficient capacity if none is left.
+func (p *DefaultProvider) createAutoProvisioningGroup(ctx context.Context, request *ecsclient.CreateAutoProvisioningGroupRequest, capacityType string) (string, error) {
+	resp, err := p.ecsClient.CreateAutoProvisioningGroupWithOptions(request, &util.RuntimeOptions{})
+	if err != nil {
+		return "", fmt.Errorf("creating auto provisioning group, %w", err)
+	}
+	if resp.Body == nil || resp.Body.LaunchResults == nil {
+		return "", fmt.Errorf("unexpected null value was returned")
+	}
+	var errs error
+	insufficientCapacity := true
+	for _, result := range resp.Body.LaunchResults.LaunchResult {
+		if result.InstanceIds != nil && len(result.InstanceIds.InstanceId) > 0 {
+			return lo.FromPtr(result.InstanceIds.InstanceId[0]), nil
+		}
+		if lo.FromPtr(result.ErrorCode) == "" {
+			continue
+		}
+		instanceType, zone := lo.FromPtr(result.InstanceType), lo.FromPtr(result.ZoneId)
+		err := alierrors.New(lo.FromPtr(result.ErrorCode), lo.FromPtr(result.ErrorMsg))
+		if alierrors.IsInsufficientCapacity(err) || alierrors.IsQuotaExceeded(err) {
+			p.markUnavailable(ctx, alierrors.Code(err), instanceType, zone, capacityType)
+		} else {
+			insufficientCapacity = false
+		}
+		errs = multierr.Append(errs, fmt.Errorf("launching %s in %s, %w", instanceType, zone, err))
+	}
+	if errs == nil {
+		return "", fmt.Errorf("creating auto provisioning group, no instance was launched")
+	}
+	if insufficientCapacity {
+		return "", cloudprovider.NewInsufficientCapacityError(fmt.Errorf("creating auto provisioning group, %w", errs))
+	}
+	return "", fmt.Errorf("creating auto provisioning group, %w", errs)
+}
+
+// markUnavailable keeps offerings that failed to launch out of the next launches for a while
+func (p *DefaultProvider) markUnavailable(ctx context.Context, reason, instanceType, zone, capacityType string) {
+	if instanceType == "" || zone == "" {
+		return
+	}
+	p.unavailableOfferings.MarkUnavailable(ctx, reason, instanceType, zone, capacityType)
+}
+
+// runInstancesRequest returns the RunInstances request launching the instance type with the launch configuration of
+// the provisioning group
+func (p *DefaultProvider) runInstancesRequest(createAutoProvisioningGroupRequest *ecsclient.CreateAutoProvisioningGroupRequest,
+	launchTemplate *LaunchTemplate, instanceType, vSwitchID string, tags map[string]string) *ecsclient.RunInstancesRequest {
+	launchConfiguration := createAutoProvisioningGroupRequest.LaunchConfiguration
+	request := &ecsclient.RunInstancesRequest{
+		RegionId:                tea.String(p.region),
+		InstanceType:            tea.String(instanceType),
+		VSwitchId:               tea.String(vSwitchID),
+		InstanceChargeType:      tea.String("PostPaid"),
+		Amount:                  tea.Int32(1),
+		ImageId:                 launchConfiguration.ImageId,
+		SecurityGroupIds:        launchConfiguration.SecurityGroupIds,
+		SystemDisk:              runInstancesSystemDisk(launchTemplate.SystemDisk),
+		ResourceGroupId:         launchConfiguration.ResourceGroupId,
+		KeyPairName:             launchConfiguration.KeyPairName,
+		PasswordInherit:         launchConfiguration.PasswordInherit,
+		CreditSpecification:     launchConfiguration.CreditSpecification,
+		InternetMaxBandwidthOut: launchConfiguration.InternetMaxBandwidthOut,
+		InternetChargeType:      launchConfiguration.InternetChargeType,
+		UserData:                launchConfiguration.UserData,
+		InstanceName:            launchConfiguration.InstanceName,
+		HostName:                launchConfiguration.HostName,
+		Tag: lo.MapToSlice(tags, func(k, v string) *ecsclient.RunInstancesRequestTag {
+			return &ecsclient.RunInstancesRequestTag{Key: tea.String(k), Value: tea.String(v)}
+		}),
+	}
+	if metadataOptions := launchTemplate.MetadataOptions; metadataOptions != nil {
+		request.HttpEndpoint = metadataOptions.HTTPEndpoint
+		request.HttpTokens = metadataOptions.HTTPTokens
+	}
+	return request
+}
+
+// runInstancesSystemDisk returns the RunInstances system disk with the settings of the system disk of the NodeClass
+func runInstancesSystemDisk(systemDisk *v1alpha1.SystemDisk) *ecsclient.RunInstancesRequestSystemDisk {
+	if systemDisk == nil {
+		return nil
+	}
+	runInstancesSystemDisk := &ecsclient.RunInstancesRequestSystemDisk{
+		Category:             systemDisk.Category,
+		PerformanceLevel:     systemDisk.PerformanceLevel,
+		DiskName:             systemDisk.DiskName,
+		AutoSnapshotPolicyId: systemDisk.AutoSnapshotPolicyID,
+	}
+	if systemDisk.Size != nil {
+		runInstancesSystemDisk.Size = tea.String(fmt.Sprint(*systemDisk.Size))
+	}
+	return runInstancesSystemDisk
+}
+
+func (p *DefaultProvider) runInstance(request *ecsclient.RunInstancesRequest) (string, error) {
+	resp, err := p.ecsClient.RunInstancesWithOptions(request, &util.RuntimeOptions{})
+	if err != nil {
+		return "", fmt.Errorf("running instance, %w", err)
+	}
+	if resp.Body == nil || resp.Body.InstanceIdSets == nil || len(resp.Body.InstanceIdSets.InstanceIdSet) == 0 {
+		return "", fmt.Errorf("unexpected null value was returned")
+	}
+	return lo.FromPtr(resp.Body.InstanceIdSets.InstanceIdSet[0]), nil
+}
+
+// launchReservedInstance launches an instance into the cheapest capacity reservation or elasticity assurance that is
+// compatible with the NodeClaim. CreateAutoProvisioningGroup can't target private pools, so RunInstances is used with
+// the launch configuration CreateAutoProvisioningGroup would have used.
+func (p *DefaultProvider) launchReservedInstance(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType,
+	tags map[string]string) (string, error) {
+	instanceType, reservation, ok := p.getReservation(nodeClass, nodeClaim, instanceTypes)
+	if !ok {
+		return "", cloudprovider.NewInsufficientCapacityError(fmt.Errorf("no capacity reservation is available"))
+	}
+	zonalVSwitchs, err := p.vSwitchProvider.ZonalVSwitchesForLaunch(ctx, nodeClass, []*cloudprovider.InstanceType{instanceType}, v1alpha1.CapacityTypeReserved)
+	if err != nil {
+		return "", fmt.Errorf("getting vSwitches, %w", err)
+	}
+	createAutoProvisioningGroupRequest, launchTemplate, err := p.getProvisioningGroup(ctx, nodeClass, nodeClaim, []*cloudprovider.InstanceType{instanceType}, zonalVSwitchs, v1alpha1.CapacityTypeReserved, tags)
 	if err != nil {
-		return nil, fmt.Errorf("creating auto provisioning group, %w", err)
+		return "", fmt.Errorf("getting provisioning group, %w", err)
 	}
+	// The reservation tag tells reserved instances apart, DescribeInstances doesn't return the private pool
+	runInstancesRequest := p.runInstancesRequest(createAutoProvisioningGroupRequest, launchTemplate, instanceType.Name,
+		lo.FromPtr(createAutoProvisioningGroupRequest.LaunchTemplateConfig[0].VSwitchId), lo.Assign(tags, map[string]string{v1alpha1.LabelCapacityReservationID: reservation.ID}))
+	runInstancesRequest.ZoneId = tea.String(reservation.ZoneID)
+	runInstancesRequest.PrivatePoolOptions = &ecsclient.RunInstancesRequestPrivatePoolOptions{
+		MatchCriteria: tea.String("Target"),
+		Id:            tea.String(reservation.ID),
+	}
+
+	securityGroupIDs := lo.Map(runInstancesRequest.SecurityGroupIds, func(id *string, _ int) string { return lo.FromPtr(id) })
+	if err := p.securityGroupProvider.ReserveCapacityForLaunch(ctx, securityGroupIDs); err != nil {
+		return "", fmt.Errorf("reserving security group capacity, %w", err)
+	}
+	instanceID, err := p.runInstance(runInstancesRequest)
+	p.securityGroupProvider.UpdateInflightCapacity(securityGroupIDs, err == nil)
+	if err != nil {
+		if alierrors.IsInsufficientCapacity(err) || alierrors.IsQuotaExceeded(err) {
+			// All reserved instances are in use, don't offer the reservation until it's described again
+			p.capacityReservationProvider.MarkExhausted(reservation)
+			return "", cloudprovider.NewInsufficientCapacityError(fmt.Errorf("running instance in private pool %s, %w", reservation.ID, err))
+		}
+		return "", fmt.Errorf("running instance in private pool %s, %w", reservation.ID, err)
+	}
+	p.capacityReservationProvider.MarkLaunched(reservation)
+	return instanceID, nil
+}
 
-	return resp.Body.LaunchResults.LaunchResult[0], nil
+// getReservation returns the instance type and the reservation of the cheapest available reserved offering that is
+// compatible with the NodeClaim
+func (p *DefaultProvider) getReservation(nodeClass *v1alpha1.ECSNodeClass, nodeClaim *karpv1.NodeClaim,
+	instanceTypes []*cloudprovider.InstanceType) (*cloudprovider.InstanceType, v1alpha1.CapacityReservation, bool) {
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	requirements[karpv1.CapacityTypeLabelKey] = scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, v1alpha1.CapacityTypeReserved)
+
+	var cheapestInstanceType *cloudprovider.InstanceType
+	var cheapestOffering cloudprovider.Offering
+	for _, instanceType := range instanceTypes {
+		for _, offering := range instanceType.Offerings.Available() {
+			if requirements.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) != nil {
+				continue
+			}
+			if cheapestInstanceType == nil || offering.Price < cheapestOffering.Price {
+				cheapestInstanceType, cheapestOffering = instanceType, offering
+			}
+		}
+	}
+	if cheapestInstanceType == nil {
+		return nil, v1alpha1.CapacityReservation{}, false
+	}
+	reservation, ok := lo.Find(nodeClass.Status.CapacityReservations, func(r v1alpha1.CapacityReservation) bool {
+		return r.ID == cheapestOffering.Requirements.Get(v1alpha1.LabelCapacityReservationID).Any() &&
+			r.InstanceType == cheapestInstanceType.Name &&
+			r.ZoneID == cheapestOffering.Requirements.Get(corev1.LabelTopologyZone).Any()
+	})
+	return cheapestInstanceType, reservation, ok
 }
 
-// getCapacityType selects spot if both constraints are flexible and there is an
-// available offering. The Alibaba Cloud Provider defaults to [ on-demand ], so spot
+// getCapacityType selects reserved if it's allowed and there is an available offering, otherwise
+// it selects spot if both constraints are flexible and there is an available offering.
+// The Alibaba Cloud Provider defaults to [ on-demand ], so reserved and spot
 // must be explicitly included in capacity type requirements.
 func (p *DefaultProvider) getCapacityType(nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) string {
 	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	if requirements.Get(karpv1.CapacityTypeLabelKey).Has(v1alpha1.CapacityTypeReserved) {
+		reservedRequirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+		reservedRequirements[karpv1.CapacityTypeLabelKey] = scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, v1alpha1.CapacityTypeReserved)
+		for _, instanceType := range instanceTypes {
+			for _, offering := range instanceType.Offerings.Available() {
+				if reservedRequirements.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) == nil {
+					return v1alpha1.CapacityTypeReserved
+				}
+			}
+		}
+	}
 	if requirements.Get(karpv1.CapacityTypeLabelKey).Has(karpv1.CapacityTypeSpot) {
 		requirements[karpv1.CapacityTypeLabelKey] = scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, karpv1.CapacityTypeSpot)
 		for _, instanceType := range instanceTypes {
@@ -366,34 +732,43 @@ func (p *DefaultProvider) getCapacityType(nodeClaim *karpv1.NodeClaim, instanceT
 }
 
 func (p *DefaultProvider) getProvisioningGroup(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, nodeClaim *karpv1.NodeClaim,
-	instanceTypes []*cloudprovider.InstanceType, zonalVSwitchs map[string]*vswitch.VSwitch, capacityType string, tags map[string]string) (*ecsclient.CreateAutoProvisioningGroupRequest, error) {
+	instanceTypes []*cloudprovider.InstanceType, zonalVSwitchs map[string]*vswitch.VSwitch, capacityType string, tags map[string]string) (*ecsclient.CreateAutoProvisioningGroupRequest, *LaunchTemplate, error) {
 
 	launchTemplates, err := p.EnsureAll(ctx, nodeClass, nodeClaim, instanceTypes, capacityType, tags)
 	if err != nil {
-		return nil, fmt.Errorf("getting launch templates, %w", err)
+		return nil, nil, fmt.Errorf("getting launch templates, %w", err)
 	}
 
 	if len(launchTemplates) == 0 {
-		return nil, fmt.Errorf("no launch templates are currently available given the constraints")
+		return nil, nil, fmt.Errorf("no launch templates are currently available given the constraints")
 	}
 
 	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
 	requirements[karpv1.CapacityTypeLabelKey] = scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, capacityType)
 
 	launchtemplate := launchTemplates[0]
+	// The credit specification of the launch configuration applies to every instance type of the provisioning group,
+	// so burstable instance types are launched apart from the others. Only the instance types of the same kind as the
+	// cheapest one are launched, the others are left to the next launch once these turn out to be unavailable.
+	launchInstanceTypes := launchtemplate.InstanceTypes
+	if nodeClass.Spec.CreditSpecification != nil {
+		launchInstanceTypes = lo.Filter(launchInstanceTypes, func(i *cloudprovider.InstanceType, _ int) bool {
+			return isBurstable(i) == isBurstable(launchtemplate.InstanceTypes[0])
+		})
+	}
 	var launchTemplateConfigs []*ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig
-	for i := range launchtemplate.InstanceTypes {
+	for i := range launchInstanceTypes {
 		if i > maxInstanceTypes-1 {
 			break
 		}
 
-		vSwitchID := p.getVSwitchID(launchtemplate.InstanceTypes[i], zonalVSwitchs, requirements)
+		vSwitchID := p.getVSwitchID(launchInstanceTypes[i], zonalVSwitchs, requirements)
 		if vSwitchID == "" {
-			return nil, errors.New("vSwitchID not found")
+			return nil, nil, errors.New("vSwitchID not found")
 		}
 
 		launchTemplateConfig := &ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig{
-			InstanceType:     &launchtemplate.InstanceTypes[i].Name,
+			InstanceType:     &launchInstanceTypes[i].Name,
 			VSwitchId:        &vSwitchID,
 			WeightedCapacity: tea.Float64(1),
 		}
@@ -412,6 +787,7 @@ func (p *DefaultProvider) getProvisioningGroup(ctx context.Context, nodeClass *v
 		LaunchConfiguration: &ecsclient.CreateAutoProvisioningGroupRequestLaunchConfiguration{
 			ImageId:          tea.String(launchtemplate.ImageID),
 			SecurityGroupIds: launchtemplate.SecurityGroupIds,
+			UserData:         tea.String(launchtemplate.UserData),
 
 			// TODO: AutoProvisioningGroup is not compatible with SecurityGroupIds, waiting for Aliyun developers to fix it,
 			// so here we only take the first one.
@@ -421,6 +797,26 @@ func (p *DefaultProvider) getProvisioningGroup(ctx context.Context, nodeClass *v
 			{DiskCategory: launchtemplate.SystemDisk.Category},
 		},
 	}
+	// The system disk config only takes the category, the other settings of the disk go into the launch configuration
+	createAutoProvisioningGroupRequest.LaunchConfiguration.SystemDiskSize = launchtemplate.SystemDisk.Size
+	createAutoProvisioningGroupRequest.LaunchConfiguration.SystemDiskPerformanceLevel = launchtemplate.SystemDisk.PerformanceLevel
+	createAutoProvisioningGroupRequest.LaunchConfiguration.SystemDiskName = launchtemplate.SystemDisk.DiskName
+
+	// The resource group of the launch configuration applies to the instance and the disks created along with it
+	createAutoProvisioningGroupRequest.ResourceGroupId = nodeClass.Spec.ResourceGroupID
+	createAutoProvisioningGroupRequest.LaunchConfiguration.ResourceGroupId = nodeClass.Spec.ResourceGroupID
+	createAutoProvisioningGroupRequest.LaunchConfiguration.InstanceName = lo.EmptyableToPtr(launchtemplate.InstanceName)
+	createAutoProvisioningGroupRequest.LaunchConfiguration.HostName = lo.EmptyableToPtr(launchtemplate.HostName)
+	createAutoProvisioningGroupRequest.LaunchConfiguration.KeyPairName = nodeClass.Spec.KeyPairName
+	createAutoProvisioningGroupRequest.LaunchConfiguration.PasswordInherit = nodeClass.Spec.PasswordInherit
+	if isBurstable(launchInstanceTypes[0]) {
+		createAutoProvisioningGroupRequest.LaunchConfiguration.CreditSpecification = nodeClass.Spec.CreditSpecification
+	}
+
+	if publicIP := nodeClass.Spec.PublicIP; publicIP != nil {
+		createAutoProvisioningGroupRequest.LaunchConfiguration.InternetMaxBandwidthOut = publicIP.InternetMaxBandwidthOut
+		createAutoProvisioningGroupRequest.LaunchConfiguration.InternetChargeType = publicIP.InternetChargeType
+	}
 
 	if capacityType == karpv1.CapacityTypeSpot {
 		createAutoProvisioningGroupRequest.SpotTargetCapacity = tea.String("1")
@@ -430,7 +826,12 @@ func (p *DefaultProvider) getProvisioningGroup(ctx context.Context, nodeClass *v
 		createAutoProvisioningGroupRequest.PayAsYouGoTargetCapacity = tea.String("1")
 	}
 
-	return createAutoProvisioningGroupRequest, nil
+	return createAutoProvisioningGroupRequest, launchtemplate, nil
+}
+
+// isBurstable returns whether the instance type earns and spends CPU credits
+func isBurstable(instanceType *cloudprovider.InstanceType) bool {
+	return instanceType.Requirements.Get(v1alpha1.LabelInstanceBurstable).Has("true")
 }
 
 func (p *DefaultProvider) checkODFallback(nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) error {
@@ -466,6 +867,10 @@ type LaunchTemplate struct {
 	ImageID          string
 	SecurityGroupIds []*string
 	SystemDisk       *v1alpha1.SystemDisk
+	MetadataOptions  *v1alpha1.MetadataOptions
+	UserData         string
+	InstanceName     string
+	HostName         string
 }
 
 func (p *DefaultProvider) EnsureAll(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string, tags map[string]string) ([]*LaunchTemplate, error) {
@@ -473,6 +878,11 @@ func (p *DefaultProvider) EnsureAll(ctx context.Context, nodeClass *v1alpha1.ECS
 	if err != nil {
 		return nil, err
 	}
+	instanceName, hostName, err := renderNames(ctx, nodeClass, nodeClaim, capacityType)
+	if err != nil {
+		return nil, err
+	}
+	imageOptions.HostName = hostName
 	resolvedLaunchTemplates, err := p.imageFamily.Resolve(ctx, nodeClass, nodeClaim, instanceTypes, capacityType, imageOptions)
 	if err != nil {
 		return nil, err
@@ -492,6 +902,10 @@ func (p *DefaultProvider) EnsureAll(ctx context.Context, nodeClass *v1alpha1.ECS
 				AutoSnapshotPolicyID: resolvedLaunchTemplates[i].SystemDisk.AutoSnapshotPolicyID,
 				BurstingEnabled:      resolvedLaunchTemplates[i].SystemDisk.BurstingEnabled,
 			}),
+			MetadataOptions: resolvedLaunchTemplates[i].MetadataOptions,
+			UserData:        resolvedLaunchTemplates[i].UserData,
+			InstanceName:    instanceName,
+			HostName:        hostName,
 		}
 	}
 	return launchTemplates, nil
@@ -516,12 +930,24 @@ func (p *DefaultProvider) resolveImageOptions(ctx context.Context, nodeClass *v1
 	if len(nodeClass.Status.SecurityGroups) == 0 {
 		return nil, fmt.Errorf("no security groups are present in the status")
 	}
+	kubernetesVersion, err := p.versionProvider.GetGitVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting kubernetes version, %w", err)
+	}
+	bootstrapToken, err := p.bootstrapTokenProvider.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting bootstrap token, %w", err)
+	}
 	return &imagefamily.Options{
-		ClusterName:     options.FromContext(ctx).ClusterName,
-		ClusterEndpoint: p.clusterEndpoint,
-		SecurityGroups:  nodeClass.Status.SecurityGroups,
-		Tags:            tags,
-		Labels:          labels,
-		NodeClassName:   nodeClass.Name,
+		ClusterName:       options.FromContext(ctx).ClusterName,
+		ClusterEndpoint:   p.clusterEndpoint,
+		CABundle:          p.caBundle,
+		SecurityGroups:    nodeClass.Status.SecurityGroups,
+		Tags:              tags,
+		Labels:            labels,
+		NodeClassName:     nodeClass.Name,
+		Region:            p.region,
+		KubernetesVersion: kubernetesVersion,
+		BootstrapToken:    bootstrapToken,
 	}, nil
 }
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	alicache "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/cache"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+// fakeECSAPI answers CreateAutoProvisioningGroup with the launch results, like the API does for launches that failed
+type fakeECSAPI struct {
+	client.ECSAPI
+	launchResults []*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult
+
+	provisioningGroupRequest *ecsclient.CreateAutoProvisioningGroupRequest
+	launchTemplateRequest    *ecsclient.CreateLaunchTemplateRequest
+	deletedLaunchTemplateIDs []string
+}
+
+func (f *fakeECSAPI) CreateAutoProvisioningGroupWithOptions(request *ecsclient.CreateAutoProvisioningGroupRequest, _ *util.RuntimeOptions) (*ecsclient.CreateAutoProvisioningGroupResponse, error) {
+	f.provisioningGroupRequest = request
+	return &ecsclient.CreateAutoProvisioningGroupResponse{Body: &ecsclient.CreateAutoProvisioningGroupResponseBody{
+		LaunchResults: &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResults{LaunchResult: f.launchResults},
+	}}, nil
+}
+
+func (f *fakeECSAPI) CreateLaunchTemplateWithOptions(request *ecsclient.CreateLaunchTemplateRequest, _ *util.RuntimeOptions) (*ecsclient.CreateLaunchTemplateResponse, error) {
+	f.launchTemplateRequest = request
+	return &ecsclient.CreateLaunchTemplateResponse{Body: &ecsclient.CreateLaunchTemplateResponseBody{
+		LaunchTemplateId:            tea.String("lt-1"),
+		LaunchTemplateVersionNumber: tea.Int64(1),
+	}}, nil
+}
+
+func (f *fakeECSAPI) DeleteLaunchTemplateWithOptions(request *ecsclient.DeleteLaunchTemplateRequest, _ *util.RuntimeOptions) (*ecsclient.DeleteLaunchTemplateResponse, error) {
+	f.deletedLaunchTemplateIDs = append(f.deletedLaunchTemplateIDs, tea.StringValue(request.LaunchTemplateId))
+	return &ecsclient.DeleteLaunchTemplateResponse{}, nil
+}
+
+func TestCreateAutoProvisioningGroupLaunchResults(t *testing.T) {
+	ctx := context.Background()
+	noStock := &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{
+		ErrorCode:    tea.String("OperationDenied.NoStock"),
+		ErrorMsg:     tea.String("The resource is out of stock in the specified zone."),
+		InstanceType: tea.String("ecs.g7.large"),
+		ZoneId:       tea.String("cn-hangzhou-h"),
+	}
+	ecsapi := &fakeECSAPI{launchResults: []*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{noStock}}
+	p := &DefaultProvider{ecsClient: ecsapi, unavailableOfferings: alicache.NewUnavailableOfferings()}
+
+	if _, err := p.createAutoProvisioningGroup(ctx, &ecsclient.CreateAutoProvisioningGroupRequest{}, karpv1.CapacityTypeSpot); !cloudprovider.IsInsufficientCapacityError(err) {
+		t.Errorf("launching out of stock instance types, got error %v, want insufficient capacity", err)
+	}
+	if !p.unavailableOfferings.IsUnavailable("ecs.g7.large", "cn-hangzhou-h", karpv1.CapacityTypeSpot) {
+		t.Errorf("out of stock offering is available")
+	}
+
+	// Other errors aren't insufficient capacity
+	ecsapi.launchResults = []*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{noStock, {
+		ErrorCode:    tea.String("InvalidParameter.ImageId"),
+		InstanceType: tea.String("ecs.g7.xlarge"),
+		ZoneId:       tea.String("cn-hangzhou-h"),
+	}}
+	if _, err := p.createAutoProvisioningGroup(ctx, &ecsclient.CreateAutoProvisioningGroupRequest{}, karpv1.CapacityTypeSpot); err == nil || cloudprovider.IsInsufficientCapacityError(err) {
+		t.Errorf("launching with an invalid parameter, got error %v, want another error", err)
+	}
+	if p.unavailableOfferings.IsUnavailable("ecs.g7.xlarge", "cn-hangzhou-h", karpv1.CapacityTypeSpot) {
+		t.Errorf("offering failing with an invalid parameter is unavailable")
+	}
+
+	// Launches without any result don't panic
+	ecsapi.launchResults = nil
+	if _, err := p.createAutoProvisioningGroup(ctx, &ecsclient.CreateAutoProvisioningGroupRequest{}, karpv1.CapacityTypeSpot); err == nil {
+		t.Errorf("launching without launch results, want error")
+	}
+
+	ecsapi.launchResults = []*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{noStock, {
+		InstanceIds: &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResultInstanceIds{InstanceId: tea.StringSlice([]string{"i-1"})},
+	}}
+	instanceID, err := p.createAutoProvisioningGroup(ctx, &ecsclient.CreateAutoProvisioningGroupRequest{}, karpv1.CapacityTypeSpot)
+	if err != nil || instanceID != "i-1" {
+		t.Errorf("launching with a successful launch result = %q, %v, want i-1", instanceID, err)
+	}
+}
+
+func TestCreateAutoProvisioningGroupFromLaunchTemplate(t *testing.T) {
+	ctx := options.ToContext(context.Background(), &options.Options{ClusterName: "cluster"})
+	ecsapi := &fakeECSAPI{launchResults: []*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{{
+		InstanceIds: &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResultInstanceIds{InstanceId: tea.StringSlice([]string{"i-1"})},
+	}}}
+	p := &DefaultProvider{ecsClient: ecsapi, unavailableOfferings: alicache.NewUnavailableOfferings()}
+	nodeClaim := &karpv1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Name: "default-abcde"}}
+	request := &ecsclient.CreateAutoProvisioningGroupRequest{
+		LaunchConfiguration: &ecsclient.CreateAutoProvisioningGroupRequestLaunchConfiguration{
+			ImageId:          tea.String("m-1"),
+			SecurityGroupIds: tea.StringSlice([]string{"sg-1"}),
+		},
+	}
+	launchTemplate := &LaunchTemplate{MetadataOptions: &v1alpha1.MetadataOptions{HTTPEndpoint: tea.String("enabled"), HTTPTokens: tea.String("required")}}
+
+	instanceID, err := p.createAutoProvisioningGroupFromLaunchTemplate(ctx, nodeClaim, request, launchTemplate, karpv1.CapacityTypeOnDemand, map[string]string{"team": "a"})
+	if err != nil || instanceID != "i-1" {
+		t.Fatalf("launching from a launch template = %q, %v, want i-1", instanceID, err)
+	}
+	launchTemplateRequest := ecsapi.launchTemplateRequest
+	if tea.StringValue(launchTemplateRequest.ImageId) != "m-1" || tea.StringValue(launchTemplateRequest.HttpTokens) != "required" {
+		t.Errorf("launch template got image %q and tokens %q, want m-1 and required",
+			tea.StringValue(launchTemplateRequest.ImageId), tea.StringValue(launchTemplateRequest.HttpTokens))
+	}
+	if len(launchTemplateRequest.Tag) != 1 || tea.StringValue(launchTemplateRequest.Tag[0].Value) != "a" {
+		t.Errorf("launch template got instance tags %v, want the tags of the launch", launchTemplateRequest.Tag)
+	}
+	if !strings.HasPrefix(tea.StringValue(launchTemplateRequest.LaunchTemplateName), "karpenter-default-abcde-") {
+		t.Errorf("launch template got name %q, want it named after the NodeClaim", tea.StringValue(launchTemplateRequest.LaunchTemplateName))
+	}
+	if got := ecsapi.provisioningGroupRequest; tea.StringValue(got.LaunchTemplateId) != "lt-1" || tea.StringValue(got.LaunchTemplateVersion) != "1" || got.LaunchConfiguration != nil {
+		t.Errorf("provisioning group got launch template %q version %q, want lt-1 version 1 without a launch configuration",
+			tea.StringValue(got.LaunchTemplateId), tea.StringValue(got.LaunchTemplateVersion))
+	}
+	if len(ecsapi.deletedLaunchTemplateIDs) != 1 || ecsapi.deletedLaunchTemplateIDs[0] != "lt-1" {
+		t.Errorf("deleted launch templates %v, want [lt-1]", ecsapi.deletedLaunchTemplateIDs)
+	}
+}
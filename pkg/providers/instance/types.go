@@ -23,6 +23,7 @@ import (
 	"github.com/samber/lo"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils"
 )
 
@@ -43,12 +44,15 @@ type Instance struct {
 	ID               string            `json:"id"`
 	ImageID          string            `json:"imageId"`
 	Type             string            `json:"type"`
+	CPU              int32             `json:"cpu"`
 	Region           string            `json:"region"`
 	Zone             string            `json:"zone"`
 	CapacityType     string            `json:"capacityType"`
 	SecurityGroupIDs []string          `json:"securityGroupIds"`
 	VSwitchID        string            `json:"vSwitchId"`
 	Tags             map[string]string `json:"tags"`
+	// CapacityReservationID is the private pool the instance was launched into, if any
+	CapacityReservationID string `json:"capacityReservationId,omitempty"`
 }
 
 func NewInstance(out *ecsclient.DescribeInstancesResponseBodyInstancesInstance) *Instance {
@@ -57,18 +61,27 @@ func NewInstance(out *ecsclient.DescribeInstancesResponseBodyInstancesInstance)
 		log.Log.Error(err, "Failed to parse creation time")
 	}
 
+	tags := toTags(out.Tags)
+	capacityType := utils.GetCapacityTypes(*out.SpotStrategy)
+	if _, ok := tags[v1alpha1.LabelCapacityReservationID]; ok {
+		capacityType = v1alpha1.CapacityTypeReserved
+	}
+
 	return &Instance{
 		CreationTime:     creationTime,
 		Status:           *out.Status,
 		ID:               *out.InstanceId,
 		ImageID:          *out.ImageId,
 		Type:             *out.InstanceType,
+		CPU:              lo.FromPtr(out.Cpu),
 		Region:           *out.RegionId,
 		Zone:             *out.ZoneId,
-		CapacityType:     utils.GetCapacityTypes(*out.SpotStrategy),
+		CapacityType:     capacityType,
 		SecurityGroupIDs: toSecurityGroupIDs(out.SecurityGroupIds),
 		VSwitchID:        toVSwitchID(out.VpcAttributes),
-		Tags:             toTags(out.Tags),
+		Tags:             tags,
+
+		CapacityReservationID: tags[v1alpha1.LabelCapacityReservationID],
 	}
 }
 
@@ -41,8 +41,11 @@ import (
 
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
 	kcache "github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/cache"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/capacityreservation"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/pricing"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/quota"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/vswitch"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
 )
 
 type Provider interface {
@@ -52,11 +55,17 @@ type Provider interface {
 	UpdateInstanceTypeOfferings(ctx context.Context) error
 }
 
+// reservedPriceDivisor scales the on-demand price down to the near-zero marginal price of reserved offerings, which
+// keeps reserved offerings cheaper than any other while preserving the price order among them
+const reservedPriceDivisor = 10_000_000
+
 type DefaultProvider struct {
-	region          string
-	ecsClient       *ecsclient.Client
-	vSwitchProvider vswitch.Provider
-	pricingProvider pricing.Provider
+	region                      string
+	ecsClient                   client.ECSAPI
+	vSwitchProvider             vswitch.Provider
+	pricingProvider             pricing.Provider
+	capacityReservationProvider capacityreservation.Provider
+	quotaProvider               quota.Provider
 
 	// Values stored *before* considering insufficient capacity errors from the unavailableOfferings cache.
 	// Fully initialized Instance Types are also cached based on the set of all instance types, zones, unavailableOfferings cache,
@@ -79,20 +88,23 @@ type DefaultProvider struct {
 	instanceTypesOfferingsSeqNum uint64
 }
 
-func NewDefaultProvider(region string, ecsClient *ecsclient.Client,
+func NewDefaultProvider(region string, ecsClient client.ECSAPI,
 	instanceTypesCache *cache.Cache, unavailableOfferingsCache *kcache.UnavailableOfferings,
-	pricingProvider pricing.Provider, vSwitchProvider vswitch.Provider) *DefaultProvider {
+	pricingProvider pricing.Provider, vSwitchProvider vswitch.Provider,
+	capacityReservationProvider capacityreservation.Provider, quotaProvider quota.Provider) *DefaultProvider {
 	return &DefaultProvider{
-		ecsClient:              ecsClient,
-		region:                 region,
-		vSwitchProvider:        vSwitchProvider,
-		pricingProvider:        pricingProvider,
-		instanceTypesInfo:      []*ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType{},
-		instanceTypesOfferings: map[string]sets.Set[string]{},
-		instanceTypesCache:     instanceTypesCache,
-		unavailableOfferings:   unavailableOfferingsCache,
-		cm:                     pretty.NewChangeMonitor(),
-		instanceTypesSeqNum:    0,
+		ecsClient:                   ecsClient,
+		region:                      region,
+		vSwitchProvider:             vSwitchProvider,
+		pricingProvider:             pricingProvider,
+		capacityReservationProvider: capacityReservationProvider,
+		quotaProvider:               quotaProvider,
+		instanceTypesInfo:           []*ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType{},
+		instanceTypesOfferings:      map[string]sets.Set[string]{},
+		instanceTypesCache:          instanceTypesCache,
+		unavailableOfferings:        unavailableOfferingsCache,
+		cm:                          pretty.NewChangeMonitor(),
+		instanceTypesSeqNum:         0,
 	}
 }
 
@@ -129,12 +141,21 @@ func (p *DefaultProvider) List(ctx context.Context, kc *v1alpha1.KubeletConfigur
 	// Compute fully initialized instance types hash key
 	vSwitchZonesHash, _ := hashstructure.Hash(vSwitchsZones, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	kcHash, _ := hashstructure.Hash(kc, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
-	key := fmt.Sprintf("%d-%d-%d-%016x-%016x",
+	// Reservations are only usable in zones the nodes can be launched in
+	reservations := lo.GroupBy(lo.Filter(nodeClass.Status.CapacityReservations, func(r v1alpha1.CapacityReservation, _ int) bool {
+		return vSwitchsZones.Has(r.ZoneID)
+	}), func(r v1alpha1.CapacityReservation) string { return r.InstanceType })
+	reservationsHash, _ := hashstructure.Hash(nodeClass.Status.CapacityReservations, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	key := fmt.Sprintf("%d-%d-%d-%d-%d-%016x-%016x-%016x-%s",
 		p.instanceTypesSeqNum,
 		p.instanceTypesOfferingsSeqNum,
 		p.unavailableOfferings.SeqNum,
+		p.capacityReservationProvider.SeqNum(),
+		p.quotaProvider.SeqNum(),
 		vSwitchZonesHash,
 		kcHash,
+		reservationsHash,
+		nodeClass.ImageFamily(),
 	)
 
 	if item, ok := p.instanceTypesCache.Get(key); ok {
@@ -174,8 +195,8 @@ func (p *DefaultProvider) List(ctx context.Context, kc *v1alpha1.KubeletConfigur
 		// Any changes to the values passed into the NewInstanceType method will require making updates to the cache key
 		// so that Karpenter is able to cache the set of InstanceTypes based on values that alter the set of instance types
 		// !!! Important !!!
-		return NewInstanceType(ctx, i, kc, p.region,
-			p.createOfferings(ctx, *i.InstanceTypeId, zoneData))
+		return NewInstanceType(ctx, i, kc, p.region, nodeClass.ImageFamily(),
+			p.createOfferings(ctx, i, zoneData, reservations[lo.FromPtr(i.InstanceTypeId)]))
 	})
 
 	p.instanceTypesCache.SetDefault(key, result)
@@ -274,7 +295,7 @@ func processAvailableResources(az *ecsclient.DescribeAvailableResourceResponseBo
 	}
 }
 
-func getAllInstanceTypes(client *ecsclient.Client) ([]*ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType, error) {
+func getAllInstanceTypes(ecsClient client.ECSAPI) ([]*ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType, error) {
 	var InstanceTypes []*ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType
 
 	describeInstanceTypesRequest := &ecsclient.DescribeInstanceTypesRequest{
@@ -290,7 +311,7 @@ func getAllInstanceTypes(client *ecsclient.Client) ([]*ecsclient.DescribeInstanc
 	}
 
 	for {
-		resp, err := client.DescribeInstanceTypesWithOptions(describeInstanceTypesRequest, &util.RuntimeOptions{})
+		resp, err := ecsClient.DescribeInstanceTypesWithOptions(describeInstanceTypesRequest, &util.RuntimeOptions{})
 		if err != nil {
 			return nil, err
 		}
@@ -316,7 +337,17 @@ func getAllInstanceTypes(client *ecsclient.Client) ([]*ecsclient.DescribeInstanc
 // offering, you can do the following thanks to this invariant:
 //
 //	offering.Requirements.Get(v1.TopologyLabelZone).Any()
-func (p *DefaultProvider) createOfferings(_ context.Context, instanceType string, zones []ZoneData) []cloudprovider.Offering {
+//
+// Burstable instance types are priced without the surplus CPU credits they may consume in Unlimited mode. Neither the
+// ECS API nor the price data carry the price of surplus credits, which depends on the load of the instance.
+//
+// Every capacity reservation and elasticity assurance of the instance type adds a reserved offering at a near-zero
+// price, it becomes unavailable once all reserved instances are launched so that launches fall back to on-demand.
+// On-demand and spot offerings are unavailable once an instance would exceed the vCPU quota of the account.
+func (p *DefaultProvider) createOfferings(_ context.Context, info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType, zones []ZoneData,
+	reservations []v1alpha1.CapacityReservation) []cloudprovider.Offering {
+	instanceType := lo.FromPtr(info.InstanceTypeId)
+	vCPUs := int64(lo.FromPtr(info.CpuCoreCount))
 	var offerings []cloudprovider.Offering
 	for _, zone := range zones {
 		odPrice, odOK := p.pricingProvider.OnDemandPrice(instanceType)
@@ -324,18 +355,27 @@ func (p *DefaultProvider) createOfferings(_ context.Context, instanceType string
 
 		if odOK {
 			isUnavailable := p.unavailableOfferings.IsUnavailable(instanceType, zone.ID, v1beta1.CapacityTypeOnDemand)
-			offeringAvailable := !isUnavailable && odOK && zone.Available
+			offeringAvailable := !isUnavailable && odOK && zone.Available && p.quotaProvider.Fits(instanceType, vCPUs, v1beta1.CapacityTypeOnDemand)
 
 			offerings = append(offerings, p.createOffering(zone.ID, v1beta1.CapacityTypeOnDemand, odPrice, offeringAvailable))
 		}
 
 		if spotOK {
 			isUnavailable := p.unavailableOfferings.IsUnavailable(instanceType, zone.ID, v1beta1.CapacityTypeSpot)
-			offeringAvailable := !isUnavailable && spotOK && zone.Available
+			offeringAvailable := !isUnavailable && spotOK && zone.Available && p.quotaProvider.Fits(instanceType, vCPUs, v1beta1.CapacityTypeSpot)
 
 			offerings = append(offerings, p.createOffering(zone.ID, v1beta1.CapacityTypeSpot, spotPrice, offeringAvailable))
 		}
 	}
+	for _, reservation := range reservations {
+		odPrice, _ := p.pricingProvider.OnDemandPrice(instanceType)
+		isUnavailable := p.unavailableOfferings.IsUnavailable(instanceType, reservation.ZoneID, v1alpha1.CapacityTypeReserved)
+		offeringAvailable := !isUnavailable && p.capacityReservationProvider.AvailableInstanceCount(reservation) > 0
+
+		offering := p.createOffering(reservation.ZoneID, v1alpha1.CapacityTypeReserved, odPrice/reservedPriceDivisor, offeringAvailable)
+		offering.Requirements.Add(scheduling.NewRequirement(v1alpha1.LabelCapacityReservationID, corev1.NodeSelectorOpIn, reservation.ID))
+		offerings = append(offerings, offering)
+	}
 	return offerings
 }
 
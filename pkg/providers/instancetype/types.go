@@ -36,6 +36,7 @@ import (
 
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/imagefamily"
 )
 
 var (
@@ -54,7 +55,14 @@ type ZoneData struct {
 	Available bool
 }
 
-func NewInstanceType(ctx context.Context, info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType, kc *v1alpha1.KubeletConfiguration, region string, offerings cloudprovider.Offerings) *cloudprovider.InstanceType {
+func NewInstanceType(ctx context.Context, info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType, kc *v1alpha1.KubeletConfiguration, region string, imageFamily string, offerings cloudprovider.Offerings) *cloudprovider.InstanceType {
+	systemReserved, evictionHard := kc.SystemReserved, kc.EvictionHard
+	// Windows nodes reserve more memory for the OS, the bootstrap applies the same defaults to the kubelet
+	windows, isWindows := imagefamily.GetImageFamily(imageFamily, nil).(*imagefamily.Windows)
+	if isWindows {
+		systemReserved = lo.Ternary(len(systemReserved) == 0, imagefamily.WindowsDefaultSystemReserved, systemReserved)
+		evictionHard = lo.Ternary(len(evictionHard) == 0, imagefamily.WindowsDefaultEvictionHard, evictionHard)
+	}
 
 	it := &cloudprovider.InstanceType{
 		Name:         *info.InstanceTypeId,
@@ -63,10 +71,14 @@ func NewInstanceType(ctx context.Context, info *ecsclient.DescribeInstanceTypesR
 		Capacity:     computeCapacity(ctx, info, kc.MaxPods, kc.PodsPerCore),
 		Overhead: &cloudprovider.InstanceTypeOverhead{
 			KubeReserved:      kubeReservedResources(cpu(info), pods(ctx, info, kc.MaxPods, kc.PodsPerCore), kc.KubeReserved),
-			SystemReserved:    systemReservedResources(kc.SystemReserved),
-			EvictionThreshold: evictionThreshold(memory(ctx, info), ephemeralStorage(info), kc.EvictionHard, kc.EvictionSoft),
+			SystemReserved:    systemReservedResources(systemReserved),
+			EvictionThreshold: evictionThreshold(memory(ctx, info), ephemeralStorage(info), evictionHard, kc.EvictionSoft),
 		},
 	}
+	if isWindows {
+		it.Requirements[corev1.LabelOSStable] = scheduling.NewRequirement(corev1.LabelOSStable, corev1.NodeSelectorOpIn, string(corev1.Windows))
+		it.Requirements[corev1.LabelWindowsBuild] = scheduling.NewRequirement(corev1.LabelWindowsBuild, corev1.NodeSelectorOpIn, windows.Build)
+	}
 	if it.Requirements.Compatible(scheduling.NewRequirements(scheduling.NewRequirement(corev1.LabelOSStable, corev1.NodeSelectorOpIn, string(corev1.Windows)))) == nil {
 		it.Capacity[v1alpha1.ResourcePrivateIPv4Address] = *privateIPv4Address(info)
 	}
@@ -119,6 +131,7 @@ func computeRequirements(info *ecsclient.DescribeInstanceTypesResponseBodyInstan
 		scheduling.NewRequirement(v1alpha1.LabelInstanceAcceleratorManufacturer, corev1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1alpha1.LabelInstanceAcceleratorCount, corev1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1alpha1.LabelInstanceEncryptionInTransitSupported, corev1.NodeSelectorOpIn, fmt.Sprint(info.NetworkEncryptionSupport)),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceBurstable, corev1.NodeSelectorOpIn, fmt.Sprint(isBurstable(info))),
 	)
 	// Only add zone-id label when available in offerings. It may not be available if a user has upgraded from a
 	// previous version of Karpenter w/o zone-id support and the nodeclass vswitch status has not yet updated.
@@ -164,6 +177,11 @@ func computeRequirements(info *ecsclient.DescribeInstanceTypesResponseBodyInstan
 	return requirements
 }
 
+// isBurstable returns whether the instance type earns and spends CPU credits, like the t5 and t6 families
+func isBurstable(info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType) bool {
+	return lo.FromPtr(info.BaselineCredit) > 0
+}
+
 func computeCapacity(ctx context.Context, info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType, maxPods *int32, podsPerCore *int32) corev1.ResourceList {
 
 	resourceList := corev1.ResourceList{
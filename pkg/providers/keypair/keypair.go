@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keypair
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+type Provider interface {
+	Get(context.Context, string) (*ecs.DescribeKeyPairsResponseBodyKeyPairsKeyPair, error)
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	region string
+	ecsapi client.ECSAPI
+	cache  *cache.Cache
+}
+
+func NewDefaultProvider(region string, ecsapi client.ECSAPI, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		region: region,
+		ecsapi: ecsapi,
+		cache:  cache,
+	}
+}
+
+// Get returns the key pair with the given name in the region, or nil if it doesn't exist
+func (p *DefaultProvider) Get(ctx context.Context, name string) (*ecs.DescribeKeyPairsResponseBodyKeyPairsKeyPair, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if keyPair, ok := p.cache.Get(name); ok {
+		return keyPair.(*ecs.DescribeKeyPairsResponseBodyKeyPairsKeyPair), nil
+	}
+	// KeyPairName supports fuzzy matching, "foo" also returns "foo-2", so every page is searched for an exact match
+	request := &ecs.DescribeKeyPairsRequest{
+		RegionId:    tea.String(p.region),
+		KeyPairName: tea.String(name),
+		PageSize:    tea.Int32(50),
+	}
+	var keyPair *ecs.DescribeKeyPairsResponseBodyKeyPairsKeyPair
+	for pageNumber := int32(1); keyPair == nil; pageNumber++ {
+		request.PageNumber = tea.Int32(pageNumber)
+		output, err := p.ecsapi.DescribeKeyPairsWithOptions(request, &util.RuntimeOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("describing key pairs, %w", err)
+		} else if output.Body == nil || output.Body.KeyPairs == nil {
+			return nil, fmt.Errorf("unexpected null value was returned")
+		}
+		keyPair, _ = lo.Find(output.Body.KeyPairs.KeyPair, func(k *ecs.DescribeKeyPairsResponseBodyKeyPairsKeyPair) bool {
+			return lo.FromPtr(k.KeyPairName) == name
+		})
+		if lo.FromPtr(output.Body.TotalCount) <= pageNumber*50 || len(output.Body.KeyPairs.KeyPair) < 50 {
+			break
+		}
+	}
+	if keyPair == nil {
+		return nil, nil
+	}
+	p.cache.SetDefault(name, keyPair)
+	return keyPair, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keypair
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+// fakeECSAPI matches key pair names by prefix like the fuzzy matching of DescribeKeyPairs
+type fakeECSAPI struct {
+	client.ECSAPI
+	keyPairs []string
+}
+
+func (f *fakeECSAPI) DescribeKeyPairsWithOptions(request *ecs.DescribeKeyPairsRequest, _ *util.RuntimeOptions) (*ecs.DescribeKeyPairsResponse, error) {
+	matches := lo.Filter(f.keyPairs, func(name string, _ int) bool { return strings.HasPrefix(name, lo.FromPtr(request.KeyPairName)) })
+	pageSize, pageNumber := int(lo.FromPtr(request.PageSize)), int(lo.FromPtr(request.PageNumber))
+	page := lo.Slice(matches, (pageNumber-1)*pageSize, pageNumber*pageSize)
+	return &ecs.DescribeKeyPairsResponse{Body: &ecs.DescribeKeyPairsResponseBody{
+		TotalCount: tea.Int32(int32(len(matches))),
+		KeyPairs: &ecs.DescribeKeyPairsResponseBodyKeyPairs{KeyPair: lo.Map(page, func(name string, _ int) *ecs.DescribeKeyPairsResponseBodyKeyPairsKeyPair {
+			return &ecs.DescribeKeyPairsResponseBodyKeyPairsKeyPair{KeyPairName: tea.String(name)}
+		})},
+	}}, nil
+}
+
+func TestGetExactMatch(t *testing.T) {
+	// The exact match is listed after a full page of fuzzy matches
+	keyPairs := lo.Times(50, func(i int) string { return fmt.Sprintf("foo-%d", i) })
+	p := NewDefaultProvider("cn-hangzhou", &fakeECSAPI{keyPairs: append(keyPairs, "foo")}, cache.New(cache.NoExpiration, cache.NoExpiration))
+
+	keyPair, err := p.Get(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("getting key pair, %v", err)
+	}
+	if got := lo.FromPtr(keyPair.KeyPairName); got != "foo" {
+		t.Errorf("key pair = %q, want foo", got)
+	}
+
+	p = NewDefaultProvider("cn-hangzhou", &fakeECSAPI{keyPairs: []string{"foo-2"}}, cache.New(cache.NoExpiration, cache.NoExpiration))
+	if keyPair, err := p.Get(context.Background(), "foo"); err != nil || keyPair != nil {
+		t.Errorf("getting missing key pair = %v, %v, want nil without error", keyPair, err)
+	}
+}
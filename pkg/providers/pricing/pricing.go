@@ -25,7 +25,6 @@ import (
 	"time"
 
 	"github.com/cloudpilot-ai/priceserver/pkg/apis"
-	"github.com/cloudpilot-ai/priceserver/pkg/tools"
 	"github.com/samber/lo"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
@@ -56,7 +55,7 @@ type Provider interface {
 type DefaultProvider struct {
 	muPriceLastUpdatedTimestamp sync.RWMutex
 	priceLastUpdatedTimestamp   time.Time
-	alibabaCloudPriceClient     tools.QueryClientInterface
+	alibabaCloudPriceClient     priceClient
 
 	region string
 	cm     *pretty.ChangeMonitor
@@ -94,8 +93,8 @@ const (
 	defaultPriceQueryEndpoint = "https://pre-price.cloudpilot.ai"
 )
 
-func NewDefaultProvider(ctx context.Context, region string) (*DefaultProvider, error) {
-	queryClient, err := tools.NewQueryClient(defaultPriceQueryEndpoint, tools.AlibabaCloudProvider, region)
+func NewDefaultProvider(ctx context.Context, region string, httpClient *http.Client) (*DefaultProvider, error) {
+	queryClient, err := newQueryClient(ctx, httpClient, defaultPriceQueryEndpoint, region)
 	if err != nil {
 		log.FromContext(ctx).Error(err, "unable to create query client")
 		return nil, err
@@ -246,7 +245,7 @@ func (p *DefaultProvider) syncPricingData(ctx context.Context) error {
 	p.muPriceLastUpdatedTimestamp.Unlock()
 
 	if lastUpdatedTime.Add(time.Minute * 5).Before(time.Now()) {
-		if err := p.alibabaCloudPriceClient.Sync(); err != nil {
+		if err := p.alibabaCloudPriceClient.Sync(ctx); err != nil {
 			log.FromContext(ctx).Error(err, "failed to sync pricing data from alibaba cloud")
 			return err
 		}
@@ -18,6 +18,7 @@ package pricing
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -52,7 +53,7 @@ func TestNewDefaultProvider(t *testing.T) {
 	ctx := context.Background()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			provider, err := NewDefaultProvider(ctx, tt.args.region)
+			provider, err := NewDefaultProvider(ctx, tt.args.region, http.DefaultClient)
 			assert.NoError(t, err)
 			assert.NoError(t, provider.UpdateSpotPricing(ctx))
 			assert.NoError(t, provider.UpdateOnDemandPricing(ctx))
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/cloudpilot-ai/priceserver/pkg/apis"
+)
+
+// priceClient is the subset of the price server query client used by the provider
+type priceClient interface {
+	Sync(context.Context) error
+	ListInstancesDetails(region string) *apis.RegionalInstancePrice
+}
+
+// queryClient queries the prices of a region from the price server like the query client of the priceserver module,
+// which always sends its requests with http.DefaultClient, with the HTTP client of the operator instead
+type queryClient struct {
+	httpClient *http.Client
+	url        string
+
+	mu        sync.Mutex
+	priceData map[string]*apis.RegionalInstancePrice
+}
+
+func newQueryClient(ctx context.Context, httpClient *http.Client, endpoint, region string) (*queryClient, error) {
+	queryURL, err := url.JoinPath(endpoint, "/api/v1/alibabacloud/ecs/regions", region, "price")
+	if err != nil {
+		return nil, fmt.Errorf("joining price query url, %w", err)
+	}
+	q := &queryClient{
+		httpClient: httpClient,
+		url:        queryURL,
+		priceData:  map[string]*apis.RegionalInstancePrice{},
+	}
+	if err := q.Sync(ctx); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *queryClient) Sync(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, q.url, nil)
+	if err != nil {
+		return fmt.Errorf("creating price request, %w", err)
+	}
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("getting price data, %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("getting price data, %s", resp.Status)
+	}
+	priceData := map[string]*apis.RegionalInstancePrice{}
+	if err := json.NewDecoder(resp.Body).Decode(&priceData); err != nil {
+		return fmt.Errorf("decoding price data, %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.priceData = priceData
+	return nil
+}
+
+func (q *queryClient) ListInstancesDetails(region string) *apis.RegionalInstancePrice {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.priceData[region]; !ok {
+		return nil
+	}
+	return q.priceData[region].DeepCopy()
+}
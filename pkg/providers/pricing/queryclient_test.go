@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQueryClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/alibabacloud/ecs/regions/cn-hangzhou/price" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"cn-hangzhou": {"instanceTypePrices": {"ecs.g7.large": {"onDemandPricePerHour": 0.5}}}}`))
+	}))
+	defer server.Close()
+
+	// The prices are queried with the HTTP client that is passed, the default client doesn't trust the test server
+	q, err := newQueryClient(context.Background(), server.Client(), server.URL, "cn-hangzhou")
+	if err != nil {
+		t.Fatalf("creating query client, %v", err)
+	}
+	prices := q.ListInstancesDetails("cn-hangzhou")
+	if prices == nil || prices.InstanceTypePrices["ecs.g7.large"] == nil {
+		t.Fatalf("prices of cn-hangzhou = %v, want the price of ecs.g7.large", prices)
+	}
+	if q.ListInstancesDetails("cn-beijing") != nil {
+		t.Errorf("found prices of a region that wasn't queried")
+	}
+
+	if _, err := newQueryClient(context.Background(), http.DefaultClient, server.URL, "cn-hangzhou"); err == nil {
+		t.Errorf("querying the test server with the default client, want certificate error")
+	}
+}
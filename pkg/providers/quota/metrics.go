@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	quotaSubsystem = "quota"
+	quotaLabel     = "quota"
+)
+
+var (
+	VCPUQuotaRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: quotaSubsystem,
+			Name:      "vcpu_remaining",
+			Help:      "Number of vCPUs that can still be launched within the account quota, labeled by the postpaid or spot quota.",
+		},
+		[]string{quotaLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(VCPUQuotaRemaining)
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/providers/instance"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+// Ref: https://api.aliyun.com/api/Ecs/2014-05-26/DescribeAccountAttributes
+const (
+	attributeMaxPostPaidVCPU        = "max-postpaid-instance-vcpu-count"
+	attributeMaxSpotVCPU            = "max-spot-instance-vcpu-count"
+	attributeUsedPostPaidVCPU       = "used-postpaid-instance-vcpu-count"
+	attributeUsedSpotVCPU           = "used-spot-instance-vcpu-count"
+	attributeSupportedPostPaidTypes = "supported-postpaid-instance-types"
+	postPaidQuota                   = "postpaid"
+	spotQuota                       = "spot"
+)
+
+type Provider interface {
+	UpdateQuotas(context.Context) error
+	// Remaining returns the vCPUs that can still be launched with the capacity type, it returns false if the quota is unknown
+	Remaining(capacityType string) (int64, bool)
+	// Fits returns whether an instance of the instance type and capacity type can be launched within the account quotas
+	Fits(instanceType string, vCPUs int64, capacityType string) bool
+	// MarkLaunched counts the vCPUs of a launched instance against the quota of its capacity type until the quotas
+	// are updated
+	MarkLaunched(capacityType string, vCPUs int64)
+	SeqNum() uint64
+}
+
+type DefaultProvider struct {
+	sync.RWMutex
+	region           string
+	ecsapi           client.ECSAPI
+	instanceProvider instance.Provider
+	cm               *pretty.ChangeMonitor
+
+	remaining map[string]int64
+	// supportedPostPaidInstanceTypes restricts the pay-as-you-go instance types of the account, empty if unrestricted.
+	// It's the only per instance type restriction known, the vCPU quotas of instance families live in Quota Center
+	// and aren't checked, launches exceeding them fail with a quota error instead.
+	supportedPostPaidInstanceTypes sets.Set[string]
+	seqNum                         uint64
+}
+
+func NewDefaultProvider(region string, ecsapi client.ECSAPI, instanceProvider instance.Provider) *DefaultProvider {
+	return &DefaultProvider{
+		region:                         region,
+		ecsapi:                         ecsapi,
+		instanceProvider:               instanceProvider,
+		cm:                             pretty.NewChangeMonitor(),
+		remaining:                      map[string]int64{},
+		supportedPostPaidInstanceTypes: sets.New[string](),
+	}
+}
+
+// UpdateQuotas refreshes the remaining vCPU quotas. The usage reported by ECS lags behind launches, so it's compared
+// with the vCPUs of the instances launched by Karpenter and the larger one is used.
+func (p *DefaultProvider) UpdateQuotas(ctx context.Context) error {
+	output, err := p.ecsapi.DescribeAccountAttributesWithOptions(&ecs.DescribeAccountAttributesRequest{
+		RegionId: tea.String(p.region),
+		AttributeName: tea.StringSlice([]string{
+			attributeMaxPostPaidVCPU, attributeMaxSpotVCPU,
+			attributeUsedPostPaidVCPU, attributeUsedSpotVCPU,
+			attributeSupportedPostPaidTypes,
+		}),
+	}, &util.RuntimeOptions{})
+	if err != nil {
+		return fmt.Errorf("describing account attributes, %w", err)
+	} else if output.Body == nil || output.Body.AccountAttributeItems == nil {
+		return fmt.Errorf("unexpected null value was returned")
+	}
+	attributes := lo.SliceToMap(output.Body.AccountAttributeItems.AccountAttributeItem, func(item *ecs.DescribeAccountAttributesResponseBodyAccountAttributeItemsAccountAttributeItem) (string, []*ecs.DescribeAccountAttributesResponseBodyAccountAttributeItemsAccountAttributeItemAttributeValuesValueItem) {
+		if item.AttributeValues == nil {
+			return lo.FromPtr(item.AttributeName), nil
+		}
+		return lo.FromPtr(item.AttributeName), item.AttributeValues.ValueItem
+	})
+
+	instances, err := p.instanceProvider.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing instances, %w", err)
+	}
+	launched := map[string]int64{}
+	for _, i := range instances {
+		launched[quotaFor(i.CapacityType)] += int64(i.CPU)
+	}
+
+	remaining := map[string]int64{}
+	for quota, attribute := range map[string][2]string{
+		postPaidQuota: {attributeMaxPostPaidVCPU, attributeUsedPostPaidVCPU},
+		spotQuota:     {attributeMaxSpotVCPU, attributeUsedSpotVCPU},
+	} {
+		limit, ok := regionalValue(attributes[attribute[0]])
+		if !ok {
+			continue
+		}
+		used, _ := regionalValue(attributes[attribute[1]])
+		remaining[quota] = limit - lo.Max([]int64{used, launched[quota]})
+	}
+	supported := sets.New(lo.FilterMap(attributes[attributeSupportedPostPaidTypes], func(v *ecs.DescribeAccountAttributesResponseBodyAccountAttributeItemsAccountAttributeItemAttributeValuesValueItem, _ int) (string, bool) {
+		return lo.FromPtr(v.InstanceType), lo.FromPtr(v.InstanceType) != ""
+	})...)
+
+	p.Lock()
+	defer p.Unlock()
+	for quota, vCPUs := range remaining {
+		VCPUQuotaRemaining.WithLabelValues(quota).Set(float64(vCPUs))
+	}
+	if p.cm.HasChanged("remaining", remaining) || p.cm.HasChanged("supported-postpaid-instance-types", supported) {
+		atomic.AddUint64(&p.seqNum, 1)
+		log.FromContext(ctx).WithValues("remaining-vcpus", remaining, "supported-postpaid-instance-types", supported.Len()).V(1).Info("discovered account quotas")
+	}
+	p.remaining = remaining
+	p.supportedPostPaidInstanceTypes = supported
+	return nil
+}
+
+func (p *DefaultProvider) Remaining(capacityType string) (int64, bool) {
+	p.RLock()
+	defer p.RUnlock()
+
+	remaining, ok := p.remaining[quotaFor(capacityType)]
+	return remaining, ok
+}
+
+func (p *DefaultProvider) Fits(instanceType string, vCPUs int64, capacityType string) bool {
+	p.RLock()
+	defer p.RUnlock()
+
+	if quotaFor(capacityType) == postPaidQuota && p.supportedPostPaidInstanceTypes.Len() > 0 && !p.supportedPostPaidInstanceTypes.Has(instanceType) {
+		return false
+	}
+	remaining, ok := p.remaining[quotaFor(capacityType)]
+	return !ok || vCPUs <= remaining
+}
+
+// MarkLaunched lowers the remaining vCPUs right away, the usage reported by ECS catches up with the launch later
+func (p *DefaultProvider) MarkLaunched(capacityType string, vCPUs int64) {
+	p.Lock()
+	defer p.Unlock()
+
+	quota := quotaFor(capacityType)
+	remaining, ok := p.remaining[quota]
+	if !ok {
+		return
+	}
+	p.remaining[quota] = remaining - vCPUs
+	VCPUQuotaRemaining.WithLabelValues(quota).Set(float64(p.remaining[quota]))
+	atomic.AddUint64(&p.seqNum, 1)
+}
+
+// SeqNum changes whenever the quotas have changed
+func (p *DefaultProvider) SeqNum() uint64 {
+	return atomic.LoadUint64(&p.seqNum)
+}
+
+// quotaFor returns the quota a capacity type is counted against, reserved instances are pay-as-you-go instances
+func quotaFor(capacityType string) string {
+	return lo.Ternary(capacityType == karpv1.CapacityTypeSpot, spotQuota, postPaidQuota)
+}
+
+// regionalValue returns the value of a vCPU quota attribute, ECS reports these for the region as a whole when the
+// request isn't scoped to a zone, so the value without a zone is preferred over any zonal one
+func regionalValue(values []*ecs.DescribeAccountAttributesResponseBodyAccountAttributeItemsAccountAttributeItemAttributeValuesValueItem) (int64, bool) {
+	values = lo.Filter(values, func(v *ecs.DescribeAccountAttributesResponseBodyAccountAttributeItemsAccountAttributeItemAttributeValuesValueItem, _ int) bool {
+		_, err := strconv.ParseInt(lo.FromPtr(v.Value), 10, 64)
+		return err == nil
+	})
+	if len(values) == 0 {
+		return 0, false
+	}
+	value, ok := lo.Find(values, func(v *ecs.DescribeAccountAttributesResponseBodyAccountAttributeItemsAccountAttributeItemAttributeValuesValueItem) bool {
+		return lo.FromPtr(v.ZoneId) == ""
+	})
+	if !ok {
+		value = values[0]
+	}
+	n, _ := strconv.ParseInt(lo.FromPtr(value.Value), 10, 64)
+	return n, true
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"testing"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+)
+
+func TestMarkLaunched(t *testing.T) {
+	p := NewDefaultProvider("cn-hangzhou", nil, nil)
+	p.remaining[spotQuota] = 8
+
+	if !p.Fits("ecs.g7.2xlarge", 8, karpv1.CapacityTypeSpot) {
+		t.Fatalf("8 vCPUs don't fit into a remaining quota of 8")
+	}
+	seqNum := p.SeqNum()
+	p.MarkLaunched(karpv1.CapacityTypeSpot, 4)
+	if p.Fits("ecs.g7.2xlarge", 8, karpv1.CapacityTypeSpot) {
+		t.Errorf("8 vCPUs fit after launching 4 of a remaining quota of 8")
+	}
+	if !p.Fits("ecs.g7.xlarge", 4, karpv1.CapacityTypeSpot) {
+		t.Errorf("4 vCPUs don't fit after launching 4 of a remaining quota of 8")
+	}
+	if p.SeqNum() == seqNum {
+		t.Errorf("sequence number unchanged after a launch")
+	}
+
+	// Launches don't make up a quota that isn't known
+	p.MarkLaunched(karpv1.CapacityTypeOnDemand, 4)
+	if _, ok := p.Remaining(karpv1.CapacityTypeOnDemand); ok {
+		t.Errorf("on-demand quota known after a launch")
+	}
+}
+
+func TestRegionalValue(t *testing.T) {
+	values := []*ecs.DescribeAccountAttributesResponseBodyAccountAttributeItemsAccountAttributeItemAttributeValuesValueItem{
+		{Value: tea.String("100"), ZoneId: tea.String("cn-hangzhou-h")},
+		{Value: tea.String("800")},
+	}
+	if value, ok := regionalValue(values); !ok || value != 800 {
+		t.Errorf("regional value = %d, %t, want 800", value, ok)
+	}
+	if _, ok := regionalValue([]*ecs.DescribeAccountAttributesResponseBodyAccountAttributeItemsAccountAttributeItemAttributeValuesValueItem{{Value: tea.String("none")}}); ok {
+		t.Errorf("found regional value without a number")
+	}
+}
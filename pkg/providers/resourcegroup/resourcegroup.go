@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcegroup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+type Provider interface {
+	Exists(context.Context, string) (bool, error)
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	resourceManagerAPI client.ResourceManagerAPI
+	cache              *cache.Cache
+}
+
+func NewDefaultProvider(resourceManagerAPI client.ResourceManagerAPI, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		resourceManagerAPI: resourceManagerAPI,
+		cache:              cache,
+	}
+}
+
+// Exists returns whether the resource group exists and can take new resources. Resource Manager doesn't fail on IDs
+// of missing groups but leaves them out of the list, groups being deleted are listed with another status.
+func (p *DefaultProvider) Exists(_ context.Context, id string) (bool, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if exists, ok := p.cache.Get(id); ok {
+		return exists.(bool), nil
+	}
+	resourceGroups, err := p.resourceManagerAPI.ListResourceGroups([]string{id})
+	if err != nil {
+		return false, fmt.Errorf("listing resource group %s, %w", id, err)
+	}
+	exists := lo.ContainsBy(resourceGroups, func(rg *client.ResourceGroup) bool {
+		return rg.ID == id && rg.Status == client.ResourceGroupStatusOK
+	})
+	p.cache.SetDefault(id, exists)
+	return exists, nil
+}
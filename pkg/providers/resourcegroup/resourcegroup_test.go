@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcegroup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+// fakeResourceManagerAPI leaves missing groups out of the list like Resource Manager does
+type fakeResourceManagerAPI struct {
+	resourceGroups []*client.ResourceGroup
+	calls          int
+}
+
+func (f *fakeResourceManagerAPI) ListResourceGroups(ids []string) ([]*client.ResourceGroup, error) {
+	f.calls++
+	return lo.Filter(f.resourceGroups, func(rg *client.ResourceGroup, _ int) bool { return lo.Contains(ids, rg.ID) }), nil
+}
+
+func TestExists(t *testing.T) {
+	api := &fakeResourceManagerAPI{resourceGroups: []*client.ResourceGroup{
+		{ID: "rg-ok", Status: client.ResourceGroupStatusOK},
+		{ID: "rg-deleting", Status: "Deleting"},
+	}}
+	p := NewDefaultProvider(api, cache.New(cache.NoExpiration, cache.NoExpiration))
+
+	for id, want := range map[string]bool{"rg-ok": true, "rg-deleting": false, "rg-missing": false} {
+		exists, err := p.Exists(context.Background(), id)
+		if err != nil {
+			t.Fatalf("checking resource group %s, %v", id, err)
+		}
+		if exists != want {
+			t.Errorf("resource group %s exists = %t, want %t", id, exists, want)
+		}
+	}
+
+	if _, err := p.Exists(context.Background(), "rg-missing"); err != nil {
+		t.Fatalf("checking resource group rg-missing, %v", err)
+	}
+	if api.calls != 3 {
+		t.Errorf("listed resource groups %d times, want 3", api.calls)
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitygroup
+
+import (
+	"context"
+	"fmt"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	vpc "github.com/alibabacloud-go/vpc-20160428/v6/client"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+)
+
+// EnsureManaged returns the security group owned by the ECSNodeClass, creating it in the given VPC if it doesn't exist yet.
+// The group accepts all traffic originating from the VPC CIDR blocks so that nodes and pods can reach each other, the
+// rules are authorized whenever the group isn't cached so that a group left behind by a failed authorization is repaired.
+func (p *DefaultProvider) EnsureManaged(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, vpcID string) (*ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if sg, ok := p.cache.Get(managedCacheKey(nodeClass)); ok {
+		return sg.(*ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup), nil
+	}
+	securityGroup, err := p.getManaged(ctx, nodeClass)
+	if err != nil {
+		return nil, err
+	}
+	cidrBlocks, err := p.vpcCIDRBlocks(vpcID)
+	if err != nil {
+		return nil, fmt.Errorf("getting cidr blocks of vpc %s, %w", vpcID, err)
+	}
+	if securityGroup == nil {
+		securityGroupID, err := p.createManaged(ctx, nodeClass, vpcID)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.authorizeManaged(securityGroupID, cidrBlocks); err != nil {
+			return nil, err
+		}
+		log.FromContext(ctx).WithValues("security-group", securityGroupID, "vpc", vpcID).Info("created managed security group")
+		if securityGroup, err = p.getManaged(ctx, nodeClass); err != nil {
+			return nil, err
+		} else if securityGroup == nil {
+			return nil, fmt.Errorf("security group %s not found after creation", securityGroupID)
+		}
+	} else if err := p.authorizeManaged(lo.FromPtr(securityGroup.SecurityGroupId), cidrBlocks); err != nil {
+		return nil, err
+	}
+	p.cache.SetDefault(managedCacheKey(nodeClass), securityGroup)
+	return securityGroup, nil
+}
+
+func (p *DefaultProvider) createManaged(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, vpcID string) (string, error) {
+	output, err := p.ecsapi.CreateSecurityGroupWithOptions(&ecs.CreateSecurityGroupRequest{
+		RegionId:          tea.String(p.region),
+		VpcId:             tea.String(vpcID),
+		SecurityGroupName: tea.String(fmt.Sprintf("karpenter-%s-%s", options.FromContext(ctx).ClusterName, nodeClass.Name)),
+		Description:       tea.String(fmt.Sprintf("Managed by Karpenter for the ECSNodeClass %s", nodeClass.Name)),
+		// Idempotent across retries of the same ECSNodeClass
+		ClientToken:     tea.String(string(nodeClass.UID)),
+		ResourceGroupId: nodeClass.Spec.ResourceGroupID,
+		Tag: lo.MapToSlice(managedTags(ctx, nodeClass), func(k, v string) *ecs.CreateSecurityGroupRequestTag {
+			return &ecs.CreateSecurityGroupRequestTag{Key: tea.String(k), Value: tea.String(v)}
+		}),
+	}, &util.RuntimeOptions{})
+	if err != nil {
+		return "", fmt.Errorf("creating security group, %w", err)
+	} else if output.Body == nil || output.Body.SecurityGroupId == nil {
+		return "", fmt.Errorf("unexpected null value was returned")
+	}
+	return lo.FromPtr(output.Body.SecurityGroupId), nil
+}
+
+// authorizeManaged accepts the traffic of the VPC CIDR blocks, authorizing existing rules again succeeds without
+// adding duplicates
+func (p *DefaultProvider) authorizeManaged(securityGroupID string, cidrBlocks []string) error {
+	if _, err := p.ecsapi.AuthorizeSecurityGroupWithOptions(&ecs.AuthorizeSecurityGroupRequest{
+		RegionId:        tea.String(p.region),
+		SecurityGroupId: tea.String(securityGroupID),
+		Permissions: lo.Map(cidrBlocks, func(cidrBlock string, _ int) *ecs.AuthorizeSecurityGroupRequestPermissions {
+			return &ecs.AuthorizeSecurityGroupRequestPermissions{
+				IpProtocol:   tea.String("ALL"),
+				PortRange:    tea.String("-1/-1"),
+				SourceCidrIp: tea.String(cidrBlock),
+				Policy:       tea.String("accept"),
+				Priority:     tea.String("1"),
+				Description:  tea.String("intra-cluster traffic"),
+			}
+		}),
+	}, &util.RuntimeOptions{}); err != nil {
+		return fmt.Errorf("authorizing security group %s, %w", securityGroupID, err)
+	}
+	return nil
+}
+
+// DeleteManaged deletes the security group owned by the ECSNodeClass, it returns an error if instances still use it
+func (p *DefaultProvider) DeleteManaged(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) error {
+	p.Lock()
+	defer p.Unlock()
+
+	p.cache.Delete(managedCacheKey(nodeClass))
+	securityGroup, err := p.getManaged(ctx, nodeClass)
+	if err != nil || securityGroup == nil {
+		return err
+	}
+	securityGroupID := lo.FromPtr(securityGroup.SecurityGroupId)
+	if count := lo.FromPtr(securityGroup.EcsCount); count > 0 {
+		return fmt.Errorf("security group %s is still used by %d instances", securityGroupID, count)
+	}
+	if _, err := p.ecsapi.DeleteSecurityGroupWithOptions(&ecs.DeleteSecurityGroupRequest{
+		RegionId:        tea.String(p.region),
+		SecurityGroupId: tea.String(securityGroupID),
+	}, &util.RuntimeOptions{}); err != nil {
+		return fmt.Errorf("deleting security group %s, %w", securityGroupID, err)
+	}
+	log.FromContext(ctx).WithValues("security-group", securityGroupID).Info("deleted managed security group")
+	return nil
+}
+
+// getManaged describes the security group owned by the ECSNodeClass, it returns nil if there is none
+func (p *DefaultProvider) getManaged(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (*ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, error) {
+	var securityGroups []*ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup
+	request := &ecs.DescribeSecurityGroupsRequest{
+		Tag: lo.MapToSlice(managedTags(ctx, nodeClass), func(k, v string) *ecs.DescribeSecurityGroupsRequestTag {
+			return &ecs.DescribeSecurityGroupsRequestTag{Key: tea.String(k), Value: tea.String(v)}
+		}),
+	}
+	if err := p.describeSecurityGroups(request, func(securityGroup *ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup) {
+		p.trackAvailableCapacity(securityGroup)
+		securityGroups = append(securityGroups, securityGroup)
+	}); err != nil {
+		return nil, fmt.Errorf("describing managed security groups, %w", err)
+	}
+	if len(securityGroups) == 0 {
+		return nil, nil
+	}
+	if len(securityGroups) > 1 {
+		return nil, fmt.Errorf("found multiple managed security groups %v", lo.Map(securityGroups, func(s *ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, _ int) string {
+			return lo.FromPtr(s.SecurityGroupId)
+		}))
+	}
+	return securityGroups[0], nil
+}
+
+func (p *DefaultProvider) vpcCIDRBlocks(vpcID string) ([]string, error) {
+	output, err := p.vpcapi.DescribeVpcsWithOptions(&vpc.DescribeVpcsRequest{
+		RegionId: tea.String(p.region),
+		VpcId:    tea.String(vpcID),
+	}, &util.RuntimeOptions{})
+	if err != nil {
+		return nil, err
+	} else if output.Body == nil || output.Body.Vpcs == nil || len(output.Body.Vpcs.Vpc) == 0 {
+		return nil, fmt.Errorf("vpc %s not found", vpcID)
+	}
+	v := output.Body.Vpcs.Vpc[0]
+	cidrBlocks := []string{lo.FromPtr(v.CidrBlock)}
+	if v.SecondaryCidrBlocks != nil {
+		cidrBlocks = append(cidrBlocks, lo.FromSlicePtr(v.SecondaryCidrBlocks.SecondaryCidrBlock)...)
+	}
+	return lo.Compact(cidrBlocks), nil
+}
+
+func managedTags(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) map[string]string {
+	return map[string]string{
+		fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName): "owned",
+		v1alpha1.LabelNodeClass: nodeClass.Name,
+	}
+}
+
+func managedCacheKey(nodeClass *v1alpha1.ECSNodeClass) string {
+	return fmt.Sprintf("managed/%s", nodeClass.Name)
+}
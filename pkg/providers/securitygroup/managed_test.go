@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitygroup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	vpc "github.com/alibabacloud-go/vpc-20160428/v6/client"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
+)
+
+// fakeECSAPI keeps the created security groups in memory, the embedded interface panics on any other call
+type fakeECSAPI struct {
+	client.ECSAPI
+	securityGroups []*ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup
+	authorizeErr   error
+	authorized     []string
+	created        int
+}
+
+func (f *fakeECSAPI) CreateSecurityGroupWithOptions(request *ecs.CreateSecurityGroupRequest, _ *util.RuntimeOptions) (*ecs.CreateSecurityGroupResponse, error) {
+	f.created++
+	f.securityGroups = append(f.securityGroups, &ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup{
+		SecurityGroupId: tea.String("sg-managed"),
+		VpcId:           request.VpcId,
+	})
+	return &ecs.CreateSecurityGroupResponse{Body: &ecs.CreateSecurityGroupResponseBody{SecurityGroupId: tea.String("sg-managed")}}, nil
+}
+
+func (f *fakeECSAPI) AuthorizeSecurityGroupWithOptions(request *ecs.AuthorizeSecurityGroupRequest, _ *util.RuntimeOptions) (*ecs.AuthorizeSecurityGroupResponse, error) {
+	if f.authorizeErr != nil {
+		return nil, f.authorizeErr
+	}
+	f.authorized = append(f.authorized, lo.FromPtr(request.SecurityGroupId))
+	return &ecs.AuthorizeSecurityGroupResponse{}, nil
+}
+
+func (f *fakeECSAPI) DescribeSecurityGroupsWithOptions(_ *ecs.DescribeSecurityGroupsRequest, _ *util.RuntimeOptions) (*ecs.DescribeSecurityGroupsResponse, error) {
+	return &ecs.DescribeSecurityGroupsResponse{Body: &ecs.DescribeSecurityGroupsResponseBody{
+		SecurityGroups: &ecs.DescribeSecurityGroupsResponseBodySecurityGroups{SecurityGroup: f.securityGroups},
+	}}, nil
+}
+
+type fakeVPCAPI struct {
+	client.VPCAPI
+}
+
+func (f *fakeVPCAPI) DescribeVpcsWithOptions(request *vpc.DescribeVpcsRequest, _ *util.RuntimeOptions) (*vpc.DescribeVpcsResponse, error) {
+	return &vpc.DescribeVpcsResponse{Body: &vpc.DescribeVpcsResponseBody{Vpcs: &vpc.DescribeVpcsResponseBodyVpcs{
+		Vpc: []*vpc.DescribeVpcsResponseBodyVpcsVpc{{VpcId: request.VpcId, CidrBlock: tea.String("192.168.0.0/16")}},
+	}}}, nil
+}
+
+func TestEnsureManagedAuthorizesAfterFailure(t *testing.T) {
+	ctx := options.ToContext(context.Background(), &options.Options{ClusterName: "cluster"})
+	ecsapi := &fakeECSAPI{authorizeErr: errors.New("throttled")}
+	p := NewDefaultProvider("cn-hangzhou", ecsapi, &fakeVPCAPI{},
+		cache.New(cache.NoExpiration, cache.NoExpiration), cache.New(cache.NoExpiration, cache.NoExpiration))
+	nodeClass := &v1alpha1.ECSNodeClass{ObjectMeta: metav1.ObjectMeta{Name: "default", UID: "uid"}}
+
+	if _, err := p.EnsureManaged(ctx, nodeClass, "vpc-1"); err == nil {
+		t.Fatalf("ensuring managed security group, want error when the authorization fails")
+	}
+	if ecsapi.created != 1 {
+		t.Fatalf("created %d security groups, want 1", ecsapi.created)
+	}
+
+	// The group left behind is found again and authorized instead of returned without rules
+	ecsapi.authorizeErr = nil
+	securityGroup, err := p.EnsureManaged(ctx, nodeClass, "vpc-1")
+	if err != nil {
+		t.Fatalf("ensuring managed security group, %v", err)
+	}
+	if got := lo.FromPtr(securityGroup.SecurityGroupId); got != "sg-managed" {
+		t.Errorf("managed security group = %s, want sg-managed", got)
+	}
+	if ecsapi.created != 1 {
+		t.Errorf("created %d security groups, want 1", ecsapi.created)
+	}
+	if len(ecsapi.authorized) != 1 || ecsapi.authorized[0] != "sg-managed" {
+		t.Errorf("authorized security groups = %v, want [sg-managed]", ecsapi.authorized)
+	}
+
+	// Cached groups aren't authorized again
+	if _, err := p.EnsureManaged(ctx, nodeClass, "vpc-1"); err != nil {
+		t.Fatalf("ensuring managed security group, %v", err)
+	}
+	if len(ecsapi.authorized) != 1 {
+		t.Errorf("authorized %d times, want 1", len(ecsapi.authorized))
+	}
+}
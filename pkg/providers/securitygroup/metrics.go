@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitygroup
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	securityGroupSubsystem = "security_groups"
+	securityGroupIDLabel   = "security_group_id"
+)
+
+var (
+	SecurityGroupAvailableCapacity = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: securityGroupSubsystem,
+			Name:      "available_instance_amount",
+			Help:      "Number of instances that can still be added to the security group, including in-flight launches.",
+		},
+		[]string{securityGroupIDLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(SecurityGroupAvailableCapacity)
+}
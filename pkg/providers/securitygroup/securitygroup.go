@@ -19,6 +19,7 @@ package securitygroup
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
@@ -28,32 +29,48 @@ import (
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
 )
 
+// LowCapacityThreshold is the remaining instance capacity at which a security group is considered close to full
+const LowCapacityThreshold = 50
+
 type Provider interface {
 	List(context.Context, *v1alpha1.ECSNodeClass) ([]*ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, error)
+	ReserveCapacityForLaunch(context.Context, []string) error
+	UpdateInflightCapacity([]string, bool)
+	EnsureManaged(context.Context, *v1alpha1.ECSNodeClass, string) (*ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, error)
+	DeleteManaged(context.Context, *v1alpha1.ECSNodeClass) error
 }
 
 type DefaultProvider struct {
 	sync.Mutex
-	region string
-	ecsapi *ecs.Client
-	cache  *cache.Cache
-	cm     *pretty.ChangeMonitor
-	// TODO: Alibaba Cloud security groups have a limit on the number of IP addresses, may need to prevent miss
-	// And the available IPs returned by the API are not real-time. It is likely that an IP cache like VSwitchProvider will be needed later.
+	region                 string
+	ecsapi                 client.ECSAPI
+	vpcapi                 client.VPCAPI
+	cache                  *cache.Cache
+	availableCapacityCache *cache.Cache
+	cm                     *pretty.ChangeMonitor
+	// Alibaba Cloud security groups limit the number of instances they can contain, and the
+	// AvailableInstanceAmount returned by the API is not real-time, so launches are tracked in memory
+	inflightCapacity map[string]int32
 }
 
-func NewDefaultProvider(region string, ecsapi *ecs.Client, cache *cache.Cache) *DefaultProvider {
+func NewDefaultProvider(region string, ecsapi client.ECSAPI, vpcapi client.VPCAPI, cache *cache.Cache, availableCapacityCache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
 		region: region,
 		ecsapi: ecsapi,
+		vpcapi: vpcapi,
 		cm:     pretty.NewChangeMonitor(),
 		// TODO: Remove cache cache when we utilize the security groups from the ECSNodeClass.status
-		cache: cache,
+		cache:                  cache,
+		availableCapacityCache: availableCapacityCache,
+		// inflightCapacity is used to track the capacity consumed by known launched instances
+		inflightCapacity: map[string]int32{},
 	}
 }
 
@@ -78,6 +95,79 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1alpha1.ECSNodeC
 	return securityGroups, nil
 }
 
+// ReserveCapacityForLaunch deducts one instance from the tracked capacity of the passed security groups. Instances
+// join every security group of the launch, so it returns an insufficient capacity error without deducting anything
+// if any of the security groups is full.
+func (p *DefaultProvider) ReserveCapacityForLaunch(ctx context.Context, securityGroupIDs []string) error {
+	p.Lock()
+	defer p.Unlock()
+
+	available := map[string]int32{}
+	for _, id := range securityGroupIDs {
+		capacity, ok := p.availableCapacity(id)
+		if !ok {
+			// Capacity is unknown until the security group has been described, don't block on it
+			continue
+		}
+		available[id] = capacity
+	}
+	full := lo.Filter(securityGroupIDs, func(id string, _ int) bool {
+		capacity, ok := available[id]
+		return ok && capacity <= 0
+	})
+	if len(full) > 0 {
+		return cloudprovider.NewInsufficientCapacityError(fmt.Errorf("security groups %s have no capacity available for new instances", strings.Join(full, ", ")))
+	}
+	for id, capacity := range available {
+		p.inflightCapacity[id] = capacity - 1
+		SecurityGroupAvailableCapacity.WithLabelValues(id).Set(float64(capacity - 1))
+		if capacity-1 <= LowCapacityThreshold {
+			log.FromContext(ctx).WithValues("security-group", id, "available", capacity-1).Info("security group is close to its instance limit")
+		}
+	}
+	return nil
+}
+
+// UpdateInflightCapacity is used to refresh the in-memory capacity by adding back the reserved
+// capacity after a launch which didn't result in an instance
+func (p *DefaultProvider) UpdateInflightCapacity(securityGroupIDs []string, launched bool) {
+	if launched {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+
+	for _, id := range securityGroupIDs {
+		capacity, ok := p.inflightCapacity[id]
+		if !ok {
+			continue
+		}
+		p.inflightCapacity[id] = capacity + 1
+		SecurityGroupAvailableCapacity.WithLabelValues(id).Set(float64(capacity + 1))
+	}
+}
+
+// availableCapacity returns the tracked capacity of a security group, preferring the in-flight accounting over the last API response
+func (p *DefaultProvider) availableCapacity(id string) (int32, bool) {
+	if capacity, ok := p.inflightCapacity[id]; ok {
+		return capacity, true
+	}
+	if capacity, ok := p.availableCapacityCache.Get(id); ok {
+		return capacity.(int32), true
+	}
+	return 0, false
+}
+
+// trackAvailableCapacity records the capacity of a freshly described security group
+func (p *DefaultProvider) trackAvailableCapacity(securityGroup *ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup) {
+	id := lo.FromPtr(securityGroup.SecurityGroupId)
+	if securityGroup.AvailableInstanceAmount != nil {
+		p.availableCapacityCache.SetDefault(id, lo.FromPtr(securityGroup.AvailableInstanceAmount))
+		SecurityGroupAvailableCapacity.WithLabelValues(id).Set(float64(lo.FromPtr(securityGroup.AvailableInstanceAmount)))
+	}
+	delete(p.inflightCapacity, id) // remove any previously tracked capacity since we just refreshed from ECS
+}
+
 func (p *DefaultProvider) getSecurityGroups(filterSets []*ecs.DescribeSecurityGroupsRequest) ([]*ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, error) {
 	hash, err := hashstructure.Hash(filterSets, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	if err != nil {
@@ -92,6 +182,7 @@ func (p *DefaultProvider) getSecurityGroups(filterSets []*ecs.DescribeSecurityGr
 	for _, filter := range filterSets {
 		if err := p.describeSecurityGroups(filter, func(securityGroup *ecs.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup) {
 			securityGroups[lo.FromPtr(securityGroup.SecurityGroupId)] = securityGroup
+			p.trackAvailableCapacity(securityGroup)
 		}); err != nil {
 			return nil, fmt.Errorf("describing security groups %+v, %w", filter, err)
 		}
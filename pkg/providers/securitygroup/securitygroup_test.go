@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitygroup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patrickmn/go-cache"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+func TestReserveCapacityForLaunch(t *testing.T) {
+	availableCapacityCache := cache.New(cache.NoExpiration, cache.NoExpiration)
+	availableCapacityCache.SetDefault("sg-full", int32(0))
+	availableCapacityCache.SetDefault("sg-available", int32(1))
+	p := NewDefaultProvider("cn-hangzhou", nil, nil, cache.New(cache.NoExpiration, cache.NoExpiration), availableCapacityCache)
+
+	// Instances join every security group, a single full group fails the launch
+	if err := p.ReserveCapacityForLaunch(context.Background(), []string{"sg-full", "sg-available", "sg-unknown"}); !cloudprovider.IsInsufficientCapacityError(err) {
+		t.Errorf("reserving capacity with a full security group, got error %v, want insufficient capacity", err)
+	}
+
+	// The failed reservation didn't deduct anything, the last instance of sg-available is still there
+	if err := p.ReserveCapacityForLaunch(context.Background(), []string{"sg-available", "sg-unknown"}); err != nil {
+		t.Fatalf("reserving capacity, %v", err)
+	}
+	if err := p.ReserveCapacityForLaunch(context.Background(), []string{"sg-available", "sg-unknown"}); !cloudprovider.IsInsufficientCapacityError(err) {
+		t.Errorf("reserving capacity of a full security group, got error %v, want insufficient capacity", err)
+	}
+
+	// A failed launch gives the capacity back
+	p.UpdateInflightCapacity([]string{"sg-available", "sg-unknown"}, false)
+	if err := p.ReserveCapacityForLaunch(context.Background(), []string{"sg-available", "sg-unknown"}); err != nil {
+		t.Errorf("reserving capacity after a failed launch, %v", err)
+	}
+}
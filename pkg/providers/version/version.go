@@ -31,7 +31,8 @@ import (
 )
 
 const (
-	kubernetesVersionCacheKey = "kubernetesVersion"
+	kubernetesVersionCacheKey    = "kubernetesVersion"
+	kubernetesGitVersionCacheKey = "kubernetesGitVersion"
 	// Karpenter's supported version of Kubernetes
 	// If a user runs a karpenter image on a k8s version outside the min and max,
 	// One error message will be fired to notify
@@ -41,6 +42,8 @@ const (
 
 type Provider interface {
 	Get(ctx context.Context) (string, error)
+	// GetGitVersion returns the full release of the API server without the "v" prefix, e.g. 1.30.1-aliyun.1
+	GetGitVersion(ctx context.Context) (string, error)
 }
 
 // DefaultProvider get the APIServer version. This will be initialized at start up and allows karpenter to have an understanding of the cluster version
@@ -78,6 +81,19 @@ func (p *DefaultProvider) Get(ctx context.Context) (string, error) {
 	return version, nil
 }
 
+func (p *DefaultProvider) GetGitVersion(_ context.Context) (string, error) {
+	if version, ok := p.cache.Get(kubernetesGitVersionCacheKey); ok {
+		return version.(string), nil
+	}
+	serverVersion, err := p.kubernetesInterface.Discovery().ServerVersion()
+	if err != nil {
+		return "", err
+	}
+	version := strings.TrimPrefix(serverVersion.GitVersion, "v")
+	p.cache.SetDefault(kubernetesGitVersionCacheKey, version)
+	return version, nil
+}
+
 func validateK8sVersion(v string) error {
 	k8sVersion := version.MustParseGeneric(v)
 
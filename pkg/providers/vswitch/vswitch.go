@@ -19,7 +19,9 @@ package vswitch
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
 
 	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
@@ -37,6 +39,7 @@ import (
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/client"
 )
 
 type Provider interface {
@@ -48,7 +51,7 @@ type Provider interface {
 
 type DefaultProvider struct {
 	sync.Mutex
-	vpcapi                  *vpc.Client
+	vpcapi                  client.VPCAPI
 	cache                   *cache.Cache
 	availableIPAddressCache *cache.Cache
 	cm                      *pretty.ChangeMonitor
@@ -61,7 +64,7 @@ type VSwitch struct {
 	AvailableIPAddressCount int64
 }
 
-func NewDefaultProvider(vpcapi *vpc.Client, cache *cache.Cache, availableIPAddressCache *cache.Cache) *DefaultProvider {
+func NewDefaultProvider(vpcapi client.VPCAPI, cache *cache.Cache, availableIPAddressCache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
 		vpcapi: vpcapi,
 		cm:     pretty.NewChangeMonitor(),
@@ -93,25 +96,14 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1alpha1.ECSNodeC
 
 	// Ensure that all the vSwitches that are returned here are unique
 	vSwitches := map[string]*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch{}
-	for _, selectorTerms := range nodeClass.Spec.VSwitchSelectorTerms {
-		var tags []*vpc.DescribeVSwitchesRequestTag
-		var vSwitchID *string
-
-		if len(selectorTerms.ID) > 0 {
-			vSwitchID = tea.String(selectorTerms.ID)
-		}
-		for k, v := range selectorTerms.Tags {
-			// Value: nil selector all switches, '' selector specify switch
-			tag := &vpc.DescribeVSwitchesRequestTag{Key: tea.String(k)}
-			if v != "*" {
-				tag.Value = tea.String(v)
-			}
-			tags = append(tags, tag)
-		}
-
+	for _, selectorTerm := range nodeClass.Spec.VSwitchSelectorTerms {
 		// API Rate Limits: 360/60(s), Max selector items: 30
 		// TODO: additional rate limits
-		if err = p.describeVSwitches(tags, vSwitchID, func(vSwitch *vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch) {
+		if err = p.describeVSwitches(getDescribeVSwitchesRequest(selectorTerm), func(vSwitch *vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch) {
+			// Name wildcards and CIDR blocks aren't supported by DescribeVSwitches, so they're filtered here
+			if !MatchesSelectorTerm(selectorTerm, vSwitch) {
+				return
+			}
 			vSwitches[lo.FromPtr(vSwitch.VSwitchId)] = vSwitch
 			// switches can be leaked here, if a switch is never called received from ecs
 			// we are accepting it for now, as this will be an insignificant amount of memory
@@ -119,7 +111,7 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1alpha1.ECSNodeC
 
 			delete(p.inflightIPs, lo.FromPtr(vSwitch.VSwitchId)) // remove any previously tracked IP addresses since we just refreshed from ECS
 		}); err != nil {
-			return nil, fmt.Errorf("describing vSwitches %s, %w", pretty.Concise(selectorTerms), err)
+			return nil, fmt.Errorf("describing vSwitches %s, %w", pretty.Concise(selectorTerm), err)
 		}
 	}
 
@@ -252,13 +244,9 @@ func (p *DefaultProvider) LivenessProbe(_ *http.Request) error {
 	return nil
 }
 
-func (p *DefaultProvider) describeVSwitches(tags []*vpc.DescribeVSwitchesRequestTag, id *string, process func(*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch)) error {
+func (p *DefaultProvider) describeVSwitches(describeVSwitchesRequest *vpc.DescribeVSwitchesRequest, process func(*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch)) error {
 	runtime := &util.RuntimeOptions{}
-	describeVSwitchesRequest := &vpc.DescribeVSwitchesRequest{
-		Tag:       tags,
-		VSwitchId: id,
-		PageSize:  tea.Int32(50),
-	}
+	describeVSwitchesRequest.PageSize = tea.Int32(50)
 	for pageNumber := int32(1); pageNumber < 360; pageNumber++ {
 		describeVSwitchesRequest.PageNumber = tea.Int32(pageNumber)
 		output, err := p.vpcapi.DescribeVSwitchesWithOptions(describeVSwitchesRequest, runtime)
@@ -279,6 +267,102 @@ func (p *DefaultProvider) describeVSwitches(tags []*vpc.DescribeVSwitchesRequest
 	return nil
 }
 
+func getDescribeVSwitchesRequest(term v1alpha1.VSwitchSelectorTerm) *vpc.DescribeVSwitchesRequest {
+	request := &vpc.DescribeVSwitchesRequest{}
+	if term.ID != "" {
+		request.VSwitchId = tea.String(term.ID)
+	}
+	// DescribeVSwitches only supports exact name matching, wildcards are matched client-side
+	if term.Name != "" && !strings.Contains(term.Name, "*") {
+		request.VSwitchName = tea.String(term.Name)
+	}
+	if term.VPCID != "" {
+		request.VpcId = tea.String(term.VPCID)
+	}
+	if term.ZoneID != "" {
+		request.ZoneId = tea.String(term.ZoneID)
+	}
+	for k, v := range term.Tags {
+		// Value: nil selector all switches, '' selector specify switch
+		tag := &vpc.DescribeVSwitchesRequestTag{Key: tea.String(k)}
+		if v != "*" {
+			tag.Value = tea.String(v)
+		}
+		request.Tag = append(request.Tag, tag)
+	}
+	return request
+}
+
+// MatchesSelectorTerm returns true if the vSwitch satisfies every field set on the selector term
+func MatchesSelectorTerm(term v1alpha1.VSwitchSelectorTerm, vSwitch *vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch) bool {
+	if term.ID != "" && term.ID != lo.FromPtr(vSwitch.VSwitchId) {
+		return false
+	}
+	if term.Name != "" && !matchesName(term.Name, lo.FromPtr(vSwitch.VSwitchName)) {
+		return false
+	}
+	if term.VPCID != "" && term.VPCID != lo.FromPtr(vSwitch.VpcId) {
+		return false
+	}
+	if term.ZoneID != "" && term.ZoneID != lo.FromPtr(vSwitch.ZoneId) {
+		return false
+	}
+	if term.CIDRBlock != "" && !cidrContains(term.CIDRBlock, lo.FromPtr(vSwitch.CidrBlock)) {
+		return false
+	}
+	if len(term.Tags) > 0 {
+		tags := map[string]string{}
+		if vSwitch.Tags != nil {
+			for _, tag := range vSwitch.Tags.Tag {
+				tags[lo.FromPtr(tag.Key)] = lo.FromPtr(tag.Value)
+			}
+		}
+		for k, v := range term.Tags {
+			value, ok := tags[k]
+			if !ok || (v != "*" && v != value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchesName returns true if the name matches the pattern, '*' matches any sequence of characters and every
+// other character only matches itself
+func matchesName(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return strings.HasSuffix(name, parts[len(parts)-1])
+}
+
+// cidrContains returns true if the subnet CIDR is fully contained within the parent CIDR
+func cidrContains(parent, subnet string) bool {
+	_, parentNet, err := net.ParseCIDR(parent)
+	if err != nil {
+		return false
+	}
+	_, subnetNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return false
+	}
+	parentOnes, _ := parentNet.Mask.Size()
+	subnetOnes, _ := subnetNet.Mask.Size()
+	return parentOnes <= subnetOnes && parentNet.Contains(subnetNet.IP)
+}
+
 func (p *DefaultProvider) minPods(instanceTypes []*cloudprovider.InstanceType, reqs scheduling.Requirements) int64 {
 	// filter for instance types available in the zone and capacity type being requested
 	filteredInstanceTypes := lo.Filter(instanceTypes, func(it *cloudprovider.InstanceType, _ int) bool {
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vswitch
+
+import (
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	vpc "github.com/alibabacloud-go/vpc-20160428/v6/client"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/apis/v1alpha1"
+)
+
+func TestMatchesSelectorTerm(t *testing.T) {
+	vSwitch := &vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch{
+		VSwitchId:   tea.String("vsw-1"),
+		VSwitchName: tea.String("karpenter-[a]?-h"),
+		VpcId:       tea.String("vpc-1"),
+		ZoneId:      tea.String("cn-hangzhou-h"),
+		CidrBlock:   tea.String("10.0.16.0/20"),
+	}
+
+	for _, tc := range []struct {
+		name    string
+		term    v1alpha1.VSwitchSelectorTerm
+		matches bool
+	}{
+		{name: "exact name", term: v1alpha1.VSwitchSelectorTerm{Name: "karpenter-[a]?-h"}, matches: true},
+		{name: "other name", term: v1alpha1.VSwitchSelectorTerm{Name: "karpenter-a1-h"}},
+		{name: "name with a wildcard", term: v1alpha1.VSwitchSelectorTerm{Name: "karpenter-*"}, matches: true},
+		{name: "name with wildcards", term: v1alpha1.VSwitchSelectorTerm{Name: "*[a]*-h"}, matches: true},
+		{name: "name with a wildcard for another prefix", term: v1alpha1.VSwitchSelectorTerm{Name: "default-*"}},
+		{name: "name with a wildcard for another suffix", term: v1alpha1.VSwitchSelectorTerm{Name: "karpenter-*-k"}},
+		{name: "name with glob syntax other than wildcards", term: v1alpha1.VSwitchSelectorTerm{Name: "karpenter-[ab]?-h"}},
+		{name: "malformed glob pattern", term: v1alpha1.VSwitchSelectorTerm{Name: "karpenter-[a"}},
+		{name: "vpc", term: v1alpha1.VSwitchSelectorTerm{VPCID: "vpc-1"}, matches: true},
+		{name: "other vpc", term: v1alpha1.VSwitchSelectorTerm{VPCID: "vpc-2"}},
+		{name: "zone", term: v1alpha1.VSwitchSelectorTerm{ZoneID: "cn-hangzhou-h"}, matches: true},
+		{name: "other zone", term: v1alpha1.VSwitchSelectorTerm{ZoneID: "cn-hangzhou-i"}},
+		{name: "same cidr", term: v1alpha1.VSwitchSelectorTerm{CIDRBlock: "10.0.16.0/20"}, matches: true},
+		{name: "containing cidr", term: v1alpha1.VSwitchSelectorTerm{CIDRBlock: "10.0.0.0/16"}, matches: true},
+		{name: "contained cidr", term: v1alpha1.VSwitchSelectorTerm{CIDRBlock: "10.0.16.0/24"}},
+		{name: "disjoint cidr", term: v1alpha1.VSwitchSelectorTerm{CIDRBlock: "10.1.0.0/16"}},
+		{name: "invalid cidr", term: v1alpha1.VSwitchSelectorTerm{CIDRBlock: "10.0.0.0"}},
+		{name: "all terms", term: v1alpha1.VSwitchSelectorTerm{Name: "karpenter-*", VPCID: "vpc-1", ZoneID: "cn-hangzhou-h", CIDRBlock: "10.0.0.0/8"}, matches: true},
+		{name: "all terms but one", term: v1alpha1.VSwitchSelectorTerm{Name: "karpenter-*", VPCID: "vpc-1", ZoneID: "cn-hangzhou-i", CIDRBlock: "10.0.0.0/8"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if matches := MatchesSelectorTerm(tc.term, vSwitch); matches != tc.matches {
+				t.Errorf("MatchesSelectorTerm() = %t, want %t", matches, tc.matches)
+			}
+		})
+	}
+}
+
+func TestMatchesName(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, name string
+		matches       bool
+	}{
+		{pattern: "*", name: "", matches: true},
+		{pattern: "*", name: "karpenter", matches: true},
+		{pattern: "karpenter", name: "karpenter", matches: true},
+		{pattern: "karpenter", name: "karpenter-a"},
+		{pattern: "karpenter*", name: "karpenter", matches: true},
+		{pattern: "*-a", name: "karpenter-a", matches: true},
+		{pattern: "a*a", name: "a"},
+		{pattern: "a*a", name: "aa", matches: true},
+		{pattern: "k*-*-a", name: "karpenter-b-a", matches: true},
+		{pattern: "k*-*-a", name: "karpenter-a"},
+		{pattern: `karpenter-\a`, name: "karpenter-a"},
+	} {
+		if matches := matchesName(tc.pattern, tc.name); matches != tc.matches {
+			t.Errorf("matchesName(%q, %q) = %t, want %t", tc.pattern, tc.name, matches, tc.matches)
+		}
+	}
+}
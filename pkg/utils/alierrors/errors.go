@@ -18,17 +18,145 @@ package alierrors
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
 )
 
+// Kind classifies Alibaba Cloud API errors by how callers should react to them
+type Kind string
+
+const (
+	KindUnknown              Kind = "Unknown"
+	KindNotFound             Kind = "NotFound"
+	KindThrottling           Kind = "Throttling"
+	KindInsufficientCapacity Kind = "InsufficientCapacity"
+	KindQuotaExceeded        Kind = "QuotaExceeded"
+	KindForbidden            Kind = "Forbidden"
+	KindInvalidParameter     Kind = "InvalidParameter"
+	KindIdempotency          Kind = "Idempotency"
+	KindServiceUnavailable   Kind = "ServiceUnavailable"
+)
+
+// Ref: https://api.aliyun.com/document/Ecs/2014-05-26/errorCode
+var (
+	codeKinds = map[string]Kind{
+		"OperationDenied.NoStock":          KindInsufficientCapacity,
+		"Zone.NotOnSale":                   KindInsufficientCapacity,
+		"InvalidResourceType.NotSupported": KindInsufficientCapacity,
+		"NoPermission":                     KindForbidden,
+		"InvalidAccessKeyId.NotFound":      KindForbidden,
+		"SignatureDoesNotMatch":            KindForbidden,
+		"MissingParameter":                 KindInvalidParameter,
+		"IdempotentParameterMismatch":      KindIdempotency,
+		"IdempotentProcessing":             KindIdempotency,
+		"LastTokenProcessing":              KindIdempotency,
+		"ServiceUnavailable":               KindServiceUnavailable,
+		"InternalError":                    KindServiceUnavailable,
+		"UnknownError":                     KindServiceUnavailable,
+	}
+	// codePrefixKinds are checked in order when a code has no exact match, e.g. Throttling.User or QuotaExceed.PostPaidInstance
+	codePrefixKinds = []lo.Entry[string, Kind]{
+		{Key: "Throttling", Value: KindThrottling},
+		{Key: "QuotaExceed", Value: KindQuotaExceeded},
+		{Key: "Forbidden", Value: KindForbidden},
+		{Key: "InvalidParameter", Value: KindInvalidParameter},
+		{Key: "ServiceUnavailable", Value: KindServiceUnavailable},
+	}
+	// retriableCodes are idempotency errors of a request that is still being processed, the same request can be retried
+	retriableCodes = []string{"IdempotentProcessing", "LastTokenProcessing"}
+)
+
+// New returns an Alibaba Cloud API error of the code, for failures that are reported in the body of a successful
+// response, so that they are classified like failed requests
+func New(code, message string) error {
+	return &tea.SDKError{Code: tea.String(code), Message: tea.String(message)}
+}
+
+// Code returns the error code of an Alibaba Cloud API error, or an empty string for any other error
+func Code(err error) string {
+	var sdkError *tea.SDKError
+	if errors.As(err, &sdkError) {
+		return lo.FromPtr(sdkError.Code)
+	}
+	return ""
+}
+
+// Classify returns the kind of an Alibaba Cloud API error, errors that aren't returned by the API are KindUnknown
+func Classify(err error) Kind {
+	var sdkError *tea.SDKError
+	if !errors.As(err, &sdkError) {
+		return KindUnknown
+	}
+	code := lo.FromPtr(sdkError.Code)
+	if kind, ok := codeKinds[code]; ok {
+		return kind
+	}
+	for _, prefix := range codePrefixKinds {
+		if strings.HasPrefix(code, prefix.Key) {
+			return prefix.Value
+		}
+	}
+	switch statusCode := lo.FromPtr(sdkError.StatusCode); {
+	case strings.HasSuffix(code, ".NotFound"), statusCode == 404:
+		return KindNotFound
+	case statusCode == 403:
+		return KindForbidden
+	case statusCode >= 500:
+		return KindServiceUnavailable
+	}
+	return KindUnknown
+}
+
 func IsNotFound(err error) bool {
 	var sdkError *tea.SDKError
 	if errors.As(err, &sdkError) {
-		if *sdkError.StatusCode == 404 {
+		if lo.FromPtr(sdkError.StatusCode) == 404 {
 			return true
 		}
 	}
 
 	return false
 }
+
+// IsThrottling returns true if the request was rejected by the API rate limits
+func IsThrottling(err error) bool {
+	return Classify(err) == KindThrottling
+}
+
+// IsInsufficientCapacity returns true if the requested instances are out of stock
+func IsInsufficientCapacity(err error) bool {
+	return Classify(err) == KindInsufficientCapacity
+}
+
+// IsQuotaExceeded returns true if the request would exceed a quota of the account
+func IsQuotaExceeded(err error) bool {
+	return Classify(err) == KindQuotaExceeded
+}
+
+// IsForbidden returns true if the credentials aren't valid or lack the RAM permissions for the request
+func IsForbidden(err error) bool {
+	return Classify(err) == KindForbidden
+}
+
+// IsInvalidParameter returns true if the request was malformed, usually because of an invalid configuration
+func IsInvalidParameter(err error) bool {
+	return Classify(err) == KindInvalidParameter
+}
+
+// IsIdempotencyConflict returns true if the client token of the request was already used
+func IsIdempotencyConflict(err error) bool {
+	return Classify(err) == KindIdempotency
+}
+
+// IsRetriable returns true if the same request may succeed when retried later
+func IsRetriable(err error) bool {
+	switch Classify(err) {
+	case KindThrottling, KindServiceUnavailable:
+		return true
+	case KindIdempotency:
+		return lo.Contains(retriableCodes, Code(err))
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alierrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+func sdkError(code string, statusCode int) error {
+	return tea.NewSDKError(map[string]interface{}{
+		"code":       code,
+		"statusCode": statusCode,
+		"message":    code,
+	})
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind Kind
+	}{
+		{name: "nil", err: nil, kind: KindUnknown},
+		{name: "non sdk error", err: errors.New("boom"), kind: KindUnknown},
+		{name: "throttling", err: sdkError("Throttling", 400), kind: KindThrottling},
+		{name: "user throttling", err: sdkError("Throttling.User", 400), kind: KindThrottling},
+		{name: "api throttling", err: sdkError("Throttling.Api", 400), kind: KindThrottling},
+		{name: "no stock", err: sdkError("OperationDenied.NoStock", 403), kind: KindInsufficientCapacity},
+		{name: "zone not on sale", err: sdkError("Zone.NotOnSale", 403), kind: KindInsufficientCapacity},
+		{name: "resource type not supported", err: sdkError("InvalidResourceType.NotSupported", 403), kind: KindInsufficientCapacity},
+		{name: "postpaid quota", err: sdkError("QuotaExceed.PostPaidInstance", 403), kind: KindQuotaExceeded},
+		{name: "spot quota", err: sdkError("QuotaExceed.SpotInstance", 403), kind: KindQuotaExceeded},
+		{name: "private ip quota", err: sdkError("QuotaExceeded.PrivateIpAddress", 403), kind: KindQuotaExceeded},
+		{name: "ram forbidden", err: sdkError("Forbidden.RAM", 403), kind: KindForbidden},
+		{name: "no permission", err: sdkError("NoPermission", 403), kind: KindForbidden},
+		{name: "invalid access key", err: sdkError("InvalidAccessKeyId.NotFound", 404), kind: KindForbidden},
+		{name: "unknown forbidden", err: sdkError("OperationDenied", 403), kind: KindForbidden},
+		{name: "invalid parameter", err: sdkError("InvalidParameter", 400), kind: KindInvalidParameter},
+		{name: "invalid parameter detail", err: sdkError("InvalidParameter.Mismatch", 400), kind: KindInvalidParameter},
+		{name: "missing parameter", err: sdkError("MissingParameter", 400), kind: KindInvalidParameter},
+		{name: "idempotent mismatch", err: sdkError("IdempotentParameterMismatch", 400), kind: KindIdempotency},
+		{name: "idempotent processing", err: sdkError("IdempotentProcessing", 400), kind: KindIdempotency},
+		{name: "not found code", err: sdkError("InvalidInstanceId.NotFound", 400), kind: KindNotFound},
+		{name: "not found status", err: sdkError("EntityNotExist", 404), kind: KindNotFound},
+		{name: "internal error", err: sdkError("InternalError", 500), kind: KindServiceUnavailable},
+		{name: "service unavailable", err: sdkError("ServiceUnavailable", 503), kind: KindServiceUnavailable},
+		{name: "unknown server error", err: sdkError("Unexpected", 502), kind: KindServiceUnavailable},
+		{name: "unknown client error", err: sdkError("Unexpected", 400), kind: KindUnknown},
+		{name: "wrapped", err: fmt.Errorf("creating instance, %w", sdkError("Throttling.User", 400)), kind: KindThrottling},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kind := Classify(tt.err); kind != tt.kind {
+				t.Errorf("Classify() = %s, want %s", kind, tt.kind)
+			}
+		})
+	}
+}
+
+func TestIsRetriable(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retriable bool
+	}{
+		{name: "nil", err: nil, retriable: false},
+		{name: "throttling", err: sdkError("Throttling.User", 400), retriable: true},
+		{name: "service unavailable", err: sdkError("ServiceUnavailable", 503), retriable: true},
+		{name: "idempotent processing", err: sdkError("IdempotentProcessing", 400), retriable: true},
+		{name: "last token processing", err: sdkError("LastTokenProcessing", 400), retriable: true},
+		{name: "idempotent mismatch", err: sdkError("IdempotentParameterMismatch", 400), retriable: false},
+		{name: "no stock", err: sdkError("OperationDenied.NoStock", 403), retriable: false},
+		{name: "invalid parameter", err: sdkError("InvalidParameter", 400), retriable: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if retriable := IsRetriable(tt.err); retriable != tt.retriable {
+				t.Errorf("IsRetriable() = %t, want %t", retriable, tt.retriable)
+			}
+		})
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	tests := []struct {
+		name      string
+		predicate func(error) bool
+		match     error
+		other     error
+	}{
+		{name: "IsNotFound", predicate: IsNotFound, match: sdkError("InvalidInstanceId.NotFound", 404), other: sdkError("InvalidParameter", 400)},
+		{name: "IsThrottling", predicate: IsThrottling, match: sdkError("Throttling", 400), other: sdkError("ServiceUnavailable", 503)},
+		{name: "IsInsufficientCapacity", predicate: IsInsufficientCapacity, match: sdkError("OperationDenied.NoStock", 403), other: sdkError("QuotaExceed.SpotInstance", 403)},
+		{name: "IsQuotaExceeded", predicate: IsQuotaExceeded, match: sdkError("QuotaExceed.SpotInstance", 403), other: sdkError("OperationDenied.NoStock", 403)},
+		{name: "IsForbidden", predicate: IsForbidden, match: sdkError("Forbidden.RAM", 403), other: sdkError("InvalidParameter", 400)},
+		{name: "IsInvalidParameter", predicate: IsInvalidParameter, match: sdkError("InvalidParameter.Mismatch", 400), other: sdkError("Forbidden.RAM", 403)},
+		{name: "IsIdempotencyConflict", predicate: IsIdempotencyConflict, match: sdkError("IdempotentParameterMismatch", 400), other: sdkError("InvalidParameter", 400)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.predicate(tt.match) {
+				t.Errorf("%s(%v) = false, want true", tt.name, tt.match)
+			}
+			if tt.predicate(tt.other) {
+				t.Errorf("%s(%v) = true, want false", tt.name, tt.other)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ackAPIVersion is the version of the ACK (Container Service for Kubernetes) API, which has no Go SDK in the module
+// graph, so requests are sent with the generic OpenAPI client
+const ackAPIVersion = "2015-12-15"
+
+// ACKAPI is the subset of the ACK API used by the operator
+type ACKAPI interface {
+	DescribeClusterDetail(clusterID string) (*ACKCluster, error)
+	DescribeClusters(name string) ([]*ACKCluster, error)
+	// AttachInstancesToNodePool returns the ID of the task attaching the instances
+	AttachInstancesToNodePool(clusterID, nodePoolID string, instanceIDs []string) (string, error)
+	DescribeNodePool(clusterID, nodePoolID string) (*ACKNodePool, error)
+	DescribeTaskInfo(taskID string) (*ACKTask, error)
+}
+
+type ACKCluster struct {
+	ClusterID string `json:"cluster_id"`
+	Name      string `json:"name"`
+	// MasterURL is a JSON document of the API server endpoints
+	MasterURL string `json:"master_url"`
+}
+
+// APIServerEndpoints returns the VPC and the public endpoint of the API server, either may be empty
+func (c *ACKCluster) APIServerEndpoints() (intranet string, public string, err error) {
+	if c.MasterURL == "" {
+		return "", "", nil
+	}
+	var endpoints struct {
+		APIServerEndpoint         string `json:"api_server_endpoint"`
+		IntranetAPIServerEndpoint string `json:"intranet_api_server_endpoint"`
+	}
+	if err := json.Unmarshal([]byte(c.MasterURL), &endpoints); err != nil {
+		return "", "", fmt.Errorf("parsing master url, %w", err)
+	}
+	return endpoints.IntranetAPIServerEndpoint, endpoints.APIServerEndpoint, nil
+}
+
+// FindACKCluster returns the cluster of the ID if set, otherwise the only cluster with the name
+func FindACKCluster(ackapi ACKAPI, clusterID, clusterName string) (*ACKCluster, error) {
+	if clusterID != "" {
+		cluster, err := ackapi.DescribeClusterDetail(clusterID)
+		if err != nil {
+			return nil, fmt.Errorf("describing cluster %s, %w", clusterID, err)
+		}
+		return cluster, nil
+	}
+	clusters, err := ackapi.DescribeClusters(clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("describing clusters, %w", err)
+	}
+	// The name filter matches by prefix
+	clusters = lo.Filter(clusters, func(c *ACKCluster, _ int) bool { return c.Name == clusterName })
+	if len(clusters) != 1 {
+		return nil, fmt.Errorf("found %d ACK clusters named %s", len(clusters), clusterName)
+	}
+	return clusters[0], nil
+}
+
+// ACKNodePool is the node pool the instances of the ack-nodepool bootstrap mode are attached to
+type ACKNodePool struct {
+	Info struct {
+		NodePoolID string `json:"nodepool_id"`
+		Name       string `json:"name"`
+	} `json:"nodepool_info"`
+	KubernetesConfig struct {
+		Taints []ACKTaint `json:"taints"`
+	} `json:"kubernetes_config"`
+}
+
+type ACKTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Effect string `json:"effect"`
+}
+
+// Taints returns the taints ACK registers the nodes of the node pool with
+func (p *ACKNodePool) Taints() []corev1.Taint {
+	return lo.Map(p.KubernetesConfig.Taints, func(t ACKTaint, _ int) corev1.Taint {
+		return corev1.Taint{Key: t.Key, Value: t.Value, Effect: corev1.TaintEffect(t.Effect)}
+	})
+}
+
+// Ref: https://api.aliyun.com/api/CS/2015-12-15/DescribeTaskInfo
+const (
+	ACKTaskStateRunning = "running"
+	ACKTaskStateSuccess = "success"
+	ACKTaskStateFailed  = "failed"
+	ACKTaskStateFail    = "fail"
+)
+
+type ACKTask struct {
+	TaskID string `json:"task_id"`
+	State  string `json:"state"`
+	Error  *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Failed returns whether the task finished without success
+func (t *ACKTask) Failed() bool {
+	return t.State == ACKTaskStateFailed || t.State == ACKTaskStateFail
+}
+
+// FailureMessage returns the error of a failed task
+func (t *ACKTask) FailureMessage() string {
+	if t.Error == nil {
+		return fmt.Sprintf("task %s %s", t.TaskID, t.State)
+	}
+	return fmt.Sprintf("task %s %s, %s: %s", t.TaskID, t.State, t.Error.Code, t.Error.Message)
+}
+
+var _ ACKAPI = (*ACKClient)(nil)
+
+// ACKClient rate limits and retries throttled requests like the ECS and VPC clients
+type ACKClient struct {
+	ctx      context.Context
+	client   *openapi.Client
+	limiters *limiters
+}
+
+func NewACKClient(ctx context.Context, config *openapi.Config) (*ACKClient, error) {
+	if config.Endpoint == nil {
+		config.Endpoint = tea.String(fmt.Sprintf("%s.%s.aliyuncs.com", ProductACK, tea.StringValue(config.RegionId)))
+	}
+	client, err := openapi.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &ACKClient{
+		ctx:      ctx,
+		client:   client,
+		limiters: newLimiters(ctx, ProductACK),
+	}, nil
+}
+
+// Ref: https://api.aliyun.com/api/CS/2015-12-15/DescribeClusterDetail
+func (c *ACKClient) DescribeClusterDetail(clusterID string) (*ACKCluster, error) {
+	cluster := &ACKCluster{}
+	if err := c.get("DescribeClusterDetail", fmt.Sprintf("/clusters/%s", clusterID), nil, cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// Ref: https://api.aliyun.com/api/CS/2015-12-15/DescribeClustersV1
+func (c *ACKClient) DescribeClusters(name string) ([]*ACKCluster, error) {
+	var out struct {
+		Clusters []*ACKCluster `json:"clusters"`
+	}
+	if err := c.get("DescribeClustersV1", "/api/v1/clusters", map[string]*string{"name": tea.String(name)}, &out); err != nil {
+		return nil, err
+	}
+	return out.Clusters, nil
+}
+
+// Ref: https://api.aliyun.com/api/CS/2015-12-15/AttachInstancesToNodePool
+func (c *ACKClient) AttachInstancesToNodePool(clusterID, nodePoolID string, instanceIDs []string) (string, error) {
+	var out struct {
+		TaskID string `json:"task_id"`
+	}
+	body := map[string]interface{}{
+		"instances": instanceIDs,
+		// The system disk is replaced with the image of the node pool, data disks are kept as they are
+		"format_disk":        false,
+		"keep_instance_name": true,
+	}
+	if err := c.do("POST", "AttachInstancesToNodePool", fmt.Sprintf("/clusters/%s/nodepools/%s/attach", clusterID, nodePoolID), nil, body, &out); err != nil {
+		return "", err
+	}
+	return out.TaskID, nil
+}
+
+// Ref: https://api.aliyun.com/api/CS/2015-12-15/DescribeClusterNodePoolDetail
+func (c *ACKClient) DescribeNodePool(clusterID, nodePoolID string) (*ACKNodePool, error) {
+	nodePool := &ACKNodePool{}
+	if err := c.get("DescribeClusterNodePoolDetail", fmt.Sprintf("/clusters/%s/nodepools/%s", clusterID, nodePoolID), nil, nodePool); err != nil {
+		return nil, err
+	}
+	return nodePool, nil
+}
+
+func (c *ACKClient) DescribeTaskInfo(taskID string) (*ACKTask, error) {
+	task := &ACKTask{}
+	if err := c.get("DescribeTaskInfo", fmt.Sprintf("/tasks/%s", taskID), nil, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (c *ACKClient) get(action, pathname string, query map[string]*string, out interface{}) error {
+	return c.do("GET", action, pathname, query, nil, out)
+}
+
+func (c *ACKClient) do(method, action, pathname string, query map[string]*string, body, out interface{}) error {
+	resp, err := call(c.ctx, c.limiters, action, func() (map[string]interface{}, error) {
+		return c.client.CallApi(&openapi.Params{
+			Action:      tea.String(action),
+			Version:     tea.String(ackAPIVersion),
+			Protocol:    tea.String("HTTPS"),
+			Pathname:    tea.String(pathname),
+			Method:      tea.String(method),
+			AuthType:    tea.String("AK"),
+			Style:       tea.String("ROA"),
+			ReqBodyType: tea.String("json"),
+			BodyType:    tea.String("json"),
+		}, &openapi.OpenApiRequest{Query: query, Body: body}, &util.RuntimeOptions{})
+	})
+	if err != nil {
+		return err
+	}
+	respBody, err := json.Marshal(resp["body"])
+	if err != nil {
+		return fmt.Errorf("encoding %s response, %w", action, err)
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("decoding %s response, %w", action, err)
+	}
+	return nil
+}
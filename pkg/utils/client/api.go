@@ -0,0 +1,241 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	vpc "github.com/alibabacloud-go/vpc-20160428/v6/client"
+)
+
+// ECSAPI is the subset of the ECS API used by the providers
+type ECSAPI interface {
+	AddTagsWithOptions(*ecs.AddTagsRequest, *util.RuntimeOptions) (*ecs.AddTagsResponse, error)
+	AuthorizeSecurityGroupWithOptions(*ecs.AuthorizeSecurityGroupRequest, *util.RuntimeOptions) (*ecs.AuthorizeSecurityGroupResponse, error)
+	CreateAutoProvisioningGroupWithOptions(*ecs.CreateAutoProvisioningGroupRequest, *util.RuntimeOptions) (*ecs.CreateAutoProvisioningGroupResponse, error)
+	CreateLaunchTemplateWithOptions(*ecs.CreateLaunchTemplateRequest, *util.RuntimeOptions) (*ecs.CreateLaunchTemplateResponse, error)
+	CreateSecurityGroupWithOptions(*ecs.CreateSecurityGroupRequest, *util.RuntimeOptions) (*ecs.CreateSecurityGroupResponse, error)
+	DeleteInstanceWithOptions(*ecs.DeleteInstanceRequest, *util.RuntimeOptions) (*ecs.DeleteInstanceResponse, error)
+	DeleteLaunchTemplateWithOptions(*ecs.DeleteLaunchTemplateRequest, *util.RuntimeOptions) (*ecs.DeleteLaunchTemplateResponse, error)
+	DeleteSecurityGroupWithOptions(*ecs.DeleteSecurityGroupRequest, *util.RuntimeOptions) (*ecs.DeleteSecurityGroupResponse, error)
+	DescribeAccountAttributesWithOptions(*ecs.DescribeAccountAttributesRequest, *util.RuntimeOptions) (*ecs.DescribeAccountAttributesResponse, error)
+	DescribeAvailableResourceWithOptions(*ecs.DescribeAvailableResourceRequest, *util.RuntimeOptions) (*ecs.DescribeAvailableResourceResponse, error)
+	DescribeCapacityReservationsWithOptions(*ecs.DescribeCapacityReservationsRequest, *util.RuntimeOptions) (*ecs.DescribeCapacityReservationsResponse, error)
+	DescribeElasticityAssurancesWithOptions(*ecs.DescribeElasticityAssurancesRequest, *util.RuntimeOptions) (*ecs.DescribeElasticityAssurancesResponse, error)
+	DescribeImagesWithOptions(*ecs.DescribeImagesRequest, *util.RuntimeOptions) (*ecs.DescribeImagesResponse, error)
+	DescribeInstanceTypesWithOptions(*ecs.DescribeInstanceTypesRequest, *util.RuntimeOptions) (*ecs.DescribeInstanceTypesResponse, error)
+	DescribeInstancesWithOptions(*ecs.DescribeInstancesRequest, *util.RuntimeOptions) (*ecs.DescribeInstancesResponse, error)
+	DescribeKeyPairsWithOptions(*ecs.DescribeKeyPairsRequest, *util.RuntimeOptions) (*ecs.DescribeKeyPairsResponse, error)
+	DescribeSecurityGroupsWithOptions(*ecs.DescribeSecurityGroupsRequest, *util.RuntimeOptions) (*ecs.DescribeSecurityGroupsResponse, error)
+	RunInstancesWithOptions(*ecs.RunInstancesRequest, *util.RuntimeOptions) (*ecs.RunInstancesResponse, error)
+}
+
+// VPCAPI is the subset of the VPC API used by the providers
+type VPCAPI interface {
+	AssociateEipAddressWithOptions(*vpc.AssociateEipAddressRequest, *util.RuntimeOptions) (*vpc.AssociateEipAddressResponse, error)
+	DescribeEipAddressesWithOptions(*vpc.DescribeEipAddressesRequest, *util.RuntimeOptions) (*vpc.DescribeEipAddressesResponse, error)
+	DescribeVSwitchesWithOptions(*vpc.DescribeVSwitchesRequest, *util.RuntimeOptions) (*vpc.DescribeVSwitchesResponse, error)
+	DescribeVpcsWithOptions(*vpc.DescribeVpcsRequest, *util.RuntimeOptions) (*vpc.DescribeVpcsResponse, error)
+	TagResourcesWithOptions(*vpc.TagResourcesRequest, *util.RuntimeOptions) (*vpc.TagResourcesResponse, error)
+	UnTagResourcesWithOptions(*vpc.UnTagResourcesRequest, *util.RuntimeOptions) (*vpc.UnTagResourcesResponse, error)
+	UnassociateEipAddressWithOptions(*vpc.UnassociateEipAddressRequest, *util.RuntimeOptions) (*vpc.UnassociateEipAddressResponse, error)
+}
+
+var _ ECSAPI = (*ECSClient)(nil)
+var _ VPCAPI = (*VPCClient)(nil)
+
+// ECSClient shares per-API rate limits and retries throttled requests across all users of the ECS API
+type ECSClient struct {
+	ctx      context.Context
+	client   *ecs.Client
+	limiters *limiters
+}
+
+func NewECSClient(ctx context.Context, client *ecs.Client) *ECSClient {
+	return &ECSClient{
+		ctx:      ctx,
+		client:   client,
+		limiters: newLimiters(ctx, ProductECS),
+	}
+}
+
+// VPCClient shares per-API rate limits and retries throttled requests across all users of the VPC API
+type VPCClient struct {
+	ctx      context.Context
+	client   *vpc.Client
+	limiters *limiters
+}
+
+func NewVPCClient(ctx context.Context, client *vpc.Client) *VPCClient {
+	return &VPCClient{
+		ctx:      ctx,
+		client:   client,
+		limiters: newLimiters(ctx, ProductVPC),
+	}
+}
+
+func (c *ECSClient) AddTagsWithOptions(request *ecs.AddTagsRequest, runtime *util.RuntimeOptions) (*ecs.AddTagsResponse, error) {
+	return call(c.ctx, c.limiters, "AddTags", func() (*ecs.AddTagsResponse, error) {
+		return c.client.AddTagsWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) AuthorizeSecurityGroupWithOptions(request *ecs.AuthorizeSecurityGroupRequest, runtime *util.RuntimeOptions) (*ecs.AuthorizeSecurityGroupResponse, error) {
+	return call(c.ctx, c.limiters, "AuthorizeSecurityGroup", func() (*ecs.AuthorizeSecurityGroupResponse, error) {
+		return c.client.AuthorizeSecurityGroupWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) CreateAutoProvisioningGroupWithOptions(request *ecs.CreateAutoProvisioningGroupRequest, runtime *util.RuntimeOptions) (*ecs.CreateAutoProvisioningGroupResponse, error) {
+	return call(c.ctx, c.limiters, "CreateAutoProvisioningGroup", func() (*ecs.CreateAutoProvisioningGroupResponse, error) {
+		return c.client.CreateAutoProvisioningGroupWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) CreateLaunchTemplateWithOptions(request *ecs.CreateLaunchTemplateRequest, runtime *util.RuntimeOptions) (*ecs.CreateLaunchTemplateResponse, error) {
+	return call(c.ctx, c.limiters, "CreateLaunchTemplate", func() (*ecs.CreateLaunchTemplateResponse, error) {
+		return c.client.CreateLaunchTemplateWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) CreateSecurityGroupWithOptions(request *ecs.CreateSecurityGroupRequest, runtime *util.RuntimeOptions) (*ecs.CreateSecurityGroupResponse, error) {
+	return call(c.ctx, c.limiters, "CreateSecurityGroup", func() (*ecs.CreateSecurityGroupResponse, error) {
+		return c.client.CreateSecurityGroupWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DeleteInstanceWithOptions(request *ecs.DeleteInstanceRequest, runtime *util.RuntimeOptions) (*ecs.DeleteInstanceResponse, error) {
+	return call(c.ctx, c.limiters, "DeleteInstance", func() (*ecs.DeleteInstanceResponse, error) {
+		return c.client.DeleteInstanceWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DeleteLaunchTemplateWithOptions(request *ecs.DeleteLaunchTemplateRequest, runtime *util.RuntimeOptions) (*ecs.DeleteLaunchTemplateResponse, error) {
+	return call(c.ctx, c.limiters, "DeleteLaunchTemplate", func() (*ecs.DeleteLaunchTemplateResponse, error) {
+		return c.client.DeleteLaunchTemplateWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DeleteSecurityGroupWithOptions(request *ecs.DeleteSecurityGroupRequest, runtime *util.RuntimeOptions) (*ecs.DeleteSecurityGroupResponse, error) {
+	return call(c.ctx, c.limiters, "DeleteSecurityGroup", func() (*ecs.DeleteSecurityGroupResponse, error) {
+		return c.client.DeleteSecurityGroupWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DescribeAccountAttributesWithOptions(request *ecs.DescribeAccountAttributesRequest, runtime *util.RuntimeOptions) (*ecs.DescribeAccountAttributesResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeAccountAttributes", func() (*ecs.DescribeAccountAttributesResponse, error) {
+		return c.client.DescribeAccountAttributesWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DescribeAvailableResourceWithOptions(request *ecs.DescribeAvailableResourceRequest, runtime *util.RuntimeOptions) (*ecs.DescribeAvailableResourceResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeAvailableResource", func() (*ecs.DescribeAvailableResourceResponse, error) {
+		return c.client.DescribeAvailableResourceWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DescribeCapacityReservationsWithOptions(request *ecs.DescribeCapacityReservationsRequest, runtime *util.RuntimeOptions) (*ecs.DescribeCapacityReservationsResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeCapacityReservations", func() (*ecs.DescribeCapacityReservationsResponse, error) {
+		return c.client.DescribeCapacityReservationsWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DescribeElasticityAssurancesWithOptions(request *ecs.DescribeElasticityAssurancesRequest, runtime *util.RuntimeOptions) (*ecs.DescribeElasticityAssurancesResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeElasticityAssurances", func() (*ecs.DescribeElasticityAssurancesResponse, error) {
+		return c.client.DescribeElasticityAssurancesWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DescribeImagesWithOptions(request *ecs.DescribeImagesRequest, runtime *util.RuntimeOptions) (*ecs.DescribeImagesResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeImages", func() (*ecs.DescribeImagesResponse, error) {
+		return c.client.DescribeImagesWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DescribeInstanceTypesWithOptions(request *ecs.DescribeInstanceTypesRequest, runtime *util.RuntimeOptions) (*ecs.DescribeInstanceTypesResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeInstanceTypes", func() (*ecs.DescribeInstanceTypesResponse, error) {
+		return c.client.DescribeInstanceTypesWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DescribeInstancesWithOptions(request *ecs.DescribeInstancesRequest, runtime *util.RuntimeOptions) (*ecs.DescribeInstancesResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeInstances", func() (*ecs.DescribeInstancesResponse, error) {
+		return c.client.DescribeInstancesWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DescribeKeyPairsWithOptions(request *ecs.DescribeKeyPairsRequest, runtime *util.RuntimeOptions) (*ecs.DescribeKeyPairsResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeKeyPairs", func() (*ecs.DescribeKeyPairsResponse, error) {
+		return c.client.DescribeKeyPairsWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) DescribeSecurityGroupsWithOptions(request *ecs.DescribeSecurityGroupsRequest, runtime *util.RuntimeOptions) (*ecs.DescribeSecurityGroupsResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeSecurityGroups", func() (*ecs.DescribeSecurityGroupsResponse, error) {
+		return c.client.DescribeSecurityGroupsWithOptions(request, runtime)
+	})
+}
+
+func (c *ECSClient) RunInstancesWithOptions(request *ecs.RunInstancesRequest, runtime *util.RuntimeOptions) (*ecs.RunInstancesResponse, error) {
+	return call(c.ctx, c.limiters, "RunInstances", func() (*ecs.RunInstancesResponse, error) {
+		return c.client.RunInstancesWithOptions(request, runtime)
+	})
+}
+
+func (c *VPCClient) AssociateEipAddressWithOptions(request *vpc.AssociateEipAddressRequest, runtime *util.RuntimeOptions) (*vpc.AssociateEipAddressResponse, error) {
+	return call(c.ctx, c.limiters, "AssociateEipAddress", func() (*vpc.AssociateEipAddressResponse, error) {
+		return c.client.AssociateEipAddressWithOptions(request, runtime)
+	})
+}
+
+func (c *VPCClient) DescribeEipAddressesWithOptions(request *vpc.DescribeEipAddressesRequest, runtime *util.RuntimeOptions) (*vpc.DescribeEipAddressesResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeEipAddresses", func() (*vpc.DescribeEipAddressesResponse, error) {
+		return c.client.DescribeEipAddressesWithOptions(request, runtime)
+	})
+}
+
+func (c *VPCClient) DescribeVSwitchesWithOptions(request *vpc.DescribeVSwitchesRequest, runtime *util.RuntimeOptions) (*vpc.DescribeVSwitchesResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeVSwitches", func() (*vpc.DescribeVSwitchesResponse, error) {
+		return c.client.DescribeVSwitchesWithOptions(request, runtime)
+	})
+}
+
+func (c *VPCClient) DescribeVpcsWithOptions(request *vpc.DescribeVpcsRequest, runtime *util.RuntimeOptions) (*vpc.DescribeVpcsResponse, error) {
+	return call(c.ctx, c.limiters, "DescribeVpcs", func() (*vpc.DescribeVpcsResponse, error) {
+		return c.client.DescribeVpcsWithOptions(request, runtime)
+	})
+}
+
+func (c *VPCClient) TagResourcesWithOptions(request *vpc.TagResourcesRequest, runtime *util.RuntimeOptions) (*vpc.TagResourcesResponse, error) {
+	return call(c.ctx, c.limiters, "TagResources", func() (*vpc.TagResourcesResponse, error) {
+		return c.client.TagResourcesWithOptions(request, runtime)
+	})
+}
+
+func (c *VPCClient) UnTagResourcesWithOptions(request *vpc.UnTagResourcesRequest, runtime *util.RuntimeOptions) (*vpc.UnTagResourcesResponse, error) {
+	return call(c.ctx, c.limiters, "UnTagResources", func() (*vpc.UnTagResourcesResponse, error) {
+		return c.client.UnTagResourcesWithOptions(request, runtime)
+	})
+}
+
+func (c *VPCClient) UnassociateEipAddressWithOptions(request *vpc.UnassociateEipAddressRequest, runtime *util.RuntimeOptions) (*vpc.UnassociateEipAddressResponse, error) {
+	return call(c.ctx, c.limiters, "UnassociateEipAddress", func() (*vpc.UnassociateEipAddressResponse, error) {
+		return c.client.UnassociateEipAddressWithOptions(request, runtime)
+	})
+}
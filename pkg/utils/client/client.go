@@ -17,30 +17,157 @@ limitations under the License.
 package client
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"os"
 
 	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
 	"github.com/alibabacloud-go/tea/tea"
 	aliyunconfig "github.com/aliyun/aliyun-cli/config"
+	"github.com/aliyun/credentials-go/credentials"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
 )
 
-func NewClientConfig() (*openapi.Config, error) {
-	profile, err := aliyunconfig.LoadCurrentProfile()
-	if err != nil {
-		return nil, err
+// Environment variables follow the conventions of the Alibaba Cloud SDKs
+// Ref: https://www.alibabacloud.com/help/en/sdk/developer-reference/v2-manage-go-access-credentials
+const (
+	envAccessKeyID       = "ALIBABA_CLOUD_ACCESS_KEY_ID"
+	envAccessKeySecret   = "ALIBABA_CLOUD_ACCESS_KEY_SECRET"
+	envSecurityToken     = "ALIBABA_CLOUD_SECURITY_TOKEN"
+	envRoleARN           = "ALIBABA_CLOUD_ROLE_ARN"
+	envOIDCProviderARN   = "ALIBABA_CLOUD_OIDC_PROVIDER_ARN"
+	envOIDCTokenFile     = "ALIBABA_CLOUD_OIDC_TOKEN_FILE"
+	envECSMetadata       = "ALIBABA_CLOUD_ECS_METADATA"
+	envECSMetadataOff    = "ALIBABA_CLOUD_ECS_METADATA_DISABLED"
+	envIMDSv1Disabled    = "ALIBABA_CLOUD_IMDSV1_DISABLED"
+	karpenterSessionName = "karpenter"
+)
+
+// probeECSRAMRole fetches the credential of the RAM role from the instance metadata, it fails outside of ECS
+var probeECSRAMRole = func(credential credentials.Credential) error {
+	_, err := credential.GetCredential()
+	return err
+}
+
+// credentialSource is a source of the credential chain, resolve returns false if the source isn't configured
+type credentialSource struct {
+	name    string
+	resolve func() (credentials.Credential, bool, error)
+}
+
+// NewClientConfig returns the client config of the region with the credential of the first configured source of
+// the chain: environment variables, the RRSA OIDC token of the pod, the RAM role of the ECS instance, and the current
+// aliyun-cli profile. Except for static access keys, the credentials are refreshed by the SDK before they expire.
+// The region of the profile is used if no region is set in the operator options.
+func NewClientConfig(ctx context.Context) (*openapi.Config, error) {
+	region := options.FromContext(ctx).Region
+	chain := []credentialSource{
+		{name: "environment", resolve: environmentCredential},
+		{name: "oidc", resolve: func() (credentials.Credential, bool, error) {
+			return oidcCredential(options.FromContext(ctx).HTTPSProxy, Endpoint(ctx, ProductSTS, region))
+		}},
+		{name: "ecs-ram-role", resolve: ecsRAMRoleCredential},
+		{name: "profile", resolve: func() (credentials.Credential, bool, error) {
+			credential, profileRegion, err := profileCredential()
+			if region == "" {
+				region = profileRegion
+			}
+			return credential, credential != nil, err
+		}},
+	}
+	var errs []error
+	for _, source := range chain {
+		credential, ok, err := source.resolve()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s, %w", source.name, err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if region == "" {
+			return nil, errors.New("region must be set with the --region flag or the ALIBABA_CLOUD_REGION_ID environment variable")
+		}
+		log.FromContext(ctx).WithValues("credential-source", source.name, "region", region).Info("resolved alibaba cloud credentials")
+		return withNetworkOptions(ctx, &openapi.Config{
+			RegionId:   tea.String(region),
+			Credential: credential,
+		})
 	}
+	return nil, fmt.Errorf("no credentials found in the credential chain, %w", errors.Join(errs...))
+}
 
-	if profile.RegionId == "" {
-		return nil, errors.New("regionId must be set in the config file")
+func environmentCredential() (credentials.Credential, bool, error) {
+	accessKeyID, accessKeySecret := os.Getenv(envAccessKeyID), os.Getenv(envAccessKeySecret)
+	if accessKeyID == "" || accessKeySecret == "" {
+		return nil, false, nil
+	}
+	config := &credentials.Config{
+		Type:            tea.String("access_key"),
+		AccessKeyId:     tea.String(accessKeyID),
+		AccessKeySecret: tea.String(accessKeySecret),
+	}
+	if securityToken := os.Getenv(envSecurityToken); securityToken != "" {
+		config.Type = tea.String("sts")
+		config.SecurityToken = tea.String(securityToken)
 	}
+	credential, err := credentials.NewCredential(config)
+	return credential, err == nil, err
+}
+
+// oidcCredential assumes the RAM role of the service account with the token mounted by ACK RRSA, through the STS
+// endpoint of the endpoint type so that it works in VPCs without internet access
+func oidcCredential(proxy, stsEndpoint string) (credentials.Credential, bool, error) {
+	roleARN, providerARN, tokenFile := os.Getenv(envRoleARN), os.Getenv(envOIDCProviderARN), os.Getenv(envOIDCTokenFile)
+	if roleARN == "" || providerARN == "" || tokenFile == "" {
+		return nil, false, nil
+	}
+	credential, err := credentials.NewCredential(&credentials.Config{
+		Type:              tea.String("oidc_role_arn"),
+		RoleArn:           tea.String(roleARN),
+		OIDCProviderArn:   tea.String(providerARN),
+		OIDCTokenFilePath: tea.String(tokenFile),
+		RoleSessionName:   tea.String(karpenterSessionName),
+		STSEndpoint:       lo.EmptyableToPtr(stsEndpoint),
+		Proxy:             lo.EmptyableToPtr(proxy),
+	})
+	return credential, err == nil, err
+}
 
-	credentialClient, err := profile.GetCredential(nil, nil)
+// ecsRAMRoleCredential uses the RAM role attached to the instance the controller runs on, the instance metadata is
+// probed so that the chain moves on outside of ECS
+func ecsRAMRoleCredential() (credentials.Credential, bool, error) {
+	if os.Getenv(envECSMetadataOff) == "true" {
+		return nil, false, nil
+	}
+	credential, err := credentials.NewCredential(&credentials.Config{
+		Type:          tea.String("ecs_ram_role"),
+		RoleName:      tea.String(os.Getenv(envECSMetadata)),
+		DisableIMDSv1: tea.Bool(os.Getenv(envIMDSv1Disabled) == "true"),
+	})
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if err := probeECSRAMRole(credential); err != nil {
+		// Not running on ECS or no RAM role is attached
+		return nil, false, nil
 	}
+	return credential, true, nil
+}
 
-	return &openapi.Config{
-		RegionId:   tea.String(profile.RegionId),
-		Credential: credentialClient,
-	}, nil
+// profileCredential uses the current profile of the aliyun-cli config file
+func profileCredential() (credentials.Credential, string, error) {
+	profile, err := aliyunconfig.LoadCurrentProfile()
+	if err != nil {
+		return nil, "", err
+	}
+	credential, err := profile.GetCredential(nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return credential, profile.RegionId, nil
 }
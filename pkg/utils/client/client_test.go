@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/aliyun/credentials-go/credentials"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+)
+
+const profileConfig = `{
+	"current": "default",
+	"profiles": [{"name": "default", "mode": "AK", "access_key_id": "profile-key", "access_key_secret": "secret", "region_id": "cn-shanghai"}]
+}`
+
+func TestNewClientConfig(t *testing.T) {
+	envAccessKey := map[string]string{envAccessKeyID: "env-key", envAccessKeySecret: "secret"}
+	envOIDC := map[string]string{envRoleARN: "acs:ram::1:role/karpenter", envOIDCProviderARN: "acs:ram::1:oidc-provider/ack", envOIDCTokenFile: "token"}
+
+	for _, tc := range []struct {
+		name    string
+		env     map[string]string
+		region  string
+		onECS   bool
+		profile bool
+		// wantType is the type of the selected credential, wantKey tells access keys of the environment and profile apart
+		wantType   string
+		wantKey    string
+		wantRegion string
+		wantErr    bool
+	}{
+		{name: "access key of the environment", env: envAccessKey, region: "cn-hangzhou", onECS: true, profile: true,
+			wantType: "access_key", wantKey: "env-key", wantRegion: "cn-hangzhou"},
+		{name: "security token of the environment", env: map[string]string{envAccessKeyID: "env-key", envAccessKeySecret: "secret", envSecurityToken: "token"}, region: "cn-hangzhou",
+			wantType: "sts", wantKey: "env-key", wantRegion: "cn-hangzhou"},
+		{name: "incomplete access key of the environment", env: map[string]string{envAccessKeyID: "env-key"}, region: "cn-hangzhou", profile: true,
+			wantType: "access_key", wantKey: "profile-key", wantRegion: "cn-hangzhou"},
+		{name: "rrsa", env: envOIDC, region: "cn-hangzhou", onECS: true, profile: true,
+			wantType: "oidc_role_arn", wantRegion: "cn-hangzhou"},
+		{name: "incomplete rrsa", env: map[string]string{envRoleARN: "acs:ram::1:role/karpenter"}, region: "cn-hangzhou", onECS: true,
+			wantType: "ecs_ram_role", wantRegion: "cn-hangzhou"},
+		{name: "ecs ram role", region: "cn-hangzhou", onECS: true, profile: true,
+			wantType: "ecs_ram_role", wantRegion: "cn-hangzhou"},
+		{name: "disabled ecs metadata", env: map[string]string{envECSMetadataOff: "true"}, region: "cn-hangzhou", onECS: true, profile: true,
+			wantType: "access_key", wantKey: "profile-key", wantRegion: "cn-hangzhou"},
+		{name: "profile outside of ecs", region: "cn-hangzhou", profile: true,
+			wantType: "access_key", wantKey: "profile-key", wantRegion: "cn-hangzhou"},
+		{name: "region of the profile", profile: true,
+			wantType: "access_key", wantKey: "profile-key", wantRegion: "cn-shanghai"},
+		{name: "no region", onECS: true, wantErr: true},
+		{name: "no credentials", region: "cn-hangzhou", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			if tc.profile {
+				if err := os.MkdirAll(filepath.Join(home, ".aliyun"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(home, ".aliyun", "config.json"), []byte(profileConfig), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, key := range []string{envAccessKeyID, envAccessKeySecret, envSecurityToken, envRoleARN, envOIDCProviderARN, envOIDCTokenFile,
+				envECSMetadata, envECSMetadataOff, envIMDSv1Disabled} {
+				t.Setenv(key, "")
+			}
+			for key, value := range tc.env {
+				if key == envOIDCTokenFile {
+					value = filepath.Join(home, value)
+					if err := os.WriteFile(value, []byte("oidc-token"), 0600); err != nil {
+						t.Fatal(err)
+					}
+				}
+				t.Setenv(key, value)
+			}
+			probe := probeECSRAMRole
+			t.Cleanup(func() { probeECSRAMRole = probe })
+			probeECSRAMRole = func(credentials.Credential) error {
+				if !tc.onECS {
+					return errors.New("not running on ecs")
+				}
+				return nil
+			}
+
+			ctx := options.ToContext(context.Background(), &options.Options{Region: tc.region, APIEndpointType: options.EndpointTypePublic})
+			config, err := NewClientConfig(ctx)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("resolved %s credentials, want error", tea.StringValue(config.Credential.GetType()))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolving credentials, %v", err)
+			}
+			if got := tea.StringValue(config.Credential.GetType()); got != tc.wantType {
+				t.Errorf("resolved %s credentials, want %s", got, tc.wantType)
+			}
+			if got := tea.StringValue(config.RegionId); got != tc.wantRegion {
+				t.Errorf("resolved region %s, want %s", got, tc.wantRegion)
+			}
+			if tc.wantKey != "" {
+				credential, err := config.Credential.GetCredential()
+				if err != nil {
+					t.Fatalf("getting access key, %v", err)
+				}
+				if got := tea.StringValue(credential.AccessKeyId); got != tc.wantKey {
+					t.Errorf("resolved access key %s, want %s", got, tc.wantKey)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	apiSubsystem = "cloudprovider_api"
+	serviceLabel = "service"
+	apiLabel     = "api"
+)
+
+var (
+	APIWaitDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: apiSubsystem,
+			Name:      "wait_duration_seconds",
+			Help:      "Time spent waiting for client-side rate limits and retry backoffs per Alibaba Cloud API call.",
+			Buckets:   metrics.DurationBuckets(),
+		},
+		[]string{serviceLabel, apiLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(APIWaitDuration)
+}
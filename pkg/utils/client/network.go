@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+)
+
+// Products are the Alibaba Cloud APIs the operator has clients for
+const (
+	ProductECS = "ecs"
+	ProductVPC = "vpc"
+	ProductSTS = "sts"
+	ProductACK = "cs"
+
+	ProductResourceManager = "resourcemanager"
+)
+
+// ServiceConfig returns a copy of the client config for the API of the product with the endpoint of Endpoint
+func ServiceConfig(ctx context.Context, config *openapi.Config, product string) *openapi.Config {
+	serviceConfig := *config
+	serviceConfig.Endpoint = lo.EmptyableToPtr(Endpoint(ctx, product, tea.StringValue(config.RegionId)))
+	return &serviceConfig
+}
+
+// Endpoint returns the endpoint override of the product if any, otherwise the endpoint of the endpoint type. An empty
+// endpoint is returned for public endpoints, which are left to the SDKs.
+func Endpoint(ctx context.Context, product, region string) string {
+	opts := options.FromContext(ctx)
+	endpoint := map[string]string{
+		ProductECS: opts.ECSEndpoint,
+		ProductVPC: opts.VPCEndpoint,
+		ProductSTS: opts.STSEndpoint,
+		ProductACK: opts.ACKEndpoint,
+
+		ProductResourceManager: opts.ResourceManagerEndpoint,
+	}[product]
+	if endpoint == "" {
+		endpoint = endpointOf(product, region, opts.APIEndpointType)
+	}
+	return endpoint
+}
+
+// endpointOf returns the endpoint of the product for the endpoint type. The regional endpoint is used for intl since
+// the SDKs map some regions to central endpoints of the China site.
+// Ref: https://www.alibabacloud.com/help/en/ecs/developer-reference/api-ecs-2014-05-26-endpoint
+func endpointOf(product, region, endpointType string) string {
+	// Resource groups are global, both sites share the central endpoint
+	if product == ProductResourceManager {
+		return lo.Ternary(endpointType == options.EndpointTypeVPC, fmt.Sprintf("%s.vpc-proxy.aliyuncs.com", product), "")
+	}
+	// Without a region only the central endpoint of the SDKs is known
+	if region == "" {
+		return ""
+	}
+	switch endpointType {
+	case options.EndpointTypeVPC:
+		return fmt.Sprintf("%s-vpc.%s.aliyuncs.com", product, region)
+	case options.EndpointTypeIntl:
+		return fmt.Sprintf("%s.%s.aliyuncs.com", product, region)
+	}
+	return ""
+}
+
+// withNetworkOptions sets the proxies and the CA bundle of the operator options on the client config. The SDKs verify
+// endpoints with the CA bundle only, like the HTTP client of NewHTTPClient does.
+func withNetworkOptions(ctx context.Context, config *openapi.Config) (*openapi.Config, error) {
+	opts := options.FromContext(ctx)
+	config.HttpProxy = lo.EmptyableToPtr(opts.HTTPProxy)
+	config.HttpsProxy = lo.EmptyableToPtr(opts.HTTPSProxy)
+	config.NoProxy = lo.EmptyableToPtr(opts.NoProxy)
+	if opts.APICABundleFile != "" {
+		caBundle, err := os.ReadFile(opts.APICABundleFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading api ca bundle, %w", err)
+		}
+		config.Ca = tea.String(string(caBundle))
+	}
+	return config, nil
+}
+
+// NewHTTPClient returns an HTTP client with the proxies and the CA bundle of the operator options for requests that
+// aren't sent with the SDKs, like the price queries. The CA bundle replaces the system roots as it does for the SDKs.
+func NewHTTPClient(ctx context.Context) (*http.Client, error) {
+	opts := options.FromContext(ctx)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	proxy := (&httpproxy.Config{
+		HTTPProxy:  opts.HTTPProxy,
+		HTTPSProxy: opts.HTTPSProxy,
+		NoProxy:    opts.NoProxy,
+	}).ProxyFunc()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+	if opts.APICABundleFile != "" {
+		caBundle, err := os.ReadFile(opts.APICABundleFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading api ca bundle, %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificates found in api ca bundle %s", opts.APICABundleFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &http.Client{Transport: transport, Timeout: time.Minute}, nil
+}
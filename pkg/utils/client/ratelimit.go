@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"golang.org/x/time/rate"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/alierrors"
+)
+
+const (
+	maxRetries  = 5
+	baseBackoff = 200 * time.Millisecond
+	maxBackoff  = 10 * time.Second
+)
+
+type limit struct {
+	qps   rate.Limit
+	burst int
+}
+
+// defaultLimit applies to the APIs without a dedicated limit
+var defaultLimit = limit{qps: 10, burst: 20}
+
+// apiLimits stay below the default per-account flow control of each API, which is shared with every other client of
+// the account, so that Karpenter doesn't starve other controllers. APIs called for every launch or deletion get the
+// largest buckets, APIs whose responses are cached get small ones. The api-rate-limit-scale option scales all of them
+// for accounts whose flow control differs from the defaults.
+// Ref: https://www.alibabacloud.com/help/en/ecs/developer-reference/api-throttling
+var apiLimits = map[string]map[string]limit{
+	ProductECS: {
+		"DescribeInstances":            {qps: 50, burst: 100},
+		"DescribeInstanceTypes":        {qps: 10, burst: 20},
+		"DescribeAvailableResource":    {qps: 10, burst: 20},
+		"DescribeImages":               {qps: 20, burst: 40},
+		"DescribeSecurityGroups":       {qps: 20, burst: 40},
+		"DescribeKeyPairs":             {qps: 10, burst: 20},
+		"DescribeAccountAttributes":    {qps: 5, burst: 10},
+		"DescribeCapacityReservations": {qps: 10, burst: 20},
+		"DescribeElasticityAssurances": {qps: 10, burst: 20},
+		"CreateLaunchTemplate":         {qps: 10, burst: 20},
+		"DeleteLaunchTemplate":         {qps: 10, burst: 20},
+		"CreateAutoProvisioningGroup":  {qps: 10, burst: 20},
+		"RunInstances":                 {qps: 10, burst: 20},
+		"DeleteInstance":               {qps: 10, burst: 20},
+		"AddTags":                      {qps: 10, burst: 10},
+	},
+	ProductVPC: {
+		"DescribeVSwitches":    {qps: 20, burst: 40},
+		"DescribeVpcs":         {qps: 20, burst: 40},
+		"DescribeEipAddresses": {qps: 20, burst: 40},
+	},
+	ProductACK: {
+		"AttachInstancesToNodePool": {qps: 5, burst: 10},
+		"DescribeTaskInfo":          {qps: 10, burst: 20},
+	},
+}
+
+// limiters holds a token bucket per API of a service
+type limiters struct {
+	mu      sync.Mutex
+	service string
+	scale   float64
+	buckets map[string]*rate.Limiter
+}
+
+func newLimiters(ctx context.Context, service string) *limiters {
+	scale := 1.0
+	if opts := options.FromContext(ctx); opts != nil && opts.APIRateLimitScale > 0 {
+		scale = opts.APIRateLimitScale
+	}
+	return &limiters{
+		service: service,
+		scale:   scale,
+		buckets: map[string]*rate.Limiter{},
+	}
+}
+
+func (l *limiters) get(api string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if bucket, ok := l.buckets[api]; ok {
+		return bucket
+	}
+	lim, ok := apiLimits[l.service][api]
+	if !ok {
+		lim = defaultLimit
+	}
+	l.buckets[api] = rate.NewLimiter(lim.qps*rate.Limit(l.scale), max(int(float64(lim.burst)*l.scale), 1))
+	return l.buckets[api]
+}
+
+// call waits for a token of the API before every attempt and retries throttled requests with a jittered exponential
+// backoff. Read-only APIs are also retried on server errors, mutating APIs aren't as the request may have been applied.
+// The clients mirror the SDKs, whose requests carry no context, so they pass the context of the operator to stop
+// waiting on shutdown.
+func call[T any](ctx context.Context, l *limiters, api string, f func() (T, error)) (T, error) {
+	var zero T
+	var waited time.Duration
+	defer func() {
+		APIWaitDuration.WithLabelValues(l.service, api).Observe(waited.Seconds())
+	}()
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		err := l.get(api).Wait(ctx)
+		waited += time.Since(start)
+		if err != nil {
+			return zero, fmt.Errorf("waiting for rate limit of %s, %w", api, err)
+		}
+
+		out, err := f()
+		if err == nil || attempt == maxRetries || !retriable(api, err) {
+			return out, err
+		}
+		start = time.Now()
+		timer := time.NewTimer(jitteredBackoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			waited += time.Since(start)
+			return zero, fmt.Errorf("retrying %s, %w", api, multierr.Append(err, ctx.Err()))
+		case <-timer.C:
+			waited += time.Since(start)
+		}
+	}
+}
+
+func retriable(api string, err error) bool {
+	if alierrors.IsThrottling(err) {
+		return true
+	}
+	return strings.HasPrefix(api, "Describe") && alierrors.IsRetriable(err)
+}
+
+// jitteredBackoff returns a random duration up to the exponential backoff of the attempt, also known as full jitter
+func jitteredBackoff(attempt int) time.Duration {
+	backoff := min(baseBackoff<<attempt, maxBackoff)
+	return time.Duration(rand.Int63n(int64(backoff))) + 1
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang.org/x/time/rate"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alicloud/pkg/utils/alierrors"
+)
+
+// failing returns a call failing with the errors in order before it succeeds, and the number of attempts
+func failing(errs ...error) (func() (string, error), *int) {
+	attempts := 0
+	return func() (string, error) {
+		attempts++
+		if attempts <= len(errs) {
+			return "", errs[attempts-1]
+		}
+		return "ok", nil
+	}, &attempts
+}
+
+func TestCallRetries(t *testing.T) {
+	throttled := alierrors.New("Throttling.User", "Request was denied due to user flow control.")
+	internal := alierrors.New("InternalError", "The request processing has failed due to some unknown error.")
+	invalid := alierrors.New("InvalidParameter", "The specified parameter is not valid.")
+
+	for _, tc := range []struct {
+		name     string
+		api      string
+		errs     []error
+		attempts int
+		wantErr  error
+	}{
+		{name: "throttled requests are retried", api: "RunInstances", errs: []error{throttled, throttled}, attempts: 3},
+		{name: "server errors of read-only APIs are retried", api: "DescribeInstances", errs: []error{internal}, attempts: 2},
+		{name: "server errors of mutating APIs aren't retried", api: "RunInstances", errs: []error{internal}, attempts: 1, wantErr: internal},
+		{name: "other errors aren't retried", api: "DescribeInstances", errs: []error{invalid}, attempts: 1, wantErr: invalid},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, attempts := failing(tc.errs...)
+			_, err := call(context.Background(), newLimiters(context.Background(), ProductECS), tc.api, f)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("call returned error %v, want %v", err, tc.wantErr)
+			}
+			if *attempts != tc.attempts {
+				t.Errorf("call made %d attempts, want %d", *attempts, tc.attempts)
+			}
+		})
+	}
+}
+
+func TestCallStopsOnContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	throttled := alierrors.New("Throttling.User", "Request was denied due to user flow control.")
+	attempts := 0
+	_, err := call(ctx, newLimiters(context.Background(), ProductECS), "DescribeInstances", func() (string, error) {
+		attempts++
+		cancel()
+		return "", throttled
+	})
+	if !errors.Is(err, context.Canceled) || !alierrors.IsThrottling(err) {
+		t.Errorf("call returned error %v, want the throttling error and context canceled", err)
+	}
+	if attempts != 1 {
+		t.Errorf("call made %d attempts after the context was canceled, want 1", attempts)
+	}
+
+	// The rate limit isn't waited for with a canceled context
+	if _, err := call(ctx, newLimiters(context.Background(), ProductECS), "DescribeInstances", func() (string, error) {
+		t.Errorf("call attempted with a canceled context")
+		return "", nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("call returned error %v, want context canceled", err)
+	}
+}
+
+func TestLimitersScale(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		ctx       context.Context
+		wantQPS   rate.Limit
+		wantBurst int
+	}{
+		{name: "without options", ctx: context.Background(), wantQPS: 50, wantBurst: 100},
+		{name: "raised", ctx: options.ToContext(context.Background(), &options.Options{APIRateLimitScale: 2}), wantQPS: 100, wantBurst: 200},
+		{name: "lowered", ctx: options.ToContext(context.Background(), &options.Options{APIRateLimitScale: 0.001}), wantQPS: 0.05, wantBurst: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bucket := newLimiters(tc.ctx, ProductECS).get("DescribeInstances")
+			if bucket.Limit() != tc.wantQPS || bucket.Burst() != tc.wantBurst {
+				t.Errorf("bucket of DescribeInstances = %v qps with a burst of %d, want %v qps with a burst of %d", bucket.Limit(), bucket.Burst(), tc.wantQPS, tc.wantBurst)
+			}
+		})
+	}
+}
+
+func TestJitteredBackoff(t *testing.T) {
+	for attempt := 0; attempt <= maxRetries+5; attempt++ {
+		limit := min(baseBackoff<<attempt, maxBackoff)
+		for range 100 {
+			if backoff := jitteredBackoff(attempt); backoff <= 0 || backoff > limit {
+				t.Fatalf("backoff of attempt %d = %s, want within (0, %s]", attempt, backoff, limit)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+// resourceManagerAPIVersion is the version of the Resource Manager API, which has no Go SDK in the module graph
+// either, so requests are sent with the generic OpenAPI client like the ACK ones
+const resourceManagerAPIVersion = "2020-03-31"
+
+// Ref: https://api.aliyun.com/api/ResourceManager/2020-03-31/ListResourceGroups
+const (
+	ResourceGroupStatusOK = "OK"
+)
+
+// ResourceManagerAPI is the subset of the Resource Manager API used by the operator
+type ResourceManagerAPI interface {
+	// ListResourceGroups returns the resource groups of the IDs, IDs of groups that don't exist are left out
+	ListResourceGroups(ids []string) ([]*ResourceGroup, error)
+}
+
+type ResourceGroup struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Status string `json:"Status"`
+}
+
+var _ ResourceManagerAPI = (*ResourceManagerClient)(nil)
+
+// ResourceManagerClient rate limits and retries throttled requests like the other clients
+type ResourceManagerClient struct {
+	ctx      context.Context
+	client   *openapi.Client
+	limiters *limiters
+}
+
+func NewResourceManagerClient(ctx context.Context, config *openapi.Config) (*ResourceManagerClient, error) {
+	// Resource groups are global, the API has a single public endpoint
+	if config.Endpoint == nil {
+		config.Endpoint = tea.String(fmt.Sprintf("%s.aliyuncs.com", ProductResourceManager))
+	}
+	client, err := openapi.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceManagerClient{
+		ctx:      ctx,
+		client:   client,
+		limiters: newLimiters(ctx, ProductResourceManager),
+	}, nil
+}
+
+// Ref: https://api.aliyun.com/api/ResourceManager/2020-03-31/ListResourceGroups
+func (c *ResourceManagerClient) ListResourceGroups(ids []string) ([]*ResourceGroup, error) {
+	var resourceGroups []*ResourceGroup
+	query := map[string]*string{"PageSize": tea.String("100")}
+	for i, id := range ids {
+		query[fmt.Sprintf("ResourceGroupIds.%d", i+1)] = tea.String(id)
+	}
+	for pageNumber := 1; ; pageNumber++ {
+		var out struct {
+			TotalCount     int `json:"TotalCount"`
+			ResourceGroups struct {
+				ResourceGroup []*ResourceGroup `json:"ResourceGroup"`
+			} `json:"ResourceGroups"`
+		}
+		query["PageNumber"] = tea.String(fmt.Sprint(pageNumber))
+		if err := c.do("ListResourceGroups", query, &out); err != nil {
+			return nil, err
+		}
+		resourceGroups = append(resourceGroups, out.ResourceGroups.ResourceGroup...)
+		if len(out.ResourceGroups.ResourceGroup) == 0 || len(resourceGroups) >= out.TotalCount {
+			return resourceGroups, nil
+		}
+	}
+}
+
+func (c *ResourceManagerClient) do(action string, query map[string]*string, out interface{}) error {
+	resp, err := call(c.ctx, c.limiters, action, func() (map[string]interface{}, error) {
+		return c.client.CallApi(&openapi.Params{
+			Action:      tea.String(action),
+			Version:     tea.String(resourceManagerAPIVersion),
+			Protocol:    tea.String("HTTPS"),
+			Pathname:    tea.String("/"),
+			Method:      tea.String("POST"),
+			AuthType:    tea.String("AK"),
+			Style:       tea.String("RPC"),
+			ReqBodyType: tea.String("formData"),
+			BodyType:    tea.String("json"),
+		}, &openapi.OpenApiRequest{Query: query}, &util.RuntimeOptions{})
+	})
+	if err != nil {
+		return err
+	}
+	respBody, err := json.Marshal(resp["body"])
+	if err != nil {
+		return fmt.Errorf("encoding %s response, %w", action, err)
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("decoding %s response, %w", action, err)
+	}
+	return nil
+}
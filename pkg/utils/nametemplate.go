@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"text/template"
+)
+
+// Ref: https://api.aliyun.com/api/Ecs/2014-05-26/RunInstances
+var (
+	instanceNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._:-]{1,127}$`)
+	hostNameRegex     = regexp.MustCompile(`^[a-z0-9]([.-]?[a-z0-9])+$`)
+)
+
+const maxHostNameLength = 64
+
+// NameTemplateData contains the variables available to the instance name and host name templates of an ECSNodeClass,
+// the names are set at launch so values only known once the instance exists aren't available
+type NameTemplateData struct {
+	ClusterName  string
+	NodePool     string
+	NodeClaim    string
+	NodeClass    string
+	CapacityType string
+}
+
+// RenderNameTemplate renders an instance name or host name template, referencing an unknown variable is an error
+func RenderNameTemplate(nameTemplate string, data NameTemplateData) (string, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("parsing name template %q, %w", nameTemplate, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("rendering name template %q, %w", nameTemplate, err)
+	}
+	return buf.String(), nil
+}
+
+// ValidateInstanceName checks a rendered instance name against the ECS naming rules, the variables of a valid template
+// may still render names that are too long
+func ValidateInstanceName(name string) error {
+	if !instanceNameRegex.MatchString(name) {
+		return fmt.Errorf("instance name %q must be 2 to 128 characters long, start with a letter and only contain letters, digits, '.', '_', ':' and '-'", name)
+	}
+	return nil
+}
+
+// ValidateHostName checks a rendered host name against the ECS naming rules of Linux instances
+func ValidateHostName(name string) error {
+	if len(name) > maxHostNameLength || !hostNameRegex.MatchString(name) {
+		return fmt.Errorf("host name %q must be 2 to %d characters long, only contain lowercase letters, digits, '.' and '-', "+
+			"start and end with a letter or a digit and not contain consecutive '.' or '-'", name, maxHostNameLength)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateInstanceName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"default-cn-hangzhou-h-i-bp1abc": true,
+		"node:ecs.g7.large_spot":         true,
+		"n":                              false,
+		"1-default":                      false,
+		"default/i-bp1abc":               false,
+		"d" + strings.Repeat("a", 127):   true,
+		"d" + strings.Repeat("a", 128):   false,
+	} {
+		if err := ValidateInstanceName(name); (err == nil) != valid {
+			t.Errorf("ValidateInstanceName(%q) = %v, want valid %t", name, err, valid)
+		}
+	}
+}
+
+func TestValidateHostName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"default-cn-hangzhou-h-i-bp1abc": true,
+		"ecs.g7.large-i-bp1abc":          true,
+		"n":                              false,
+		"Default-i-bp1abc":               false,
+		"default--i-bp1abc":              false,
+		"default-i-bp1abc-":              false,
+		"default_i-bp1abc":               false,
+		strings.Repeat("a", 64):          true,
+		strings.Repeat("a", 65):          false,
+	} {
+		if err := ValidateHostName(name); (err == nil) != valid {
+			t.Errorf("ValidateHostName(%q) = %v, want valid %t", name, err, valid)
+		}
+	}
+}